@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -8,18 +9,42 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 
 	"user_crud/internal/api/controller"
+	"user_crud/internal/api/middleware"
 	"user_crud/internal/api/routes"
 	"user_crud/internal/config"
 	"user_crud/internal/domain/repository"
+	"user_crud/internal/domain/repository/interfaces"
 	"user_crud/internal/domain/service"
+	"user_crud/internal/util"
+	"user_crud/pkg/blobstore"
+	"user_crud/pkg/events"
+	"user_crud/pkg/mailer"
+	"user_crud/pkg/policy"
 	"user_crud/pkg/storage"
 )
 
 func main() {
 	// Load configuration
-	cfg := config.NewConfig()
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Configure password hashing; existing hashes are upgraded on login
+	util.SetPasswordHasher(util.NewPasswordHasher(
+		cfg.PasswordHashAlgorithm,
+		util.Argon2idParams{
+			Memory:      uint32(cfg.Argon2Memory),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+			SaltLength:  util.DefaultArgon2idParams.SaltLength,
+			KeyLength:   util.DefaultArgon2idParams.KeyLength,
+		},
+		cfg.BcryptCost,
+	))
 
 	// Setup database connection
 	db := storage.NewDatabaseConnection(cfg.DatabaseDSN)
@@ -28,17 +53,232 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	fileRepo := repository.NewFileRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
+	serviceAccountRepo := repository.NewServiceAccountRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	verificationTokenRepo := repository.NewVerificationTokenRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	membershipRepo := repository.NewMembershipRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	roleGrantRepo := repository.NewRoleGrantRepository(db)
+	auditEventRepo := repository.NewAuditEventRepository(db)
+	webhookSubscriptionRepo := repository.NewWebhookSubscriptionRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	notificationPreferenceRepo := repository.NewNotificationPreferenceRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
+
+	var loginAttemptRepo interfaces.LoginAttemptRepository
+	if cfg.LoginAttemptStore == "sql" {
+		loginAttemptRepo = repository.NewLoginAttemptRepository(db)
+	} else {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepository(cfg.LoginFailureWindow + cfg.LoginLockoutDuration)
+	}
+
+	// Initialize breached password check
+	breachedPasswords := util.NewNoopBreachedPasswordChecker()
+	if cfg.BreachedPasswordsDir != "" {
+		breachedPasswords = util.NewRangeDirectoryChecker(cfg.BreachedPasswordsDir)
+	}
+
+	// Initialize disposable email domain blocklist
+	disposableDomains := util.NewDomainList(nil)
+	if cfg.DisposableEmailDomainsFile != "" {
+		domains, err := util.LoadDomainList(cfg.DisposableEmailDomainsFile)
+		if err != nil {
+			log.Fatalf("Failed to load disposable email domains: %v", err)
+		}
+		disposableDomains = domains
+	}
+
+	// Initialize access policy
+	accessPolicy, err := service.LoadPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load access policy: %v", err)
+	}
+	policyEngine, err := policy.NewEngine(accessPolicy)
+	if err != nil {
+		log.Fatalf("Failed to load access policy: %v", err)
+	}
+
+	// Initialize mailer
+	var mail mailer.Mailer
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		mail = mailer.NewLogMailer()
+	}
+
+	// Initialize file storage; raw uploads waiting for processing and attachments are kept
+	// apart from the served images on disk, and in the same private bucket with S3
+	var images, uploads, attachments blobstore.BlobStore
+	if cfg.ImageStore == "s3" {
+		images, err = blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+			Timeout:         cfg.S3Timeout,
+		})
+		uploads, attachments = images, images
+	} else {
+		images, err = blobstore.NewLocalStore(cfg.ImageDir)
+		if err == nil {
+			uploads, err = blobstore.NewLocalStore(cfg.ImageUploadDir)
+		}
+		if err == nil {
+			attachments, err = blobstore.NewLocalStore(cfg.FileDir)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to set up file storage: %v", err)
+	}
+
+	// Initialize the hub sharing presence and pushed messages between instances
+	var realtimeHub events.Hub
+	if cfg.RealtimeHub == "nats" {
+		realtimeHub, err = events.NewNATSHub(cfg.NATSURL, cfg.NATSSubjectPrefix+".realtime", cfg.EventSinkTimeout)
+		if err != nil {
+			log.Fatalf("Failed to set up the realtime hub: %v", err)
+		}
+	} else {
+		realtimeHub = events.NewMemoryHub()
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, fileRepo)
-	authService := service.NewAuthService(userRepo, roleRepo)
+	auditService := service.NewAuditService(auditEventRepo)
+	realtimeService := service.NewRealtimeService(realtimeHub, membershipRepo, cfg)
+	notificationService := service.NewNotificationService(notificationRepo, notificationPreferenceRepo, userRepo, realtimeService, mail, cfg)
+	webhookService := service.NewWebhookService(webhookSubscriptionRepo, webhookDeliveryRepo, auditService, cfg)
+	organizationService := service.NewOrganizationService(
+		organizationRepo,
+		membershipRepo,
+		groupRepo,
+		userRepo,
+		unitOfWork,
+		auditService,
+		cfg,
+	)
+	if err := organizationService.EnsureDefaultOrganization(); err != nil {
+		log.Fatalf("Failed to set up default organization: %v", err)
+	}
+	groupService := service.NewGroupService(groupRepo, membershipRepo, userRepo, auditService)
+	sessionService := service.NewSessionService(sessionRepo, userRepo, membershipRepo, roleGrantRepo, groupService, auditService, cfg)
+	passwordPolicyService := service.NewPasswordPolicyService(passwordHistoryRepo, breachedPasswords, cfg)
+	policyService := service.NewPolicyService(policyEngine, userRepo, membershipRepo, groupService, auditService, cfg)
+	fileService := service.NewFileService(fileRepo, userRepo, unitOfWork, auditService, uploads, images, attachments, cfg)
+	userService := service.NewUserService(
+		userRepo,
+		roleRepo,
+		fileRepo,
+		sessionRepo,
+		verificationTokenRepo,
+		membershipRepo,
+		unitOfWork,
+		passwordPolicyService,
+		policyService,
+		organizationService,
+		notificationService,
+		auditService,
+		mail,
+		fileService,
+		cfg,
+	)
+	loginGuardService := service.NewLoginGuardService(loginAttemptRepo, userRepo, mail, auditService, cfg)
+	registrationService := service.NewRegistrationService(userRepo, unitOfWork, disposableDomains, mail, auditService, cfg)
+	authService := service.NewAuthService(
+		userRepo,
+		roleRepo,
+		unitOfWork,
+		sessionService,
+		loginGuardService,
+		passwordPolicyService,
+		registrationService,
+		organizationService,
+		notificationService,
+		auditService,
+		cfg,
+	)
+	invitationService := service.NewInvitationService(
+		invitationRepo,
+		userRepo,
+		roleRepo,
+		unitOfWork,
+		sessionService,
+		passwordPolicyService,
+		organizationService,
+		mail,
+		cfg,
+	)
+	serviceAccountService := service.NewServiceAccountService(serviceAccountRepo, userRepo, auditService)
+	roleGrantService := service.NewRoleGrantService(roleGrantRepo, userRepo, roleRepo, sessionRepo, auditService, cfg)
+	impersonationService := service.NewImpersonationService(userRepo, roleGrantRepo, sessionService, auditService)
+
+	// Mark ended role grants as expired in the background
+	go roleGrantService.RunExpirySweeper(context.Background(), cfg.RoleGrantSweepInterval)
+
+	// Publish domain events from the outbox to every configured sink in the background
+	userStreamService := service.NewUserStreamService(membershipRepo, cfg)
+	eventBus := events.NewBus()
+	eventBus.Subscribe(events.AllEvents, userStreamService.Handle)
+	eventSinks := []events.Sink{eventBus, webhookService}
+	if cfg.NATSURL != "" {
+		natsSink, err := events.NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix, cfg.EventSinkTimeout)
+		if err != nil {
+			log.Fatalf("Failed to set up NATS publishing: %v", err)
+		}
+		eventSinks = append(eventSinks, natsSink)
+	}
+	if cfg.KafkaRESTURL != "" {
+		kafkaSink, err := events.NewKafkaRESTSink(cfg.KafkaRESTURL, cfg.KafkaTopic, cfg.EventSinkTimeout)
+		if err != nil {
+			log.Fatalf("Failed to set up Kafka publishing: %v", err)
+		}
+		eventSinks = append(eventSinks, kafkaSink)
+	}
+	outboxService := service.NewOutboxService(outboxRepo, eventSinks, cfg)
+	go outboxService.RunDispatcher(context.Background(), cfg.OutboxDispatchInterval)
+
+	// Track presence and push notifications over WebSockets, shared between instances through the hub
+	go realtimeService.RunPresenceAnnouncer(context.Background(), cfg.PresenceAnnounceInterval)
+
+	// Remove notifications past retention in the background
+	go notificationService.RunRetentionSweeper(context.Background(), cfg.NotificationSweepInterval)
+
+	// Deliver queued webhook events in the background
+	go webhookService.RunDispatcher(context.Background(), cfg.WebhookDispatchInterval)
+
+	// Sanitize uploaded images and make their thumbnails in the background
+	go fileService.RunWorkers(context.Background(), cfg.MediaPollInterval)
 
 	// Initialize controllers
 	userController := controller.NewUserController(userService)
-	authController := controller.NewAuthController(authService)
+	authController := controller.NewAuthController(authService, loginGuardService)
+	serviceAccountController := controller.NewServiceAccountController(serviceAccountService)
+	sessionController := controller.NewSessionController(sessionService)
+	meController := controller.NewMeController(userService)
+	invitationController := controller.NewInvitationController(invitationService)
+	registrationController := controller.NewRegistrationController(registrationService)
+	organizationController := controller.NewOrganizationController(organizationService)
+	groupController := controller.NewGroupController(groupService)
+	roleGrantController := controller.NewRoleGrantController(roleGrantService)
+	impersonationController := controller.NewImpersonationController(impersonationService)
+	policyController := controller.NewPolicyController(policyService)
+	auditController := controller.NewAuditController(auditService)
+	webhookController := controller.NewWebhookController(webhookService)
+	userStreamController := controller.NewUserStreamController(userStreamService, cfg.UserStreamHeartbeatInterval)
+	realtimeController := controller.NewRealtimeController(realtimeService, cfg.WebSocketPingInterval)
+	notificationController := controller.NewNotificationController(notificationService)
+	fileController := controller.NewFileController(fileService)
 
 	// Setup Fiber app
 	app := fiber.New(fiber.Config{
+		// Leave room for the other form fields sent along with an image or attachment
+		BodyLimit: int(max(cfg.ImageMaxBytes, cfg.FileMaxBytes)) + 1024*1024,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			// Handle fiber-specific errors
 			if fiberErr, ok := err.(*fiber.Error); ok {
@@ -55,12 +295,49 @@ func main() {
 	})
 
 	// Add middleware
-	app.Use(logger.New())
+	app.Use(logger.New(logger.Config{
+		// Distinguish human users from service accounts in the request log
+		Format: "${time} | ${status} | ${latency} | ${ip} | ${principal} | ${method} | ${path} | ${error}\n",
+		CustomTags: map[string]logger.LogFunc{
+			"principal": func(output logger.Buffer, c *fiber.Ctx, _ *logger.Data, _ string) (int, error) {
+				return output.WriteString(middleware.PrincipalLabel(c))
+			},
+		},
+	}))
 	app.Use(recover.New())
-	app.Use(cors.New())
+	// Tag every request with an ID that is returned to the client and stored on audit events
+	app.Use(requestid.New())
+	app.Use(cors.New(cors.Config{
+		// Let browser clients show a banner while an admin impersonates the user
+		ExposeHeaders: "X-Impersonation, X-Impersonator-Id, X-Request-Id",
+	}))
 
 	// Setup routes
-	routes.SetupRoutes(app, userController, authController)
+	routes.SetupRoutes(
+		app,
+		images,
+		middleware.Protected(sessionService, serviceAccountService, auditService),
+		middleware.LoadPermissions(groupService),
+		policyService,
+		userController,
+		authController,
+		serviceAccountController,
+		sessionController,
+		meController,
+		invitationController,
+		registrationController,
+		organizationController,
+		groupController,
+		roleGrantController,
+		impersonationController,
+		policyController,
+		auditController,
+		webhookController,
+		userStreamController,
+		realtimeController,
+		notificationController,
+		fileController,
+	)
 
 	// Start server
 	serverAddr := fmt.Sprintf("%s:%d", cfg.ServerHost, cfg.ServerPort)
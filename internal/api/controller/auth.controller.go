@@ -1,19 +1,25 @@
 package controller
 
 import (
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 
+	"user_crud/internal/api/middleware"
+	"user_crud/internal/domain/entity"
 	"user_crud/internal/domain/service/interfaces"
 	"user_crud/internal/dto"
 )
 
 type AuthController struct {
-	authService interfaces.AuthService
+	authService       interfaces.AuthService
+	loginGuardService interfaces.LoginGuardService
 }
 
-func NewAuthController(authService interfaces.AuthService) *AuthController {
+func NewAuthController(authService interfaces.AuthService, loginGuardService interfaces.LoginGuardService) *AuthController {
 	return &AuthController{
-		authService: authService,
+		authService:       authService,
+		loginGuardService: loginGuardService,
 	}
 }
 
@@ -23,11 +29,18 @@ func (ac *AuthController) Register(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	response, err, status := ac.authService.Register(req)
+	response, err, status := ac.authService.Register(req, clientInfo(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
 
+	if status == fiber.StatusAccepted {
+		return c.Status(status).JSON(dto.RegistrationPendingResponse{
+			Status:  entity.UserStatusPendingApproval,
+			Message: "Your account will be available once an administrator approves it",
+		})
+	}
+
 	return c.Status(status).JSON(response)
 }
 
@@ -37,7 +50,7 @@ func (ac *AuthController) Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	response, err, status := ac.authService.Login(req)
+	response, err, status := ac.authService.Login(req, clientInfo(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -54,10 +67,42 @@ func (ac *AuthController) RefreshToken(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	response, err, status := ac.authService.RefreshToken(req.RefreshToken)
+	response, err, status := ac.authService.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
 
 	return c.Status(status).JSON(response)
 }
+
+// UnlockUser lifts a brute-force lockout of a user's account and optionally of a client address
+func (ac *AuthController) UnlockUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req dto.UnlockRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	err, status := ac.loginGuardService.UnlockUser(uint(id), req.IPAddress, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// clientInfo captures the device and request details recorded on sessions and audit events
+func clientInfo(c *fiber.Ctx) dto.ClientInfo {
+	return middleware.ClientInfo(c)
+}
+
+// currentActor collects the authenticated principal set by the auth middleware
+func currentActor(c *fiber.Ctx) dto.Actor {
+	return middleware.CurrentActor(c)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"mime"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+)
+
+// FileController serves the files of users: attachments and avatar images
+type FileController struct {
+	fileService interfaces.FileService
+}
+
+func NewFileController(fileService interfaces.FileService) *FileController {
+	return &FileController{
+		fileService: fileService,
+	}
+}
+
+// ListFiles lists the user's files; "purpose" narrows them down to avatars or documents
+func (fc *FileController) ListFiles(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	files, err, status := fc.fileService.ListFiles(c, currentActor(c), uint(userID), c.Query("purpose"))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(files)
+}
+
+func (fc *FileController) UploadFile(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	file, err, status := fc.fileService.UploadFile(c, currentActor(c), uint(userID))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(file)
+}
+
+// GetFile describes a file; clients poll it until an uploaded image is processed
+func (fc *FileController) GetFile(c *fiber.Ctx) error {
+	userID, fileID, err := fileParams(c)
+	if err != nil {
+		return err
+	}
+
+	file, err, status := fc.fileService.GetFile(c, currentActor(c), userID, fileID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(file)
+}
+
+// DownloadFile sends the content of a file as an attachment, so browsers never render
+// uploaded content in the API's origin
+func (fc *FileController) DownloadFile(c *fiber.Ctx) error {
+	userID, fileID, err := fileParams(c)
+	if err != nil {
+		return err
+	}
+
+	download, err, status := fc.fileService.DownloadFile(c, currentActor(c), userID, fileID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, download.ContentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	// The stream is closed once it has been sent
+	return c.Status(status).SendStream(download.Body, int(download.Size))
+}
+
+func (fc *FileController) DeleteFile(c *fiber.Ctx) error {
+	userID, fileID, err := fileParams(c)
+	if err != nil {
+		return err
+	}
+
+	err, status := fc.fileService.DeleteFile(currentActor(c), userID, fileID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(status)
+}
+
+func fileParams(c *fiber.Ctx) (uint, uint, error) {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	fileID, err := strconv.ParseUint(c.Params("fileId"), 10, 32)
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid file ID")
+	}
+
+	return uint(userID), uint(fileID), nil
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type GroupController struct {
+	groupService interfaces.GroupService
+}
+
+func NewGroupController(groupService interfaces.GroupService) *GroupController {
+	return &GroupController{
+		groupService: groupService,
+	}
+}
+
+func (gc *GroupController) CreateGroup(c *fiber.Ctx) error {
+	var req dto.GroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	group, err, status := gc.groupService.CreateGroup(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(group)
+}
+
+func (gc *GroupController) GetGroups(c *fiber.Ctx) error {
+	groups, err, status := gc.groupService.GetGroups(currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(groups)
+}
+
+func (gc *GroupController) GetGroup(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	group, err, status := gc.groupService.GetGroup(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(group)
+}
+
+func (gc *GroupController) UpdateGroup(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	var req dto.GroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	group, err, status := gc.groupService.UpdateGroup(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(group)
+}
+
+func (gc *GroupController) DeleteGroup(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	err, status := gc.groupService.DeleteGroup(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (gc *GroupController) GetMembers(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	members, err, status := gc.groupService.GetMembers(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(members)
+}
+
+func (gc *GroupController) AddMember(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	var req dto.AddGroupMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	err, status := gc.groupService.AddMember(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (gc *GroupController) RemoveMember(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid group ID")
+	}
+
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	err, status := gc.groupService.RemoveMember(uint(id), uint(userID), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type ImpersonationController struct {
+	impersonationService interfaces.ImpersonationService
+}
+
+func NewImpersonationController(impersonationService interfaces.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{
+		impersonationService: impersonationService,
+	}
+}
+
+// Impersonate issues a short-lived access token acting as the user; it is ended early with /api/auth/logout
+func (ic *ImpersonationController) Impersonate(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req dto.ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err, status := ic.impersonationService.Impersonate(uint(id), req, currentActor(c), clientInfo(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type InvitationController struct {
+	invitationService interfaces.InvitationService
+}
+
+func NewInvitationController(invitationService interfaces.InvitationService) *InvitationController {
+	return &InvitationController{
+		invitationService: invitationService,
+	}
+}
+
+func (ic *InvitationController) CreateInvitation(c *fiber.Ctx) error {
+	var req dto.CreateInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	currentUserID := c.Locals("user_id").(uint)
+
+	invitation, err, status := ic.invitationService.CreateInvitation(req, currentUserID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(invitation)
+}
+
+func (ic *InvitationController) GetAllInvitations(c *fiber.Ctx) error {
+	invitations, err, status := ic.invitationService.GetAllInvitations(c.Query("status"))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(invitations)
+}
+
+func (ic *InvitationController) RevokeInvitation(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid invitation ID")
+	}
+
+	err, status := ic.invitationService.RevokeInvitation(uint(id))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (ic *InvitationController) AcceptInvitation(c *fiber.Ctx) error {
+	var req dto.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err, status := ic.invitationService.AcceptInvitation(req, clientInfo(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+// MeController serves the self-service endpoints of the authenticated user
+type MeController struct {
+	userService interfaces.UserService
+}
+
+func NewMeController(userService interfaces.UserService) *MeController {
+	return &MeController{
+		userService: userService,
+	}
+}
+
+func (mc *MeController) GetProfile(c *fiber.Ctx) error {
+	currentUserID := c.Locals("user_id").(uint)
+
+	user, err, status := mc.userService.GetUser(currentUserID, c, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(user)
+}
+
+func (mc *MeController) UpdateProfile(c *fiber.Ctx) error {
+	currentUserID := c.Locals("user_id").(uint)
+
+	user, err, status := mc.userService.UpdateUser(c, currentUserID, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(user)
+}
+
+func (mc *MeController) ChangePassword(c *fiber.Ctx) error {
+	var req dto.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	currentSessionID := c.Locals("session_id").(uint)
+
+	err, status := mc.userService.ChangePassword(currentActor(c), currentSessionID, req)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (mc *MeController) ChangeEmail(c *fiber.Ctx) error {
+	var req dto.ChangeEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	err, status := mc.userService.RequestEmailChange(currentActor(c), req)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"message": "Verification email sent to the new address",
+	})
+}
+
+// VerifyEmail confirms an email change with the token sent to the new address
+func (mc *MeController) VerifyEmail(c *fiber.Ctx) error {
+	var req dto.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	err, status := mc.userService.ConfirmEmailChange(req.Token, clientInfo(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (mc *MeController) UploadAvatar(c *fiber.Ctx) error {
+	user, err, status := mc.userService.UpdateAvatar(c, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(user)
+}
+
+func (mc *MeController) DeleteAvatar(c *fiber.Ctx) error {
+	user, err, status := mc.userService.DeleteAvatar(c, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(user)
+}
+
+func (mc *MeController) DeleteAccount(c *fiber.Ctx) error {
+	var req dto.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	err, status := mc.userService.DeleteOwnAccount(currentActor(c), req)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
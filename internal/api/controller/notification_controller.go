@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+// NotificationController serves the notification center of the authenticated user
+type NotificationController struct {
+	notificationService interfaces.NotificationService
+}
+
+func NewNotificationController(notificationService interfaces.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications lists the caller's notifications; "unread=true" leaves out those already read
+func (nc *NotificationController) GetNotifications(c *fiber.Ctx) error {
+	query := dto.NotificationQuery{UnreadOnly: c.QueryBool("unread")}
+
+	var err error
+	if query.Limit, err = strconv.Atoi(c.Query("limit", "0")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid limit")
+	}
+	if query.Offset, err = strconv.Atoi(c.Query("offset", "0")); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid offset")
+	}
+
+	notifications, err, status := nc.notificationService.GetNotifications(currentActor(c), query)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(notifications)
+}
+
+func (nc *NotificationController) MarkRead(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid notification ID")
+	}
+
+	err, status := nc.notificationService.MarkRead(currentActor(c), uint(id))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(status)
+}
+
+func (nc *NotificationController) MarkAllRead(c *fiber.Ctx) error {
+	err, status := nc.notificationService.MarkAllRead(currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(status)
+}
+
+func (nc *NotificationController) GetPreferences(c *fiber.Ctx) error {
+	preferences, err, status := nc.notificationService.GetPreferences(currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(preferences)
+}
+
+func (nc *NotificationController) UpdatePreferences(c *fiber.Ctx) error {
+	var req dto.UpdateNotificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	preferences, err, status := nc.notificationService.UpdatePreferences(currentActor(c), req)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(preferences)
+}
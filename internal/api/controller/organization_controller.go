@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type OrganizationController struct {
+	organizationService interfaces.OrganizationService
+}
+
+func NewOrganizationController(organizationService interfaces.OrganizationService) *OrganizationController {
+	return &OrganizationController{
+		organizationService: organizationService,
+	}
+}
+
+func (oc *OrganizationController) CreateOrganization(c *fiber.Ctx) error {
+	var req dto.CreateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	organization, err, status := oc.organizationService.CreateOrganization(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(organization)
+}
+
+func (oc *OrganizationController) GetAllOrganizations(c *fiber.Ctx) error {
+	organizations, err, status := oc.organizationService.GetAllOrganizations()
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(organizations)
+}
+
+func (oc *OrganizationController) GetOrganization(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	organization, err, status := oc.organizationService.GetOrganization(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(organization)
+}
+
+func (oc *OrganizationController) UpdateOrganization(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	var req dto.UpdateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	organization, err, status := oc.organizationService.UpdateOrganization(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(organization)
+}
+
+func (oc *OrganizationController) DeleteOrganization(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	err, status := oc.organizationService.DeleteOrganization(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (oc *OrganizationController) GetMembers(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	members, err, status := oc.organizationService.GetMembers(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(members)
+}
+
+func (oc *OrganizationController) AddMember(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	var req dto.AddMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	member, err, status := oc.organizationService.AddMember(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(member)
+}
+
+func (oc *OrganizationController) UpdateMember(c *fiber.Ctx) error {
+	id, userID, err := membershipParams(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	member, err, status := oc.organizationService.UpdateMember(id, userID, req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(member)
+}
+
+func (oc *OrganizationController) RemoveMember(c *fiber.Ctx) error {
+	id, userID, err := membershipParams(c)
+	if err != nil {
+		return err
+	}
+
+	err, status := oc.organizationService.RemoveMember(id, userID, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// MoveMember moves a user from this organization to another one
+func (oc *OrganizationController) MoveMember(c *fiber.Ctx) error {
+	id, userID, err := membershipParams(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.MoveMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	member, err, status := oc.organizationService.MoveMember(id, userID, req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(member)
+}
+
+// GetMyOrganizations lists the organizations of the current user
+func (oc *OrganizationController) GetMyOrganizations(c *fiber.Ctx) error {
+	actor := currentActor(c)
+
+	organizations, err, status := oc.organizationService.GetUserOrganizations(actor.UserID, actor.OrganizationID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(organizations)
+}
+
+// membershipParams parses the organization and user IDs of membership routes
+func membershipParams(c *fiber.Ctx) (uint, uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 32)
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	return uint(id), uint(userID), nil
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type PolicyController struct {
+	policyService interfaces.PolicyService
+}
+
+func NewPolicyController(policyService interfaces.PolicyService) *PolicyController {
+	return &PolicyController{
+		policyService: policyService,
+	}
+}
+
+func (pc *PolicyController) GetPolicy(c *fiber.Ctx) error {
+	return c.JSON(pc.policyService.GetPolicy())
+}
+
+// Explain evaluates a request against the access policy and reports the result of every rule
+func (pc *PolicyController) Explain(c *fiber.Ctx) error {
+	var req dto.PolicyExplainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err, status := pc.policyService.Explain(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+func (pc *PolicyController) Reload(c *fiber.Ctx) error {
+	policy, err, status := pc.policyService.Reload(currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(policy)
+}
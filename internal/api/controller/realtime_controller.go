@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/api/middleware"
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+// Close codes from the application range of RFC 6455, telling clients why the server hung up
+const (
+	closeTokenExpired = 4001 // reconnect with a fresh access token
+	closeTooSlow      = 4008 // messages were not read fast enough; reconnect
+)
+
+// realtimeWriteTimeout bounds every write to a client
+const realtimeWriteTimeout = 10 * time.Second
+
+// realtimeReadLimit is the largest message accepted from clients, which have nothing to send but control frames
+const realtimeReadLimit = 4096
+
+type RealtimeController struct {
+	realtimeService interfaces.RealtimeService
+	pingInterval    time.Duration
+	upgrade         fiber.Handler
+}
+
+func NewRealtimeController(realtimeService interfaces.RealtimeService, pingInterval time.Duration) *RealtimeController {
+	rc := &RealtimeController{
+		realtimeService: realtimeService,
+		pingInterval:    pingInterval,
+	}
+	rc.upgrade = websocket.New(rc.serve, websocket.Config{
+		Subprotocols:     []string{middleware.WebSocketTokenProtocol},
+		HandshakeTimeout: realtimeWriteTimeout,
+	})
+	return rc
+}
+
+// Connect upgrades to a WebSocket that pushes presence changes and notifications as JSON
+// messages. The user is online while connected. The connection is closed when the access
+// token expires, so clients reconnect with a fresh one.
+func (rc *RealtimeController) Connect(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.NewError(fiber.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+
+	if _, ok := c.Locals("token_expires_at").(time.Time); !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	connection, err, status := rc.realtimeService.Connect(currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	// Handed over to serve, which runs once the handshake is done
+	c.Locals("realtime_connection", connection)
+	if err := rc.upgrade(c); err != nil {
+		connection.Close()
+		return err
+	}
+	return nil
+}
+
+func (rc *RealtimeController) serve(conn *websocket.Conn) {
+	connection := conn.Locals("realtime_connection").(dto.RealtimeConnection)
+	defer connection.Close()
+	expiresAt := conn.Locals("token_expires_at").(time.Time)
+
+	// Clients must answer pings; anything they send other than control frames is ignored
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(realtimeReadLimit)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * rc.pingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * rc.pingInterval))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(rc.pingInterval)
+	defer ping.Stop()
+	expired := time.NewTimer(time.Until(expiresAt))
+	defer expired.Stop()
+
+	for {
+		var err error
+		select {
+		case message, ok := <-connection.Messages:
+			if !ok {
+				rc.close(conn, closeTooSlow, "too slow")
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout))
+			err = conn.WriteJSON(message)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteTimeout))
+		case <-expired.C:
+			rc.close(conn, closeTokenExpired, "token expired")
+			return
+		case <-closed:
+			return
+		}
+
+		// A failed write means the client has gone away
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (rc *RealtimeController) close(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(realtimeWriteTimeout))
+}
+
+// GetPresence reports whether the users in the comma-separated "user_ids" query are online
+func (rc *RealtimeController) GetPresence(c *fiber.Ctx) error {
+	var userIDs []uint
+	for _, value := range strings.Split(c.Query("user_ids"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+	if len(userIDs) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "user_ids is required")
+	}
+
+	presence, err, status := rc.realtimeService.GetPresence(currentActor(c), userIDs)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(presence)
+}
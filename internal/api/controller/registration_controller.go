@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type RegistrationController struct {
+	registrationService interfaces.RegistrationService
+}
+
+func NewRegistrationController(registrationService interfaces.RegistrationService) *RegistrationController {
+	return &RegistrationController{
+		registrationService: registrationService,
+	}
+}
+
+func (rc *RegistrationController) GetPendingRegistrations(c *fiber.Ctx) error {
+	registrations, err, status := rc.registrationService.GetPendingRegistrations()
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(registrations)
+}
+
+func (rc *RegistrationController) ApproveRegistration(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	registration, err, status := rc.registrationService.ApproveRegistration(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(registration)
+}
+
+func (rc *RegistrationController) RejectRegistration(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req dto.RejectRegistrationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	registration, err, status := rc.registrationService.RejectRegistration(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(registration)
+}
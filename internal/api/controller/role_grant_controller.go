@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type RoleGrantController struct {
+	roleGrantService interfaces.RoleGrantService
+}
+
+func NewRoleGrantController(roleGrantService interfaces.RoleGrantService) *RoleGrantController {
+	return &RoleGrantController{
+		roleGrantService: roleGrantService,
+	}
+}
+
+func (rc *RoleGrantController) RequestGrant(c *fiber.Ctx) error {
+	var req dto.RoleGrantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	grant, err, status := rc.roleGrantService.RequestGrant(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(grant)
+}
+
+func (rc *RoleGrantController) GetGrants(c *fiber.Ctx) error {
+	var userID uint64
+	if value := c.Query("user_id"); value != "" {
+		var err error
+		userID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		}
+	}
+
+	grants, err, status := rc.roleGrantService.GetGrants(c.Query("status"), uint(userID))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(grants)
+}
+
+func (rc *RoleGrantController) GetMyGrants(c *fiber.Ctx) error {
+	grants, err, status := rc.roleGrantService.GetUserGrants(c.Locals("user_id").(uint))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(grants)
+}
+
+func (rc *RoleGrantController) ApproveGrant(c *fiber.Ctx) error {
+	return rc.decide(c, rc.roleGrantService.ApproveGrant)
+}
+
+func (rc *RoleGrantController) RejectGrant(c *fiber.Ctx) error {
+	return rc.decide(c, rc.roleGrantService.RejectGrant)
+}
+
+func (rc *RoleGrantController) RevokeGrant(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role grant ID")
+	}
+
+	grant, err, status := rc.roleGrantService.RevokeGrant(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(grant)
+}
+
+// decide handles approving and rejecting, which share an optional note in the body
+func (rc *RoleGrantController) decide(
+	c *fiber.Ctx,
+	decision func(uint, dto.RoleGrantDecisionRequest, dto.Actor) (dto.RoleGrantResponse, error, int),
+) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role grant ID")
+	}
+
+	var req dto.RoleGrantDecisionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	grant, err, status := decision(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(grant)
+}
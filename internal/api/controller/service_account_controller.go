@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type ServiceAccountController struct {
+	serviceAccountService interfaces.ServiceAccountService
+}
+
+func NewServiceAccountController(serviceAccountService interfaces.ServiceAccountService) *ServiceAccountController {
+	return &ServiceAccountController{
+		serviceAccountService: serviceAccountService,
+	}
+}
+
+func (sc *ServiceAccountController) CreateServiceAccount(c *fiber.Ctx) error {
+	var req dto.CreateServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err, status := sc.serviceAccountService.CreateServiceAccount(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+func (sc *ServiceAccountController) GetAllServiceAccounts(c *fiber.Ctx) error {
+	accounts, err, status := sc.serviceAccountService.GetAllServiceAccounts()
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(accounts)
+}
+
+func (sc *ServiceAccountController) GetServiceAccount(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid service account ID")
+	}
+
+	account, err, status := sc.serviceAccountService.GetServiceAccount(uint(id))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(account)
+}
+
+func (sc *ServiceAccountController) UpdateServiceAccount(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid service account ID")
+	}
+
+	var req dto.UpdateServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	account, err, status := sc.serviceAccountService.UpdateServiceAccount(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(account)
+}
+
+func (sc *ServiceAccountController) RotateSecret(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid service account ID")
+	}
+
+	response, err, status := sc.serviceAccountService.RotateSecret(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+func (sc *ServiceAccountController) DeleteServiceAccount(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid service account ID")
+	}
+
+	err, status := sc.serviceAccountService.DeleteServiceAccount(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Token implements the OAuth 2.0 token endpoint for service accounts.
+// Client credentials may be sent in the body or with HTTP Basic authentication.
+func (sc *ServiceAccountController) Token(c *fiber.Ctx) error {
+	var req dto.ServiceTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.ClientID == "" {
+		if clientID, clientSecret, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization)); ok {
+			req.ClientID = clientID
+			req.ClientSecret = clientSecret
+		}
+	}
+
+	response, err, status := sc.serviceAccountService.IssueToken(req, clientInfo(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(response)
+}
+
+// parseBasicAuth decodes an "Authorization: Basic" header as described in RFC 6749 section 2.3.1
+func parseBasicAuth(header string) (string, string, bool) {
+	if !strings.HasPrefix(header, "Basic ") {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+
+	clientID, clientSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+
+	clientID, err = url.QueryUnescape(clientID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err = url.QueryUnescape(clientSecret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return clientID, clientSecret, true
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type SessionController struct {
+	sessionService interfaces.SessionService
+}
+
+func NewSessionController(sessionService interfaces.SessionService) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
+
+func (sc *SessionController) GetMySessions(c *fiber.Ctx) error {
+	currentUserID := c.Locals("user_id").(uint)
+	currentSessionID := c.Locals("session_id").(uint)
+
+	sessions, err, status := sc.sessionService.GetUserSessions(currentUserID, currentSessionID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(sessions)
+}
+
+func (sc *SessionController) RevokeMySession(c *fiber.Ctx) error {
+	sessionID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	currentUserID := c.Locals("user_id").(uint)
+
+	err, status := sc.sessionService.RevokeSession(currentUserID, uint(sessionID))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SwitchOrganization moves the current session to another organization of the user
+func (sc *SessionController) SwitchOrganization(c *fiber.Ctx) error {
+	var req dto.SwitchOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	currentUserID := c.Locals("user_id").(uint)
+	currentSessionID := c.Locals("session_id").(uint)
+
+	response, err, status := sc.sessionService.SwitchOrganization(currentSessionID, currentUserID, req.OrganizationID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+// Logout revokes the session of the current access token
+func (sc *SessionController) Logout(c *fiber.Ctx) error {
+	currentUserID := c.Locals("user_id").(uint)
+	currentSessionID := c.Locals("session_id").(uint)
+
+	err, status := sc.sessionService.RevokeSession(currentUserID, currentSessionID)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (sc *SessionController) GetUserSessions(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	sessions, err, status := sc.sessionService.GetUserSessions(uint(userID), 0)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(sessions)
+}
+
+func (sc *SessionController) RevokeUserSession(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	sessionID, err := strconv.ParseUint(c.Params("sessionId"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	err, status := sc.sessionService.RevokeSession(uint(userID), uint(sessionID))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (sc *SessionController) RevokeAllUserSessions(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	err, status := sc.sessionService.RevokeAllSessions(uint(userID))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
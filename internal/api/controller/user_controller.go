@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
 )
 
 type UserController struct {
@@ -19,10 +20,7 @@ func NewUserController(userService interfaces.UserService) *UserController {
 }
 
 func (uc *UserController) CreateUser(c *fiber.Ctx) error {
-	// Get current user role from context (set by auth middleware)
-	role := c.Locals("role").(string)
-
-	response, err, status := uc.userService.CreateUser(c, role)
+	response, err, status := uc.userService.CreateUser(c, currentActor(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -31,7 +29,7 @@ func (uc *UserController) CreateUser(c *fiber.Ctx) error {
 }
 
 func (uc *UserController) GetAllUsers(c *fiber.Ctx) error {
-	users, err, status := uc.userService.GetAllUsers(c)
+	users, err, status := uc.userService.GetAllUsers(c, currentActor(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -45,7 +43,7 @@ func (uc *UserController) GetUser(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	user, err, status := uc.userService.GetUser(uint(id), c)
+	user, err, status := uc.userService.GetUser(uint(id), c, currentActor(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -60,11 +58,7 @@ func (uc *UserController) UpdateUser(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	// Get current user info from context (set by auth middleware)
-	currentUserID := c.Locals("user_id").(uint)
-	currentUserRole := c.Locals("role").(string)
-
-	user, err, status := uc.userService.UpdateUser(c, uint(id), currentUserID, currentUserRole)
+	user, err, status := uc.userService.UpdateUser(c, uint(id), currentActor(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -79,11 +73,22 @@ func (uc *UserController) DeleteUser(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	// Get current user info from context (set by auth middleware)
-	currentUserID := c.Locals("user_id").(uint)
-	currentUserRole := c.Locals("role").(string)
+	err, status := uc.userService.DeleteUser(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetPassword lets a user created with send_invite choose their password
+func (uc *UserController) SetPassword(c *fiber.Ctx) error {
+	var req dto.SetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
 
-	err, status := uc.userService.DeleteUser(uint(id), currentUserID, currentUserRole)
+	err, status := uc.userService.CompletePasswordSetup(req, clientInfo(c))
 	if err != nil {
 		return fiber.NewError(status, err.Error())
 	}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+// userStreamRetry is how long browsers wait before reconnecting, in milliseconds
+const userStreamRetry = 3000
+
+type UserStreamController struct {
+	userStreamService interfaces.UserStreamService
+	heartbeatInterval time.Duration
+}
+
+func NewUserStreamController(userStreamService interfaces.UserStreamService, heartbeatInterval time.Duration) *UserStreamController {
+	return &UserStreamController{
+		userStreamService: userStreamService,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+// Stream sends user changes as Server-Sent Events. Clients reconnecting with a
+// Last-Event-ID header receive the buffered events they missed, or a "reset" event
+// when those are gone and the user list has to be reloaded. The stream ends when
+// the access token expires, so clients reconnect with a fresh one.
+func (uc *UserStreamController) Stream(c *fiber.Ctx) error {
+	subscription, err, status := uc.userStreamService.Subscribe(currentActor(c), c.Get("Last-Event-ID"))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	expiresAt, ok := c.Locals("token_expires_at").(time.Time)
+	if !ok {
+		subscription.Cancel()
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Keep reverse proxies from buffering the stream
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer subscription.Cancel()
+
+		fmt.Fprintf(w, "retry: %d\n\n", userStreamRetry)
+		if subscription.Reset {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, event := range subscription.Missed {
+			writeUserStreamEvent(w, event)
+		}
+		if w.Flush() != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(uc.heartbeatInterval)
+		defer heartbeat.Stop()
+		expired := time.NewTimer(time.Until(expiresAt))
+		defer expired.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscription.Events:
+				if !ok {
+					// Dropped for falling behind; the client resumes from its last event
+					return
+				}
+				writeUserStreamEvent(w, event)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+			case <-expired.C:
+				fmt.Fprint(w, "event: token_expired\ndata: {}\n\n")
+				_ = w.Flush()
+				return
+			}
+
+			// A failed flush means the client has gone away
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func writeUserStreamEvent(w *bufio.Writer, event dto.UserStreamEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type WebhookController struct {
+	webhookService interfaces.WebhookService
+}
+
+func NewWebhookController(webhookService interfaces.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+func (wc *WebhookController) CreateWebhook(c *fiber.Ctx) error {
+	var req dto.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err, status := wc.webhookService.CreateSubscription(req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+func (wc *WebhookController) GetWebhooks(c *fiber.Ctx) error {
+	webhooks, err, status := wc.webhookService.GetSubscriptions()
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(webhooks)
+}
+
+func (wc *WebhookController) GetWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	webhook, err, status := wc.webhookService.GetSubscription(uint(id))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(webhook)
+}
+
+func (wc *WebhookController) UpdateWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	webhook, err, status := wc.webhookService.UpdateSubscription(uint(id), req, currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(webhook)
+}
+
+func (wc *WebhookController) RotateSecret(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	response, err, status := wc.webhookService.RotateSecret(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+func (wc *WebhookController) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	err, status := wc.webhookService.DeleteSubscription(uint(id), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetDeliveries lists the delivery log, optionally filtered by ?status=pending|succeeded|dead
+func (wc *WebhookController) GetDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "0"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid limit")
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid offset")
+	}
+
+	deliveries, err, status := wc.webhookService.GetDeliveries(uint(id), c.Query("status"), limit, offset)
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(deliveries)
+}
+
+func (wc *WebhookController) Redeliver(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Params("deliveryId"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid delivery ID")
+	}
+
+	delivery, err, status := wc.webhookService.Redeliver(uint(id), uint(deliveryID), currentActor(c))
+	if err != nil {
+		return fiber.NewError(status, err.Error())
+	}
+
+	return c.Status(status).JSON(delivery)
+}
@@ -1,15 +1,26 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
 	"user_crud/internal/util"
+	"user_crud/pkg/policy"
 )
 
-// Protected middleware to verify JWT access tokens
-func Protected() fiber.Handler {
+// Protected middleware to verify JWT access tokens.
+// User tokens are rejected once the session they belong to is revoked or expired,
+// service account tokens once the account is disabled or deleted.
+func Protected(sessionService interfaces.SessionService, serviceAccountService interfaces.ServiceAccountService, auditService interfaces.AuditService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get authorization header
 		authHeader := c.Get("Authorization")
@@ -30,12 +41,111 @@ func Protected() fiber.Handler {
 		if err != nil {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
 		}
+		// Long-lived responses such as event streams end when the token does
+		c.Locals("token_expires_at", claims.ExpiresAt.Time)
+
+		// Service accounts are a separate kind of principal: they have no user ID
+		// and carry scopes instead of a role. Scopes removed from the account since
+		// the token was issued no longer apply.
+		if claims.PrincipalType == entity.PrincipalTypeServiceAccount {
+			scopes, err := serviceAccountService.ValidateServiceAccount(claims.ServiceAccountID, claims.Scopes)
+			if err != nil {
+				return fiber.NewError(fiber.StatusUnauthorized, "Service account disabled or deleted")
+			}
+
+			c.Locals("principal_type", entity.PrincipalTypeServiceAccount)
+			c.Locals("service_account_id", claims.ServiceAccountID)
+			c.Locals("client_id", claims.Subject)
+			c.Locals("scopes", scopes)
+			c.Locals("user_id", uint(0))
+			c.Locals("email", "")
+			c.Locals("role", entity.ServiceAccountRole)
+			c.Locals("organization_id", uint(0))
+			c.Locals("organization_role", "")
+			return c.Next()
+		}
+
+		// Check the session behind the token is still active
+		if claims.SessionID == 0 || sessionService.ValidateSession(claims.SessionID, claims.UserID, claims.OrganizationID, claims.OrganizationRole, claims.RoleGrantID) != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Session revoked or expired")
+		}
 
 		// Set user information in context for later use
+		c.Locals("principal_type", entity.PrincipalTypeUser)
+		c.Locals("session_id", claims.SessionID)
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
 		c.Locals("role", claims.Role)
+		c.Locals("organization_id", claims.OrganizationID)
+		c.Locals("organization_role", claims.OrganizationRole)
+
+		// Roles from temporary grants fall back to the user's own role once the grant ends
+		if claims.RoleGrantID != 0 {
+			c.Locals("role_grant_id", claims.RoleGrantID)
+			c.Locals("base_role", claims.BaseRole)
+			c.Locals("role_expires_at", time.Unix(claims.RoleExpiresAt, 0))
+			effectiveRole(c)
+		}
+
+		if claims.Act != nil {
+			return impersonating(c, claims.Act.UserID, auditService)
+		}
+
+		return c.Next()
+	}
+}
+
+// impersonating flags the response so clients can show a banner and records every
+// request an admin makes while acting as the user
+func impersonating(c *fiber.Ctx, impersonatorID uint, auditService interfaces.AuditService) error {
+	c.Locals("impersonator_id", impersonatorID)
+	c.Set("X-Impersonation", "true")
+	c.Set("X-Impersonator-Id", strconv.FormatUint(uint64(impersonatorID), 10))
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+	auditService.Record(dto.AuditRecord{
+		Actor:      CurrentActor(c),
+		Action:     entity.AuditActionImpersonatedRequest,
+		TargetType: entity.AuditTargetSession,
+		TargetID:   c.Locals("session_id").(uint),
+		Details: map[string]any{
+			"method": c.Method(),
+			"path":   c.OriginalURL(),
+			"status": status,
+		},
+	})
+
+	return err
+}
 
+// WebSocketTokenProtocol is the WebSocket subprotocol under which browsers, which cannot
+// set headers on WebSocket requests, send their access token: they request the protocols
+// "access_token" and the token itself, and the server accepts "access_token".
+const WebSocketTokenProtocol = "access_token"
+
+// WebSocketToken middleware lets Protected authenticate WebSocket upgrade requests that carry
+// their access token in the Sec-WebSocket-Protocol header instead of Authorization
+func WebSocketToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") != "" {
+			return c.Next()
+		}
+
+		protocols := strings.Split(c.Get(fiber.HeaderSecWebSocketProtocol), ",")
+		for i := range protocols {
+			protocols[i] = strings.TrimSpace(protocols[i])
+		}
+		if len(protocols) == 2 && protocols[0] == WebSocketTokenProtocol && protocols[1] != "" {
+			c.Request().Header.Set("Authorization", "Bearer "+protocols[1])
+		}
 		return c.Next()
 	}
 }
@@ -49,8 +159,16 @@ func RoleRequired(roles ...string) fiber.Handler {
 			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
 		}
 
+		// Service accounts have no role; they pass only where ScopeRequired has granted access
+		if IsServiceAccount(c) {
+			if granted, _ := c.Locals("scope_granted").(bool); granted {
+				return c.Next()
+			}
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+		}
+
 		// Check if user has one of the required roles
-		roleStr := userRole.(string)
+		roleStr := effectiveRole(c)
 		for _, role := range roles {
 			if role == roleStr {
 				return c.Next()
@@ -60,3 +178,174 @@ func RoleRequired(roles ...string) fiber.Handler {
 		return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
 	}
 }
+
+// effectiveRole returns the role of the caller. A role from a temporary grant is replaced by
+// the user's own role once the grant has expired, even if the access token is still valid.
+func effectiveRole(c *fiber.Ctx) string {
+	role, _ := c.Locals("role").(string)
+	if expiresAt, ok := c.Locals("role_expires_at").(time.Time); ok && !time.Now().Before(expiresAt) {
+		role, _ = c.Locals("base_role").(string)
+		c.Locals("role", role)
+	}
+	return role
+}
+
+// LoadPermissions middleware resolves the permissions a user receives through their groups
+// in the current organization and stores them in c.Locals("permissions")
+func LoadPermissions(groupService interfaces.GroupService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		organizationID, _ := c.Locals("organization_id").(uint)
+		if IsServiceAccount(c) || organizationID == 0 {
+			return c.Next()
+		}
+
+		permissions, err := groupService.GetPermissions(c.Locals("user_id").(uint), organizationID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load permissions")
+		}
+
+		c.Locals("permissions", permissions)
+		return c.Next()
+	}
+}
+
+// PermissionRequired middleware to check if user holds one of the permissions in their current
+// organization. Global admins and organization admins hold every permission; services still
+// restrict everyone but global admins to their tenant.
+func PermissionRequired(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("role") == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		if organizationRole, _ := c.Locals("organization_role").(string); organizationRole == entity.OrganizationRoleAdmin {
+			return c.Next()
+		}
+
+		granted, _ := c.Locals("permissions").([]string)
+		for _, permission := range permissions {
+			if slices.Contains(granted, permission) {
+				return c.Next()
+			}
+		}
+
+		return RoleRequired("admin")(c)
+	}
+}
+
+// PolicyRequired middleware to check the access policy allows the caller to perform the action
+// on a resource of the given type. The resource is identified by the "id" route parameter, if any;
+// services check policies needing further resource attributes themselves.
+func PolicyRequired(policies interfaces.PolicyService, action, resourceType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("role") == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		resource := policy.Attributes{"type": resourceType}
+		if id, err := strconv.ParseUint(c.Params("id"), 10, 32); err == nil {
+			resource["id"] = uint(id)
+		}
+
+		decision := policies.Authorize(CurrentActor(c), action, resource, policy.Attributes{"ip": c.IP()})
+		if !decision.Allowed {
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+		}
+		return c.Next()
+	}
+}
+
+// CurrentActor collects the authenticated principal set by Protected and LoadPermissions
+func CurrentActor(c *fiber.Ctx) dto.Actor {
+	actor := dto.Actor{
+		UserID: c.Locals("user_id").(uint),
+		Role:   c.Locals("role").(string),
+	}
+	actor.OrganizationID, _ = c.Locals("organization_id").(uint)
+	actor.OrganizationRole, _ = c.Locals("organization_role").(string)
+	actor.Permissions, _ = c.Locals("permissions").([]string)
+	actor.ServiceAccountID, _ = c.Locals("service_account_id").(uint)
+	actor.Scopes, _ = c.Locals("scopes").([]string)
+	actor.ImpersonatorID, _ = c.Locals("impersonator_id").(uint)
+	actor.Client = ClientInfo(c)
+	return actor
+}
+
+// ClientInfo captures the device and request details recorded on sessions and audit events
+func ClientInfo(c *fiber.Ctx) dto.ClientInfo {
+	client := dto.ClientInfo{
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IPAddress: c.IP(),
+	}
+	client.RequestID, _ = c.Locals("requestid").(string)
+	return client
+}
+
+// ScopeRequired middleware to check if a service account was granted one of the required scopes.
+// Human users are not affected; their access is governed by roles.
+func ScopeRequired(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsServiceAccount(c) {
+			return c.Next()
+		}
+
+		granted, _ := c.Locals("scopes").([]string)
+		for _, scope := range scopes {
+			if slices.Contains(granted, scope) {
+				c.Locals("scope_granted", true)
+				return c.Next()
+			}
+		}
+
+		return fiber.NewError(fiber.StatusForbidden, "Insufficient scope")
+	}
+}
+
+// HumanOnly middleware to reject service account principals
+func HumanOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsServiceAccount(c) {
+			return fiber.NewError(fiber.StatusForbidden, "Not available to service accounts")
+		}
+		return c.Next()
+	}
+}
+
+// NotImpersonating middleware to reject requests made with an impersonation token
+func NotImpersonating() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsImpersonating(c) {
+			return fiber.NewError(fiber.StatusForbidden, "Not available while impersonating")
+		}
+		return c.Next()
+	}
+}
+
+// IsImpersonating reports whether an admin is acting as the authenticated user
+func IsImpersonating(c *fiber.Ctx) bool {
+	impersonatorID, _ := c.Locals("impersonator_id").(uint)
+	return impersonatorID != 0
+}
+
+// IsServiceAccount reports whether the authenticated principal is a service account
+func IsServiceAccount(c *fiber.Ctx) bool {
+	principalType, _ := c.Locals("principal_type").(string)
+	return principalType == entity.PrincipalTypeServiceAccount
+}
+
+// PrincipalLabel identifies the authenticated principal for logs, e.g. "user:5" or "service_account:2"
+func PrincipalLabel(c *fiber.Ctx) string {
+	switch principalType, _ := c.Locals("principal_type").(string); principalType {
+	case entity.PrincipalTypeServiceAccount:
+		id, _ := c.Locals("service_account_id").(uint)
+		return fmt.Sprintf("%s:%d", entity.PrincipalTypeServiceAccount, id)
+	case entity.PrincipalTypeUser:
+		id, _ := c.Locals("user_id").(uint)
+		if impersonatorID, _ := c.Locals("impersonator_id").(uint); impersonatorID != 0 {
+			return fmt.Sprintf("%s:%d (impersonated by %s:%d)", entity.PrincipalTypeUser, id, entity.PrincipalTypeUser, impersonatorID)
+		}
+		return fmt.Sprintf("%s:%d", entity.PrincipalTypeUser, id)
+	default:
+		return "anonymous"
+	}
+}
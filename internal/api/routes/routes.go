@@ -5,11 +5,40 @@ import (
 
 	"user_crud/internal/api/controller"
 	"user_crud/internal/api/middleware"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/service/interfaces"
+	"user_crud/pkg/blobstore"
 )
 
-func SetupRoutes(app *fiber.App, userController *controller.UserController, authController *controller.AuthController) {
-	// Serve static files from public directory
-	app.Static("/images", "./public/images")
+func SetupRoutes(
+	app *fiber.App,
+	images blobstore.BlobStore,
+	protected fiber.Handler,
+	permissions fiber.Handler,
+	policies interfaces.PolicyService,
+	userController *controller.UserController,
+	authController *controller.AuthController,
+	serviceAccountController *controller.ServiceAccountController,
+	sessionController *controller.SessionController,
+	meController *controller.MeController,
+	invitationController *controller.InvitationController,
+	registrationController *controller.RegistrationController,
+	organizationController *controller.OrganizationController,
+	groupController *controller.GroupController,
+	roleGrantController *controller.RoleGrantController,
+	impersonationController *controller.ImpersonationController,
+	policyController *controller.PolicyController,
+	auditController *controller.AuditController,
+	webhookController *controller.WebhookController,
+	userStreamController *controller.UserStreamController,
+	realtimeController *controller.RealtimeController,
+	notificationController *controller.NotificationController,
+	fileController *controller.FileController,
+) {
+	// Serve images kept on the local disk; object stores hand out presigned URLs instead
+	if dir, ok := blobstore.LocalDirectory(images); ok {
+		app.Static("/images", dir)
+	}
 
 	// API routes
 	api := app.Group("/api")
@@ -19,12 +48,130 @@ func SetupRoutes(app *fiber.App, userController *controller.UserController, auth
 	auth.Post("/register", authController.Register)
 	auth.Post("/login", authController.Login)
 	auth.Post("/refresh", authController.RefreshToken)
+	auth.Post("/token", serviceAccountController.Token)
+	auth.Post("/logout", protected, middleware.HumanOnly(), sessionController.Logout)
+	auth.Post("/switch-organization", protected, middleware.HumanOnly(), sessionController.SwitchOrganization)
+	auth.Post("/verify-email", meController.VerifyEmail)
+	auth.Post("/set-password", userController.SetPassword)
 
-	// User routes (protected)
-	users := api.Group("/users", middleware.Protected())
-	users.Post("/", middleware.RoleRequired("admin"), userController.CreateUser)
-	users.Get("/", userController.GetAllUsers)
-	users.Get("/:id", userController.GetUser)
-	users.Put("/:id", userController.UpdateUser)
-	users.Delete("/:id", middleware.RoleRequired("admin"), userController.DeleteUser)
+	// WebSocket channel for presence and notifications; browsers may send the token as a subprotocol
+	api.Get("/ws", middleware.WebSocketToken(), protected, middleware.HumanOnly(), realtimeController.Connect)
+
+	// Current user routes (protected)
+	me := api.Group("/me", protected, middleware.HumanOnly())
+	me.Get("/", meController.GetProfile)
+	me.Put("/", meController.UpdateProfile)
+	me.Delete("/", middleware.NotImpersonating(), meController.DeleteAccount)
+	me.Put("/password", middleware.NotImpersonating(), meController.ChangePassword)
+	me.Post("/email", middleware.NotImpersonating(), meController.ChangeEmail)
+	me.Put("/avatar", meController.UploadAvatar)
+	me.Delete("/avatar", meController.DeleteAvatar)
+	me.Get("/sessions", sessionController.GetMySessions)
+	me.Delete("/sessions/:id", sessionController.RevokeMySession)
+	me.Get("/organizations", organizationController.GetMyOrganizations)
+	me.Get("/role-grants", roleGrantController.GetMyGrants)
+	// Notification center; an impersonating admin must not mark the user's notifications as seen
+	me.Get("/notifications", notificationController.GetNotifications)
+	me.Post("/notifications/read-all", middleware.NotImpersonating(), notificationController.MarkAllRead)
+	me.Post("/notifications/:id/read", middleware.NotImpersonating(), notificationController.MarkRead)
+	me.Get("/notification-preferences", notificationController.GetPreferences)
+	me.Put("/notification-preferences", middleware.NotImpersonating(), notificationController.UpdatePreferences)
+
+	// User routes (protected); results are limited to the caller's organization unless they are a global admin
+	users := api.Group("/users", protected, permissions)
+	users.Post("/", middleware.ScopeRequired(entity.ScopeUsersWrite), middleware.PermissionRequired(entity.ScopeUsersWrite), userController.CreateUser)
+	users.Get("/", middleware.ScopeRequired(entity.ScopeUsersRead), userController.GetAllUsers)
+	// Server-Sent Events of user changes, registered before "/:id" so it is not taken for an ID
+	users.Get("/stream", middleware.ScopeRequired(entity.ScopeUsersRead), userStreamController.Stream)
+	users.Get("/presence", middleware.ScopeRequired(entity.ScopeUsersRead), realtimeController.GetPresence)
+	users.Get("/:id", middleware.ScopeRequired(entity.ScopeUsersRead), userController.GetUser)
+	users.Put("/:id", middleware.ScopeRequired(entity.ScopeUsersWrite), userController.UpdateUser)
+	users.Get("/:id/files", middleware.ScopeRequired(entity.ScopeUsersRead), fileController.ListFiles)
+	users.Post("/:id/files", middleware.ScopeRequired(entity.ScopeUsersWrite), fileController.UploadFile)
+	users.Get("/:id/files/:fileId", middleware.ScopeRequired(entity.ScopeUsersRead), fileController.GetFile)
+	users.Get("/:id/files/:fileId/download", middleware.ScopeRequired(entity.ScopeUsersRead), fileController.DownloadFile)
+	users.Delete("/:id/files/:fileId", middleware.ScopeRequired(entity.ScopeUsersWrite), fileController.DeleteFile)
+	users.Delete("/:id", middleware.ScopeRequired(entity.ScopeUsersDelete), middleware.PermissionRequired(entity.ScopeUsersDelete), userController.DeleteUser)
+	users.Post("/:id/unlock", middleware.RoleRequired("admin"), authController.UnlockUser)
+	users.Get("/:id/sessions", middleware.RoleRequired("admin"), sessionController.GetUserSessions)
+	users.Delete("/:id/sessions", middleware.RoleRequired("admin"), sessionController.RevokeAllUserSessions)
+	users.Delete("/:id/sessions/:sessionId", middleware.RoleRequired("admin"), sessionController.RevokeUserSession)
+	users.Post("/:id/impersonate", middleware.HumanOnly(), middleware.NotImpersonating(), middleware.RoleRequired("admin"), impersonationController.Impersonate)
+
+	// Invitation routes; accepting is public and authenticated by the invitation token
+	api.Post("/invitations/accept", invitationController.AcceptInvitation)
+	invitations := api.Group("/invitations", protected, middleware.HumanOnly(), middleware.RoleRequired("admin"))
+	invitations.Post("/", invitationController.CreateInvitation)
+	invitations.Get("/", invitationController.GetAllInvitations)
+	invitations.Delete("/:id", invitationController.RevokeInvitation)
+
+	// Registration approval queue (admin only)
+	registrations := api.Group("/registrations", protected, middleware.HumanOnly(), middleware.RoleRequired("admin"))
+	registrations.Get("/", registrationController.GetPendingRegistrations)
+	registrations.Post("/:id/approve", registrationController.ApproveRegistration)
+	registrations.Post("/:id/reject", registrationController.RejectRegistration)
+
+	// Organization routes; global admins manage all organizations, organization admins their own
+	organizations := api.Group("/organizations", protected, middleware.HumanOnly())
+	organizations.Post("/", middleware.RoleRequired("admin"), organizationController.CreateOrganization)
+	organizations.Get("/", middleware.RoleRequired("admin"), organizationController.GetAllOrganizations)
+	organizations.Get("/:id", organizationController.GetOrganization)
+	organizations.Put("/:id", organizationController.UpdateOrganization)
+	organizations.Delete("/:id", middleware.RoleRequired("admin"), organizationController.DeleteOrganization)
+	organizations.Get("/:id/members", organizationController.GetMembers)
+	organizations.Post("/:id/members", middleware.RoleRequired("admin"), organizationController.AddMember)
+	organizations.Put("/:id/members/:userId", organizationController.UpdateMember)
+	organizations.Delete("/:id/members/:userId", organizationController.RemoveMember)
+	organizations.Post("/:id/members/:userId/move", middleware.RoleRequired("admin"), organizationController.MoveMember)
+
+	// Group routes; groups belong to the caller's current organization
+	groups := api.Group("/groups", protected, middleware.HumanOnly(), permissions)
+	groups.Post("/", groupController.CreateGroup)
+	groups.Get("/", groupController.GetGroups)
+	groups.Get("/:id", groupController.GetGroup)
+	groups.Put("/:id", groupController.UpdateGroup)
+	groups.Delete("/:id", groupController.DeleteGroup)
+	groups.Get("/:id/members", groupController.GetMembers)
+	groups.Post("/:id/members", groupController.AddMember)
+	groups.Delete("/:id/members/:userId", groupController.RemoveMember)
+
+	// Temporary role grants; anyone may request one, admins decide and grantees may give theirs up
+	roleGrants := api.Group("/role-grants", protected, middleware.HumanOnly())
+	roleGrants.Post("/", middleware.NotImpersonating(), roleGrantController.RequestGrant)
+	roleGrants.Get("/", middleware.RoleRequired("admin"), roleGrantController.GetGrants)
+	roleGrants.Post("/:id/approve", middleware.RoleRequired("admin"), roleGrantController.ApproveGrant)
+	roleGrants.Post("/:id/reject", middleware.RoleRequired("admin"), roleGrantController.RejectGrant)
+	roleGrants.Post("/:id/revoke", roleGrantController.RevokeGrant)
+
+	// Access policy inspection and debugging
+	policy := api.Group("/policy", protected, middleware.HumanOnly(), permissions)
+	policy.Get("/", middleware.PolicyRequired(policies, entity.ActionPolicyExplain, entity.ResourceTypePolicy), policyController.GetPolicy)
+	policy.Post("/explain", middleware.PolicyRequired(policies, entity.ActionPolicyExplain, entity.ResourceTypePolicy), policyController.Explain)
+	policy.Post("/reload", middleware.PolicyRequired(policies, entity.ActionPolicyManage, entity.ResourceTypePolicy), policyController.Reload)
+
+	// Audit log (admin only)
+	auditEvents := api.Group("/audit-events", protected, middleware.HumanOnly(), middleware.RoleRequired("admin"))
+	auditEvents.Get("/", auditController.GetEvents)
+	auditEvents.Get("/export", auditController.ExportEvents)
+	auditEvents.Get("/verify", auditController.VerifyChain)
+
+	// Service account routes (admin only, never available to service accounts themselves)
+	serviceAccounts := api.Group("/service-accounts", protected, middleware.HumanOnly(), middleware.RoleRequired("admin"))
+	serviceAccounts.Post("/", serviceAccountController.CreateServiceAccount)
+	serviceAccounts.Get("/", serviceAccountController.GetAllServiceAccounts)
+	serviceAccounts.Get("/:id", serviceAccountController.GetServiceAccount)
+	serviceAccounts.Put("/:id", serviceAccountController.UpdateServiceAccount)
+	serviceAccounts.Post("/:id/rotate-secret", serviceAccountController.RotateSecret)
+	serviceAccounts.Delete("/:id", serviceAccountController.DeleteServiceAccount)
+
+	// Webhook subscriptions and their delivery log (admin only)
+	webhooks := api.Group("/webhooks", protected, middleware.HumanOnly(), middleware.RoleRequired("admin"))
+	webhooks.Post("/", webhookController.CreateWebhook)
+	webhooks.Get("/", webhookController.GetWebhooks)
+	webhooks.Get("/:id", webhookController.GetWebhook)
+	webhooks.Put("/:id", webhookController.UpdateWebhook)
+	webhooks.Post("/:id/rotate-secret", webhookController.RotateSecret)
+	webhooks.Delete("/:id", webhookController.DeleteWebhook)
+	webhooks.Get("/:id/deliveries", webhookController.GetDeliveries)
+	webhooks.Post("/:id/deliveries/:deliveryId/redeliver", webhookController.Redeliver)
 }
@@ -1,24 +1,316 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// Registration modes
+const (
+	RegistrationModeOpen            = "open"
+	RegistrationModeInviteOnly      = "invite_only"
+	RegistrationModeDomainAllowlist = "domain_allowlist" // only addresses in RegistrationAllowedDomains
+	RegistrationModeApproval        = "approval"         // new accounts wait for an admin to approve them
 )
 
 type Config struct {
 	DatabaseDSN string
 	ServerPort  int
 	ServerHost  string
+	AppBaseURL  string // used to build links in emails
+
+	// Registration; RegistrationModeInviteOnly disables public registration
+	RegistrationMode           string
+	RegistrationAllowedDomains []string // comma separated in the environment
+	DisposableEmailDomainsFile string   // one domain per line, check disabled when empty
+
+	// Organization self-registered and invited users join; created on startup
+	DefaultOrganizationName string
+	DefaultOrganizationSlug string
+
+	// Add effective group names and permissions to access tokens
+	TokenIncludeGroups bool
+
+	// Temporary role grants
+	RoleGrantMaxDuration   time.Duration // longest time window a grant may cover
+	RoleGrantSweepInterval time.Duration // how often ended grants are marked expired
+
+	// Lifetime of access tokens admins receive when impersonating a user
+	ImpersonationTokenExpiry time.Duration
+
+	// JSON access policy; the embedded DefaultPolicy is used when empty
+	PolicyFile string
+
+	// Webhook delivery
+	WebhookTimeout          time.Duration // per attempt
+	WebhookMaxAttempts      int           // attempts before a delivery is dead-lettered
+	WebhookRetryBaseDelay   time.Duration // wait after the first failed attempt, doubling with every further one
+	WebhookRetryMaxDelay    time.Duration
+	WebhookDispatchInterval time.Duration // how often due deliveries are sent
+
+	// Domain event outbox and the sinks it publishes to
+	OutboxDispatchInterval time.Duration // how often pending events are published
+	OutboxMaxAttempts      int           // attempts before an event is given up
+	OutboxRetryBaseDelay   time.Duration // wait after the first failed attempt, doubling with every further one
+	OutboxRetryMaxDelay    time.Duration
+	OutboxRetention        time.Duration // how long published events are kept
+	EventSinkTimeout       time.Duration // per publish to a sink
+	NATSURL                string        // e.g. nats://localhost:4222; NATS publishing is off when empty
+	NATSSubjectPrefix      string
+	KafkaRESTURL           string // Kafka REST proxy; Kafka publishing is off when empty
+	KafkaTopic             string
+
+	// Server-Sent Events stream of user changes
+	UserStreamBufferSize        int           // events kept for clients resuming with Last-Event-ID
+	UserStreamHeartbeatInterval time.Duration // keepalive comments sent while no event arrives
+
+	// WebSocket channel for presence and notifications
+	RealtimeHub              string        // "memory" for a single instance, "nats" to share presence and notifications through NATSURL
+	WebSocketPingInterval    time.Duration // pings sent to detect dead connections; clients must answer within twice this
+	PresenceAnnounceInterval time.Duration // how often an instance announces its online users to the others
+
+	// In-app notifications
+	NotificationRetention     time.Duration // how long notifications are kept, read or not
+	NotificationSweepInterval time.Duration // how often notifications past retention are removed
+
+	// Storage of uploaded images
+	ImageStore        string // "local" to keep them in ImageDir and serve them under /images, "s3" for an S3-compatible bucket
+	ImageDir          string
+	ImageUploadDir    string // raw uploads waiting for processing with the local store; never served
+	ImageMaxBytes     int64  // largest accepted upload; request bodies may exceed it by a MiB for the other form fields
+	ImageMaxWidth     int
+	ImageMaxHeight    int
+	ImageVariantSizes []int         // edge lengths of the square thumbnails made of every image, comma separated in the environment
+	ImageURLExpiry    time.Duration // validity of presigned image URLs handed out with the s3 store
+	S3Endpoint        string        // e.g. http://localhost:9000 for MinIO; Amazon S3 of S3Region when empty
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool // address the bucket in the path instead of the host name
+	S3Timeout         time.Duration
+
+	// Attachments uploaded for users; kept in FileDir with the local image store, in the S3 bucket otherwise
+	FileDir          string
+	FileMaxBytes     int64    // request bodies may exceed it by a MiB like ImageMaxBytes
+	FileAllowedTypes []string // media types sniffed from the content, comma separated in the environment
+	FileMaxPerUser   int      // attachments a user can have
+
+	// Media processing
+	MediaWorkers           int           // images processed at the same time
+	MediaPollInterval      time.Duration // how often the workers look for uploads when not woken up
+	MediaProcessingTimeout time.Duration // after which an unfinished attempt is taken over by another worker
+	MediaMaxAttempts       int           // attempts before an upload is given up
+	MediaRetryBaseDelay    time.Duration // wait after the first failed attempt, doubling with every further one
+	MediaRetryMaxDelay     time.Duration
+
+	// Outgoing mail; emails are only logged when SMTPHost is empty
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// Password hashing
+	PasswordHashAlgorithm string // "argon2id" or "bcrypt"
+	Argon2Memory          int    // KiB
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	BcryptCost            int
+
+	// Password policy
+	PasswordMinLength       int
+	PasswordMaxLength       int
+	PasswordRequireUpper    bool
+	PasswordRequireLower    bool
+	PasswordRequireDigit    bool
+	PasswordRequireSymbol   bool
+	PasswordCheckSimilarity bool   // reject passwords resembling the user's name or email
+	PasswordHistorySize     int    // number of previous passwords that cannot be reused
+	BreachedPasswordsDir    string // local k-anonymity range files, check disabled when empty
+
+	// Brute-force protection
+	LoginAttemptStore       string // "memory" for a single node, "sql" to share state between nodes
+	LoginMaxAccountFailures int
+	LoginMaxIPFailures      int
+	LoginDelayAfterFailures int
+	LoginBaseDelay          time.Duration
+	LoginMaxDelay           time.Duration
+	LoginFailureWindow      time.Duration
+	LoginLockoutDuration    time.Duration
 }
 
-func NewConfig() *Config {
+// NewConfig reads the configuration from the environment and reports settings the
+// application cannot run with
+func NewConfig() (*Config, error) {
 	config := &Config{
 		DatabaseDSN: getEnv("DATABASE_DSN", "user_crud.db"),
 		ServerPort:  getEnvAsInt("SERVER_PORT", 8080),
 		ServerHost:  getEnv("SERVER_HOST", "0.0.0.0"),
+		AppBaseURL:  getEnv("APP_BASE_URL", "http://localhost:8080"),
+
+		RegistrationMode:           getEnv("REGISTRATION_MODE", RegistrationModeOpen),
+		RegistrationAllowedDomains: getEnvAsList("REGISTRATION_ALLOWED_DOMAINS"),
+		DisposableEmailDomainsFile: getEnv("DISPOSABLE_EMAIL_DOMAINS_FILE", ""),
+
+		DefaultOrganizationName: getEnv("DEFAULT_ORGANIZATION_NAME", "Default"),
+		DefaultOrganizationSlug: getEnv("DEFAULT_ORGANIZATION_SLUG", "default"),
+
+		TokenIncludeGroups: getEnvAsBool("TOKEN_INCLUDE_GROUPS", false),
+
+		RoleGrantMaxDuration:   getEnvAsDuration("ROLE_GRANT_MAX_DURATION", 8*time.Hour),
+		RoleGrantSweepInterval: getEnvAsDuration("ROLE_GRANT_SWEEP_INTERVAL", time.Minute),
+
+		ImpersonationTokenExpiry: getEnvAsDuration("IMPERSONATION_TOKEN_EXPIRY", 15*time.Minute),
+
+		PolicyFile: getEnv("POLICY_FILE", ""),
+
+		WebhookTimeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:      getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBaseDelay:   getEnvAsDuration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+		WebhookRetryMaxDelay:    getEnvAsDuration("WEBHOOK_RETRY_MAX_DELAY", 6*time.Hour),
+		WebhookDispatchInterval: getEnvAsDuration("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second),
+
+		OutboxDispatchInterval: getEnvAsDuration("OUTBOX_DISPATCH_INTERVAL", time.Second),
+		OutboxMaxAttempts:      getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 20),
+		OutboxRetryBaseDelay:   getEnvAsDuration("OUTBOX_RETRY_BASE_DELAY", 5*time.Second),
+		OutboxRetryMaxDelay:    getEnvAsDuration("OUTBOX_RETRY_MAX_DELAY", time.Hour),
+		OutboxRetention:        getEnvAsDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+		EventSinkTimeout:       getEnvAsDuration("EVENT_SINK_TIMEOUT", 10*time.Second),
+		NATSURL:                getEnv("NATS_URL", ""),
+		NATSSubjectPrefix:      getEnv("NATS_SUBJECT_PREFIX", "user_crud"),
+		KafkaRESTURL:           getEnv("KAFKA_REST_URL", ""),
+		KafkaTopic:             getEnv("KAFKA_TOPIC", "user_crud.events"),
+
+		UserStreamBufferSize:        getEnvAsInt("USER_STREAM_BUFFER_SIZE", 1000),
+		UserStreamHeartbeatInterval: getEnvAsDuration("USER_STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+
+		RealtimeHub:              getEnv("REALTIME_HUB", "memory"),
+		WebSocketPingInterval:    getEnvAsDuration("WEBSOCKET_PING_INTERVAL", 30*time.Second),
+		PresenceAnnounceInterval: getEnvAsDuration("PRESENCE_ANNOUNCE_INTERVAL", 30*time.Second),
+
+		NotificationRetention:     getEnvAsDuration("NOTIFICATION_RETENTION", 90*24*time.Hour),
+		NotificationSweepInterval: getEnvAsDuration("NOTIFICATION_SWEEP_INTERVAL", time.Hour),
+
+		ImageStore:        getEnv("IMAGE_STORE", "local"),
+		ImageDir:          getEnv("IMAGE_DIR", "public/images"),
+		ImageUploadDir:    getEnv("IMAGE_UPLOAD_DIR", "uploads"),
+		ImageMaxBytes:     int64(getEnvAsInt("IMAGE_MAX_BYTES", 5*1024*1024)),
+		ImageMaxWidth:     getEnvAsInt("IMAGE_MAX_WIDTH", 4096),
+		ImageMaxHeight:    getEnvAsInt("IMAGE_MAX_HEIGHT", 4096),
+		ImageVariantSizes: getEnvAsIntList("IMAGE_VARIANT_SIZES", []int{64, 256, 1024}),
+		ImageURLExpiry:    getEnvAsDuration("IMAGE_URL_EXPIRY", time.Hour),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:       getEnvAsBool("S3_PATH_STYLE", true),
+		S3Timeout:         getEnvAsDuration("S3_TIMEOUT", 30*time.Second),
+
+		FileDir:      getEnv("FILE_DIR", "files"),
+		FileMaxBytes: int64(getEnvAsInt("FILE_MAX_BYTES", 10*1024*1024)),
+		FileAllowedTypes: getEnvAsListOr("FILE_ALLOWED_TYPES", []string{
+			"application/pdf", "application/zip", "text/plain", "image/jpeg", "image/png", "image/gif", "image/webp",
+		}),
+		FileMaxPerUser: getEnvAsInt("FILE_MAX_PER_USER", 100),
+
+		MediaWorkers:           getEnvAsInt("MEDIA_WORKERS", 4),
+		MediaPollInterval:      getEnvAsDuration("MEDIA_POLL_INTERVAL", 2*time.Second),
+		MediaProcessingTimeout: getEnvAsDuration("MEDIA_PROCESSING_TIMEOUT", 2*time.Minute),
+		MediaMaxAttempts:       getEnvAsInt("MEDIA_MAX_ATTEMPTS", 5),
+		MediaRetryBaseDelay:    getEnvAsDuration("MEDIA_RETRY_BASE_DELAY", 10*time.Second),
+		MediaRetryMaxDelay:     getEnvAsDuration("MEDIA_RETRY_MAX_DELAY", 10*time.Minute),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+
+		PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2Memory:          getEnvAsInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Iterations:      getEnvAsInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:     getEnvAsInt("ARGON2_PARALLELISM", 2),
+		BcryptCost:            getEnvAsInt("BCRYPT_COST", 10),
+
+		PasswordMinLength:       getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:       getEnvAsInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireUpper:    getEnvAsBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:    getEnvAsBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:    getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:   getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordCheckSimilarity: getEnvAsBool("PASSWORD_CHECK_SIMILARITY", true),
+		PasswordHistorySize:     getEnvAsInt("PASSWORD_HISTORY_SIZE", 5),
+		BreachedPasswordsDir:    getEnv("BREACHED_PASSWORDS_DIR", ""),
+
+		LoginAttemptStore:       getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+		LoginMaxAccountFailures: getEnvAsInt("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      getEnvAsInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginDelayAfterFailures: getEnvAsInt("LOGIN_DELAY_AFTER_FAILURES", 2),
+		LoginBaseDelay:          getEnvAsDuration("LOGIN_BASE_DELAY", time.Second),
+		LoginMaxDelay:           getEnvAsDuration("LOGIN_MAX_DELAY", 30*time.Second),
+		LoginFailureWindow:      getEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration:    getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
 	}
 
-	return config
+	if err := config.validateDurations(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validateDurations checks the duration settings, which must be valid positive durations.
+// Unlike other settings they do not fall back to their default when malformed, so a typo
+// is not silently ignored.
+func (c *Config) validateDurations() error {
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"LOGIN_BASE_DELAY", c.LoginBaseDelay},
+		{"LOGIN_MAX_DELAY", c.LoginMaxDelay},
+		{"LOGIN_FAILURE_WINDOW", c.LoginFailureWindow},
+		{"LOGIN_LOCKOUT_DURATION", c.LoginLockoutDuration},
+		{"ROLE_GRANT_MAX_DURATION", c.RoleGrantMaxDuration},
+		{"ROLE_GRANT_SWEEP_INTERVAL", c.RoleGrantSweepInterval},
+		{"IMPERSONATION_TOKEN_EXPIRY", c.ImpersonationTokenExpiry},
+		{"WEBHOOK_TIMEOUT", c.WebhookTimeout},
+		{"WEBHOOK_RETRY_BASE_DELAY", c.WebhookRetryBaseDelay},
+		{"WEBHOOK_RETRY_MAX_DELAY", c.WebhookRetryMaxDelay},
+		{"WEBHOOK_DISPATCH_INTERVAL", c.WebhookDispatchInterval},
+		{"OUTBOX_DISPATCH_INTERVAL", c.OutboxDispatchInterval},
+		{"OUTBOX_RETRY_BASE_DELAY", c.OutboxRetryBaseDelay},
+		{"OUTBOX_RETRY_MAX_DELAY", c.OutboxRetryMaxDelay},
+		{"OUTBOX_RETENTION", c.OutboxRetention},
+		{"EVENT_SINK_TIMEOUT", c.EventSinkTimeout},
+		{"USER_STREAM_HEARTBEAT_INTERVAL", c.UserStreamHeartbeatInterval},
+		{"WEBSOCKET_PING_INTERVAL", c.WebSocketPingInterval},
+		{"PRESENCE_ANNOUNCE_INTERVAL", c.PresenceAnnounceInterval},
+		{"NOTIFICATION_RETENTION", c.NotificationRetention},
+		{"NOTIFICATION_SWEEP_INTERVAL", c.NotificationSweepInterval},
+		{"IMAGE_URL_EXPIRY", c.ImageURLExpiry},
+		{"S3_TIMEOUT", c.S3Timeout},
+		{"MEDIA_POLL_INTERVAL", c.MediaPollInterval},
+		{"MEDIA_PROCESSING_TIMEOUT", c.MediaProcessingTimeout},
+		{"MEDIA_RETRY_BASE_DELAY", c.MediaRetryBaseDelay},
+		{"MEDIA_RETRY_MAX_DELAY", c.MediaRetryMaxDelay},
+	}
+
+	for _, duration := range durations {
+		if valueStr, exists := os.LookupEnv(duration.key); exists {
+			if _, err := time.ParseDuration(valueStr); err != nil {
+				return fmt.Errorf("%s must be a duration such as \"30s\", got %q", duration.key, valueStr)
+			}
+		}
+		if duration.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", duration.key, duration.value)
+		}
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -35,3 +327,56 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a comma separated value, dropping empty items
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvAsListOr is getEnvAsList falling back to defaultValue when the list is empty
+func getEnvAsListOr(key string, defaultValue []string) []string {
+	if values := getEnvAsList(key); len(values) > 0 {
+		return values
+	}
+	return defaultValue
+}
+
+// getEnvAsIntList splits a comma separated list of positive numbers, falling back to
+// defaultValue when any item is not one
+func getEnvAsIntList(key string, defaultValue []int) []int {
+	var values []int
+	for _, item := range getEnvAsList(key) {
+		value, err := strconv.Atoi(item)
+		if err != nil || value <= 0 {
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// getEnvAsDuration reads values such as "30s" or "15m"
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConfigValidatesDurations(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		err   string
+	}{
+		{"default", "", "", ""},
+		{"valid", "LOGIN_LOCKOUT_DURATION", "30m", ""},
+		{"zero", "LOGIN_LOCKOUT_DURATION", "0s", "LOGIN_LOCKOUT_DURATION must be positive, got 0s"},
+		{"malformed", "LOGIN_FAILURE_WINDOW", "15", `LOGIN_FAILURE_WINDOW must be a duration such as "30s", got "15"`},
+		{"empty", "LOGIN_BASE_DELAY", "", `LOGIN_BASE_DELAY must be a duration such as "30s", got ""`},
+		{"role grant sweep", "ROLE_GRANT_SWEEP_INTERVAL", "0", "ROLE_GRANT_SWEEP_INTERVAL must be positive, got 0s"},
+		{"impersonation token expiry", "IMPERSONATION_TOKEN_EXPIRY", "-5m", "IMPERSONATION_TOKEN_EXPIRY must be positive, got -5m0s"},
+		{"webhook dispatch", "WEBHOOK_DISPATCH_INTERVAL", "0s", "WEBHOOK_DISPATCH_INTERVAL must be positive, got 0s"},
+		{"outbox retention", "OUTBOX_RETENTION", "7d", `OUTBOX_RETENTION must be a duration such as "30s", got "7d"`},
+		{"user stream heartbeat", "USER_STREAM_HEARTBEAT_INTERVAL", "0s", "USER_STREAM_HEARTBEAT_INTERVAL must be positive, got 0s"},
+		{"presence announce", "PRESENCE_ANNOUNCE_INTERVAL", "30", `PRESENCE_ANNOUNCE_INTERVAL must be a duration such as "30s", got "30"`},
+		{"notification sweep", "NOTIFICATION_SWEEP_INTERVAL", "", `NOTIFICATION_SWEEP_INTERVAL must be a duration such as "30s", got ""`},
+		{"s3 timeout", "S3_TIMEOUT", "-1s", "S3_TIMEOUT must be positive, got -1s"},
+		{"media poll", "MEDIA_POLL_INTERVAL", "-1s", "MEDIA_POLL_INTERVAL must be positive, got -1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.key != "" {
+				t.Setenv(tt.key, tt.value)
+			}
+
+			config, err := NewConfig()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("expected the configuration to be valid, got %v", err)
+				}
+				if tt.key == "LOGIN_LOCKOUT_DURATION" && config.LoginLockoutDuration != 30*time.Minute {
+					t.Errorf("expected 30m, got %s", config.LoginLockoutDuration)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package config
+
+import _ "embed"
+
+// DefaultPolicy is the access policy used when PolicyFile is not set
+//
+//go:embed default_policy.json
+var DefaultPolicy []byte
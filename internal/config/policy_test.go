@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"testing"
+
+	"user_crud/internal/config"
+	"user_crud/pkg/policy"
+	"user_crud/pkg/policy/policytest"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	engine := policytest.Engine(t, string(config.DefaultPolicy))
+
+	user := func(role string) *policytest.RequestBuilder {
+		return policytest.NewRequest("").Subject("id", 1).Subject("type", "user").Subject("role", role).
+			Subject("organization_id", 10).Subject("organization_role", "member").
+			Subject("permissions", []string{}).Subject("scopes", []string{})
+	}
+	serviceAccount := func(scopes ...string) *policytest.RequestBuilder {
+		return policytest.NewRequest("").Subject("id", 0).Subject("type", "service_account").
+			Subject("role", "service_account").Subject("organization_id", 0).
+			Subject("permissions", []string{}).Subject("scopes", scopes)
+	}
+	target := func(builder *policytest.RequestBuilder, action string, id uint, role string, organizationIDs ...uint) policy.Request {
+		req := builder.Resource("type", "user").Resource("id", id).Resource("role", role).
+			Resource("organization_ids", organizationIDs).Build()
+		req.Action = action
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		req     policy.Request
+		allowed bool
+		ruleID  string
+	}{
+		{"admin deletes a user", target(user("admin"), "users:delete", 2, "user"), true, "admins"},
+		{"admin deletes an admin", target(user("admin"), "users:delete", 2, "admin"), true, "admins"},
+		{"admin manages the policy", func() policy.Request {
+			req := user("admin").Resource("type", "policy").Build()
+			req.Action = "policy:manage"
+			return req
+		}(), true, "admins"},
+		{"user updates themselves", target(user("user"), "users:update", 1, "user", 10), true, "update-self"},
+		{"user updates someone else", target(user("user"), "users:update", 2, "user", 10), false, ""},
+		{"user deletes themselves", target(user("user"), "users:delete", 1, "user", 10), false, ""},
+		{"organization admin updates a member", target(user("user").Subject("organization_role", "admin"), "users:update", 2, "user", 10), true, "organization-admins"},
+		{"organization admin updates an outsider", target(user("user").Subject("organization_role", "admin"), "users:update", 2, "user", 11), false, ""},
+		{"organization admin updates an admin", target(user("user").Subject("organization_role", "admin"), "users:update", 2, "admin", 10), false, "protect-admins"},
+		{"group member with users:write", target(user("user").Subject("permissions", []string{"users:write"}), "users:update", 2, "user", 10), true, "group-users-write"},
+		{"group member with users:write deletes", target(user("user").Subject("permissions", []string{"users:write"}), "users:delete", 2, "user", 10), false, ""},
+		{"group member with users:delete", target(user("user").Subject("permissions", []string{"users:delete"}), "users:delete", 2, "user", 10), true, "group-users-delete"},
+		{"moderator updates a member", target(user("moderator"), "users:update", 2, "user", 10), true, "moderators-update-organization-users"},
+		{"moderator updates an admin", target(user("moderator"), "users:update", 2, "admin", 10), false, "protect-admins"},
+		{"moderator deletes a member", target(user("moderator"), "users:delete", 2, "user", 10), false, ""},
+		{"service account with users:write", target(serviceAccount("users:write"), "users:update", 2, "user"), true, "service-accounts-users-write"},
+		{"service account without scopes", target(serviceAccount("users:read"), "users:update", 2, "user"), false, ""},
+		{"service account with users:delete", target(serviceAccount("users:delete"), "users:delete", 2, "user"), true, "service-accounts-users-delete"},
+		{"service account deletes an admin", target(serviceAccount("users:delete"), "users:delete", 2, "admin"), false, "protect-admins"},
+		{"service account manages the policy", func() policy.Request {
+			req := serviceAccount("users:write", "users:delete").Resource("type", "policy").Build()
+			req.Action = "policy:manage"
+			return req
+		}(), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decision policy.Decision
+			if tt.allowed {
+				decision = policytest.AssertAllowed(t, engine, tt.req)
+			} else {
+				decision = policytest.AssertDenied(t, engine, tt.req)
+			}
+			if decision.RuleID != tt.ruleID {
+				t.Errorf("expected rule %q to decide, got %q", tt.ruleID, decision.RuleID)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"time"
+)
+
+// Principals recorded as the actor of an audit event, besides users and service accounts
+const (
+	AuditActorAnonymous = "anonymous" // unauthenticated request, e.g. a failed login
+	AuditActorSystem    = "system"    // background jobs
+)
+
+// Audit event actions
+const (
+	AuditActionRegistered                = "auth.registered"
+	AuditActionLoginSucceeded            = "auth.login_succeeded"
+	AuditActionLoginFailed               = "auth.login_failed"
+	AuditActionRefreshTokenReused        = "auth.refresh_token_reused"
+	AuditActionAccountLocked             = "auth.account_locked"
+	AuditActionAddressLocked             = "auth.address_locked"
+	AuditActionAccountUnlocked           = "auth.account_unlocked"
+	AuditActionUserCreated               = "user.created"
+	AuditActionUserUpdated               = "user.updated"
+	AuditActionUserDeleted               = "user.deleted"
+	AuditActionPasswordChanged           = "user.password_changed"
+	AuditActionPasswordSet               = "user.password_set"
+	AuditActionEmailChangeRequested      = "user.email_change_requested"
+	AuditActionEmailChanged              = "user.email_changed"
+	AuditActionAvatarUpdated             = "user.avatar_updated"
+	AuditActionAvatarDeleted             = "user.avatar_deleted"
+	AuditActionAccountDeleted            = "user.account_deleted"
+	AuditActionRegistrationApproved      = "registration.approved"
+	AuditActionRegistrationRejected      = "registration.rejected"
+	AuditActionOrganizationCreated       = "organization.created"
+	AuditActionOrganizationUpdated       = "organization.updated"
+	AuditActionOrganizationDeleted       = "organization.deleted"
+	AuditActionMemberAdded               = "organization.member_added"
+	AuditActionMemberUpdated             = "organization.member_updated"
+	AuditActionMemberRemoved             = "organization.member_removed"
+	AuditActionMemberMoved               = "organization.member_moved"
+	AuditActionGroupCreated              = "group.created"
+	AuditActionGroupUpdated              = "group.updated"
+	AuditActionGroupDeleted              = "group.deleted"
+	AuditActionGroupMemberAdded          = "group.member_added"
+	AuditActionGroupMemberRemoved        = "group.member_removed"
+	AuditActionRoleGrantRequested        = "role_grant.requested"
+	AuditActionRoleGrantGranted          = "role_grant.granted"
+	AuditActionRoleGrantApproved         = "role_grant.approved"
+	AuditActionRoleGrantRejected         = "role_grant.rejected"
+	AuditActionRoleGrantRevoked          = "role_grant.revoked"
+	AuditActionRoleGrantExpired          = "role_grant.expired"
+	AuditActionImpersonationStarted      = "impersonation.started"
+	AuditActionImpersonatedRequest       = "impersonation.request"
+	AuditActionServiceAccountCreated     = "service_account.created"
+	AuditActionServiceAccountUpdated     = "service_account.updated"
+	AuditActionServiceAccountRotated     = "service_account.secret_rotated"
+	AuditActionServiceAccountDeleted     = "service_account.deleted"
+	AuditActionServiceAccountTokenIssued = "service_account.token_issued"
+	AuditActionPolicyReloaded            = "policy.reloaded"
+	AuditActionWebhookCreated            = "webhook.created"
+	AuditActionWebhookUpdated            = "webhook.updated"
+	AuditActionWebhookRotated            = "webhook.secret_rotated"
+	AuditActionWebhookDeleted            = "webhook.deleted"
+	AuditActionWebhookRedelivered        = "webhook.redelivered"
+	AuditActionFileUploaded              = "file.uploaded"
+	AuditActionFileDeleted               = "file.deleted"
+)
+
+// Audit event target types
+const (
+	AuditTargetUser           = "user"
+	AuditTargetOrganization   = "organization"
+	AuditTargetGroup          = "group"
+	AuditTargetRoleGrant      = "role_grant"
+	AuditTargetServiceAccount = "service_account"
+	AuditTargetSession        = "session"
+	AuditTargetPolicy         = "policy"
+	AuditTargetWebhook        = "webhook"
+	AuditTargetFile           = "file"
+)
+
+// AuditEvent is an entry of the append-only audit log. Every event stores the hash of its
+// predecessor and a hash over its own content, so changing or removing an event breaks the chain.
+type AuditEvent struct {
+	ID             uint      `gorm:"primaryKey"`
+	ActorType      string    `gorm:"size:20;not null;index:idx_audit_actor"`
+	ActorID        uint      `gorm:"not null;index:idx_audit_actor"`
+	ImpersonatorID *uint     // admin acting as the user, if any
+	Action         string    `gorm:"size:64;not null;index"`
+	TargetType     string    `gorm:"size:32;index:idx_audit_target"`
+	TargetID       uint      `gorm:"index:idx_audit_target"`
+	Changes        string    `gorm:"type:text"` // JSON object of changed fields with their old and new values
+	Details        string    `gorm:"type:text"` // JSON object
+	IPAddress      string    `gorm:"size:64"`
+	UserAgent      string    `gorm:"size:512"`
+	RequestID      string    `gorm:"size:64;index"`
+	CreatedAt      time.Time `gorm:"not null;index"` // set before hashing, not by the database
+	PrevHash       string    `gorm:"size:64;not null;uniqueIndex"`
+	Hash           string    `gorm:"size:64;not null;uniqueIndex"`
+}
@@ -4,11 +4,49 @@ import (
 	"time"
 )
 
+// File processing statuses
+const (
+	FileStatusPending    = "pending"    // uploaded, waiting for a worker
+	FileStatusProcessing = "processing" // claimed by a worker
+	FileStatusReady      = "ready"      // sanitized and stored under FileName
+	FileStatusFailed     = "failed"     // rejected, or given up after the maximum number of attempts
+)
+
+// File purposes
+const (
+	FilePurposeAvatar   = "avatar"   // profile image, processed and kept in the image store
+	FilePurposeDocument = "document" // attachment, kept as uploaded in the attachment store
+)
+
 type File struct {
-	ID        uint      `gorm:"primaryKey"`
-	FileName  string    `gorm:"size:255;not null"`
-	UserID    uint      `gorm:"not null"`
-	User      User      `gorm:"foreignKey:UserID"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID uint `gorm:"primaryKey"`
+	// FileName is the file in the store of its purpose, empty until the file is ready
+	FileName string `gorm:"size:255;not null"`
+	// OriginalName is the name the file was uploaded with, offered again on download
+	OriginalName string `gorm:"size:255"`
+	Purpose      string `gorm:"size:20;index;not null;default:avatar"`
+	MimeType     string `gorm:"size:127"`
+	Size         int64  `gorm:"not null;default:0"` // in bytes
+	Checksum     string `gorm:"size:64"`            // hex encoded SHA-256 of the stored content
+	Width        int    `gorm:"not null;default:0"` // of images, 0 for other files
+	Height       int    `gorm:"not null;default:0"`
+	// Variant is the edge length of a square thumbnail, 0 for the uploaded image itself
+	Variant int `gorm:"not null;default:0"`
+	// ParentID is the image a thumbnail was made of
+	ParentID *uint `gorm:"index"`
+	UserID   uint  `gorm:"not null;index"`
+	User     User  `gorm:"foreignKey:UserID"`
+	// UploaderID is the user who uploaded the file, kept when they are deleted; nil when
+	// a service account uploaded it
+	UploaderID *uint `gorm:"index"`
+	// UploaderServiceAccountID is the service account that uploaded the file, if any
+	UploaderServiceAccountID *uint  `gorm:"index"`
+	Status                   string `gorm:"size:16;index;not null;default:ready"`
+	// SourceName is the raw upload in the upload store, removed once it is processed
+	SourceName    string     `gorm:"size:255"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time `gorm:"index"` // when a pending file is due, or the lease of a processing one ends
+	LastError     string     `gorm:"size:1024"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
 }
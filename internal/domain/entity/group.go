@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+)
+
+// PermissionGroupsManage lets members of a group create groups and manage their members
+const PermissionGroupsManage = "groups:manage"
+
+// AvailablePermissions lists every permission a group can grant. User permissions
+// share their names with the service account scopes.
+var AvailablePermissions = []string{
+	ScopeUsersWrite,
+	ScopeUsersDelete,
+	PermissionGroupsManage,
+}
+
+// Group is a named set of users within an organization. Groups can be nested;
+// members of a group are also members of all its ancestors and receive their permissions.
+type Group struct {
+	ID             uint         `gorm:"primaryKey"`
+	OrganizationID uint         `gorm:"not null;uniqueIndex:idx_group_org_name"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID"`
+	ParentID       *uint        `gorm:"index"`
+	Name           string       `gorm:"size:255;not null;uniqueIndex:idx_group_org_name"`
+	Description    string       `gorm:"size:1024"`
+	Permissions    string       `gorm:"size:1024"` // space separated
+	CreatedAt      time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time    `gorm:"autoUpdateTime"`
+}
+
+// GroupMember is the direct membership of a user in a group
+type GroupMember struct {
+	ID        uint      `gorm:"primaryKey"`
+	GroupID   uint      `gorm:"not null;uniqueIndex:idx_group_member"`
+	Group     Group     `gorm:"foreignKey:GroupID"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_group_member;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
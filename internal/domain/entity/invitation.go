@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+)
+
+// Invitation statuses, derived from the timestamps of an invitation
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+	InvitationStatusExpired  = "expired"
+)
+
+// Invitation lets an admin onboard someone who does not have an account yet.
+// Only the SHA-256 hash of the invitation token is stored.
+type Invitation struct {
+	ID             uint      `gorm:"primaryKey"`
+	Email          string    `gorm:"size:255;not null;index"`
+	RoleID         uint      `gorm:"not null"`
+	Role           Role      `gorm:"foreignKey:RoleID"`
+	InviterID      uint      `gorm:"not null"`
+	Inviter        User      `gorm:"foreignKey:InviterID"`
+	TokenHash      string    `gorm:"size:64;not null;unique"`
+	ExpiresAt      time.Time `gorm:"not null"`
+	AcceptedAt     *time.Time
+	AcceptedUserID *uint
+	RevokedAt      *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+// Status returns the current state of the invitation
+func (i Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !time.Now().Before(i.ExpiresAt):
+		return InvitationStatusExpired
+	default:
+		return InvitationStatusPending
+	}
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginAttempt tracks recent failed logins for a single key, either an
+// account ("account:<email>") or a client address ("ip:<address>")
+type LoginAttempt struct {
+	Key            string    `gorm:"primaryKey;size:320"`
+	Failures       int       `gorm:"not null;default:0"`
+	FirstFailureAt time.Time `gorm:"not null"`
+	LastFailureAt  time.Time `gorm:"not null"`
+	LockedUntil    *time.Time
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+// IsLocked reports whether the key is locked out at the given time
+func (a LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
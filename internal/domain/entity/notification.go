@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+)
+
+// Notification types; security notifications tell users about changes to their account
+const (
+	NotificationNewLogin        = "security.new_login"
+	NotificationPasswordChanged = "security.password_changed"
+	NotificationRoleChanged     = "security.role_changed"
+)
+
+// NotificationTypes lists every type users can set preferences for
+var NotificationTypes = []string{
+	NotificationNewLogin,
+	NotificationPasswordChanged,
+	NotificationRoleChanged,
+}
+
+// Notification is an in-app notification of a user
+type Notification struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Type      string `gorm:"size:64;not null"`
+	Title     string `gorm:"size:255;not null"`
+	Body      string `gorm:"size:2048"`
+	Data      string `gorm:"type:text"` // JSON object with details, e.g. the device of a login
+	ReadAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// NotificationPreference overrides where a user receives notifications of a type.
+// Types without a preference are delivered on the default channels.
+type NotificationPreference struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_notification_preference"`
+	Type      string    `gorm:"size:64;not null;uniqueIndex:idx_notification_preference"`
+	InApp     bool      `gorm:"not null"`
+	Email     bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+)
+
+// Roles a user can hold within an organization. They are independent of the
+// global role: a global admin manages every organization, an organization
+// admin only the members of their own.
+const (
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// Organization is a tenant; users only see the members of their current organization
+type Organization struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"size:255;not null"`
+	Slug      string    `gorm:"size:100;not null;unique"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// OrganizationMembership links a user to an organization with a per-organization role
+type OrganizationMembership struct {
+	ID             uint         `gorm:"primaryKey"`
+	OrganizationID uint         `gorm:"not null;uniqueIndex:idx_membership_org_user"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID"`
+	UserID         uint         `gorm:"not null;uniqueIndex:idx_membership_org_user;index"`
+	User           User         `gorm:"foreignKey:UserID"`
+	Role           string       `gorm:"size:20;not null;default:member"`
+	CreatedAt      time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time    `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+)
+
+// Domain event types
+const (
+	EventUserRegistered = "user.registered"
+	EventUserUpdated    = "user.updated"
+	EventUserDeleted    = "user.deleted"
+	EventRoleChanged    = "user.role_changed"
+	EventImageReplaced  = "user.image_replaced"
+)
+
+// AggregateUser is the aggregate type of the user events
+const AggregateUser = "user"
+
+// Outbox event statuses
+const (
+	OutboxEventPending   = "pending"   // not yet accepted by every sink
+	OutboxEventPublished = "published" // every sink accepted the event
+	OutboxEventFailed    = "failed"    // gave up after the maximum number of attempts
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes
+// and published to the event sinks afterwards, so no event is lost when the process dies
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey"`
+	EventID       string     `gorm:"size:64;uniqueIndex;not null"` // "evt_<hex>", lets consumers drop duplicates
+	Type          string     `gorm:"size:64;index;not null"`
+	AggregateType string     `gorm:"size:32;index:idx_outbox_aggregate;not null"`
+	AggregateID   uint       `gorm:"index:idx_outbox_aggregate;not null"`
+	Payload       string     `gorm:"type:text;not null"` // JSON event data
+	OccurredAt    time.Time  `gorm:"not null"`
+	Status        string     `gorm:"size:16;index;not null"`
+	Delivered     string     `gorm:"size:255"` // space separated names of the sinks that accepted the event
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `gorm:"index;not null"`
+	LastError     string     `gorm:"size:1024"`
+	PublishedAt   *time.Time `gorm:"index"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+}
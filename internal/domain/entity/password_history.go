@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"time"
+)
+
+// PasswordHistory keeps previous password hashes of a user to prevent reuse
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"not null;index"`
+	PasswordHash string    `gorm:"size:255;not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+// Actions and resource types evaluated by the access policy
+const (
+	ActionUsersUpdate   = "users:update"
+	ActionUsersDelete   = "users:delete"
+	ActionPolicyExplain = "policy:explain"
+	ActionPolicyManage  = "policy:manage"
+
+	ResourceTypeUser   = "user"
+	ResourceTypePolicy = "policy"
+)
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+)
+
+// Role grant statuses
+const (
+	RoleGrantStatusRequested = "requested"
+	RoleGrantStatusApproved  = "approved"
+	RoleGrantStatusRejected  = "rejected"
+	RoleGrantStatusRevoked   = "revoked"
+	RoleGrantStatusExpired   = "expired"
+)
+
+// RoleGrant temporarily gives a user another role between StartsAt and EndsAt.
+// The user's own role is left untouched and applies again once the grant ends.
+type RoleGrant struct {
+	ID            uint      `gorm:"primaryKey"`
+	UserID        uint      `gorm:"not null;index"`
+	User          User      `gorm:"foreignKey:UserID"`
+	RoleID        uint      `gorm:"not null"`
+	Role          Role      `gorm:"foreignKey:RoleID"`
+	Reason        string    `gorm:"size:1024;not null"`
+	Status        string    `gorm:"size:20;not null;index"`
+	RequestedByID uint      `gorm:"not null"`
+	DecidedByID   *uint     // approver or rejecter
+	DecisionNote  string    `gorm:"size:1024"`
+	StartsAt      time.Time `gorm:"not null"`
+	EndsAt        time.Time `gorm:"not null;index"`
+	DecidedAt     *time.Time
+	RevokedByID   *uint
+	RevokedAt     *time.Time
+	ExpiredAt     *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// IsActive reports whether the grant is approved and its time window includes now
+func (g RoleGrant) IsActive(now time.Time) bool {
+	return g.Status == RoleGrantStatusApproved && !now.Before(g.StartsAt) && now.Before(g.EndsAt)
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+)
+
+// Principal types carried in access tokens and c.Locals("principal_type")
+const (
+	PrincipalTypeUser           = "user"
+	PrincipalTypeServiceAccount = "service_account"
+)
+
+// ServiceAccountRole is the pseudo-role set in c.Locals("role") for service
+// accounts. It is never stored in the roles table.
+const ServiceAccountRole = "service_account"
+
+// Scopes that can be granted to service accounts
+const (
+	ScopeUsersRead   = "users:read"
+	ScopeUsersWrite  = "users:write"
+	ScopeUsersDelete = "users:delete"
+	// ScopeUsersAdmin lets the other scopes reach the users of every organization
+	ScopeUsersAdmin = "users:admin"
+)
+
+// AvailableScopes lists every scope a service account may be granted
+var AvailableScopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeUsersDelete,
+	ScopeUsersAdmin,
+}
+
+type ServiceAccount struct {
+	ID               uint   `gorm:"primaryKey"`
+	Name             string `gorm:"size:255;not null"`
+	Description      string `gorm:"size:1024"`
+	ClientID         string `gorm:"size:64;not null;unique"`
+	ClientSecretHash string `gorm:"size:255"`
+	PublicKey        string `gorm:"type:text"` // PEM encoded key used to verify JWT assertions
+	Scopes           string `gorm:"size:1024"` // space separated
+	OwnerID          uint   `gorm:"not null"`
+	Owner            User   `gorm:"foreignKey:OwnerID"`
+	Disabled         bool   `gorm:"not null;default:false"`
+	LastUsedAt       *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+}
+
+// UsedAssertion remembers the jti of a JWT-bearer assertion until it expires,
+// so the same assertion cannot be exchanged for a token twice
+type UsedAssertion struct {
+	ClientID  string    `gorm:"primaryKey;size:64"`
+	TokenID   string    `gorm:"primaryKey;size:255"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
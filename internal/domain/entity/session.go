@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// Session represents a single login of a user on a device. Every refresh
+// token belongs to exactly one session and access tokens carry its ID.
+type Session struct {
+	ID               uint      `gorm:"primaryKey"`
+	UserID           uint      `gorm:"not null;index"`
+	User             User      `gorm:"foreignKey:UserID"`
+	OrganizationID   uint      `gorm:"not null;default:0;index"` // current tenant, 0 when the user has none
+	RoleGrantID      uint      `gorm:"not null;default:0;index"` // temporary role grant the tokens carry, 0 for none
+	ImpersonatorID   *uint     `gorm:"index"`                    // admin acting as the user; such sessions have no refresh token
+	RefreshTokenHash string    `gorm:"size:64;not null"`
+	UserAgent        string    `gorm:"size:512"`
+	IPAddress        string    `gorm:"size:64"`
+	LastSeenAt       time.Time `gorm:"not null"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+}
+
+// IsActive reports whether the session can still be used
+func (s Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
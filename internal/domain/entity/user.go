@@ -4,15 +4,31 @@ import (
 	"time"
 )
 
+// User account statuses
+const (
+	UserStatusActive          = "active"
+	UserStatusPendingApproval = "pending_approval"
+	UserStatusRejected        = "rejected"
+)
+
 type User struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `gorm:"size:255;not null"`
-	Email     string    `gorm:"size:255;not null;unique"`
-	Password  string    `gorm:"size:255;not null"`
-	Age       int       `gorm:"not null"`
-	ImageName string    `gorm:"size:255"`
-	RoleID    uint      `gorm:"not null"`
-	Role      Role      `gorm:"foreignKey:RoleID"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gorm:"size:255;not null"`
+	Email string `gorm:"size:255;not null;unique"`
+	// NormalizedEmail is the lowercased address without plus-addressing tag,
+	// unique so variants of one address cannot register twice. It is maintained
+	// by the user repository.
+	NormalizedEmail string    `gorm:"size:255;uniqueIndex"`
+	Password        string    `gorm:"size:255;not null"`
+	Age             int       `gorm:"not null"`
+	ImageName       string    `gorm:"size:255"`
+	RoleID          uint      `gorm:"not null"`
+	Role            Role      `gorm:"foreignKey:RoleID"`
+	Status          string    `gorm:"size:20;not null;default:active"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+	// Direct group memberships; maintained through the group repository
+	GroupMemberships []GroupMember `gorm:"foreignKey:UserID"`
+	// Stored image and its thumbnails; maintained through the file repository
+	Files []File `gorm:"foreignKey:UserID"`
 }
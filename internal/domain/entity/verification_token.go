@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+// Purposes of verification tokens
+const (
+	TokenPurposeEmailChange   = "email_change"
+	TokenPurposePasswordSetup = "password_setup"
+)
+
+// VerificationToken is a single-use token sent to a user by email.
+// Only the SHA-256 hash of the token is stored.
+type VerificationToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	User      User      `gorm:"foreignKey:UserID"`
+	Purpose   string    `gorm:"size:50;not null"`
+	TokenHash string    `gorm:"size:64;not null;unique"`
+	Payload   string    `gorm:"size:255"` // purpose specific, e.g. the new address of an email change
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// IsUsable reports whether the token can still be redeemed
+func (t VerificationToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"time"
+)
+
+// Webhook event types sent to subscribers
+const (
+	WebhookEventUserCreated     = "user.created"
+	WebhookEventUserUpdated     = "user.updated"
+	WebhookEventUserDeleted     = "user.deleted"
+	WebhookEventUserRoleChanged = "user.role_changed"
+)
+
+// WebhookEvents lists every event type a subscription can filter on
+var WebhookEvents = []string{
+	WebhookEventUserCreated,
+	WebhookEventUserUpdated,
+	WebhookEventUserDeleted,
+	WebhookEventUserRoleChanged,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"   // waiting for its first or next attempt
+	WebhookDeliverySucceeded = "succeeded" // the endpoint answered with a 2xx status
+	WebhookDeliveryDead      = "dead"      // every attempt failed; only redelivered on request
+)
+
+// WebhookSubscription sends the events it is subscribed to to an HTTP endpoint
+type WebhookSubscription struct {
+	ID          uint      `gorm:"primaryKey"`
+	Name        string    `gorm:"size:255;not null"`
+	URL         string    `gorm:"size:2048;not null"`
+	Secret      string    `gorm:"size:128;not null"` // HMAC key; kept in plain text to sign payloads
+	Events      string    `gorm:"size:1024"`         // space separated, every event when empty
+	Active      bool      `gorm:"not null;default:true"`
+	CreatedByID uint      `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// WebhookDelivery is one event queued for one subscription, together with the
+// outcome of its latest attempt. Each event is queued once per subscription;
+// only redeliveries repeat it.
+type WebhookDelivery struct {
+	ID             uint                `gorm:"primaryKey"`
+	SubscriptionID uint                `gorm:"not null;index;uniqueIndex:idx_webhook_delivery_event,where:redelivery_of_id IS NULL"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+	EventID        string              `gorm:"size:64;not null;index;uniqueIndex:idx_webhook_delivery_event"` // the same for every subscription receiving the event
+	EventType      string              `gorm:"size:64;not null"`
+	Payload        string              `gorm:"type:text;not null"`
+	Status         string              `gorm:"size:20;not null;index:idx_webhook_delivery_due"`
+	Attempts       int                 `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time           `gorm:"index:idx_webhook_delivery_due"`
+	LastAttemptAt  *time.Time
+	ResponseStatus int    // HTTP status of the latest attempt, 0 when no response was received
+	ResponseBody   string `gorm:"size:1024"` // truncated
+	Error          string `gorm:"size:1024"`
+	DeliveredAt    *time.Time
+	RedeliveryOfID *uint     // delivery this one repeats, if any
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"strings"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type auditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) interfaces.AuditEventRepository {
+	return &auditEventRepository{db: db}
+}
+
+func (r *auditEventRepository) Create(event *entity.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *auditEventRepository) FindLast() (entity.AuditEvent, error) {
+	var event entity.AuditEvent
+	err := r.db.Order("id DESC").First(&event).Error
+	return event, err
+}
+
+func (r *auditEventRepository) FindAll(filter interfaces.AuditEventFilter, limit, offset int) ([]entity.AuditEvent, error) {
+	var events []entity.AuditEvent
+	err := r.filtered(filter).Order("id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, err
+}
+
+func (r *auditEventRepository) Count(filter interfaces.AuditEventFilter) (int64, error) {
+	var count int64
+	err := r.filtered(filter).Model(&entity.AuditEvent{}).Count(&count).Error
+	return count, err
+}
+
+func (r *auditEventRepository) FindAfter(filter interfaces.AuditEventFilter, afterID uint, limit int) ([]entity.AuditEvent, error) {
+	var events []entity.AuditEvent
+	err := r.filtered(filter).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (r *auditEventRepository) filtered(filter interfaces.AuditEventFilter) *gorm.DB {
+	query := r.db
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "."); ok {
+		query = query.Where("action LIKE ?", prefix+".%")
+	} else if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	return query
+}
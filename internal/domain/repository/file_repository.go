@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"user_crud/internal/domain/entity"
 	"user_crud/internal/domain/repository/interfaces"
 
@@ -19,16 +21,84 @@ func (r *fileRepository) Create(file *entity.File) error {
 	return r.db.Create(file).Error
 }
 
-func (r *fileRepository) FindByUserID(userID uint) (entity.File, error) {
+func (r *fileRepository) FindByID(id uint) (entity.File, error) {
 	var file entity.File
-	err := r.db.Where("user_id = ?", userID).First(&file).Error
+	err := r.db.First(&file, id).Error
 	return file, err
 }
 
+func (r *fileRepository) FindByUserID(userID uint) ([]entity.File, error) {
+	var files []entity.File
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&files).Error
+	return files, err
+}
+
 func (r *fileRepository) Update(file *entity.File) error {
-	return r.db.Save(file).Error
+	return r.db.Omit("User").Save(file).Error
+}
+
+func (r *fileRepository) DeleteByIDs(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Where("id IN ?", ids).Delete(&entity.File{}).Error
 }
 
 func (r *fileRepository) DeleteByUserID(userID uint) error {
 	return r.db.Where("user_id = ?", userID).Delete(&entity.File{}).Error
 }
+
+func (r *fileRepository) FindDue(now time.Time, limit int) ([]entity.File, error) {
+	var files []entity.File
+	err := r.db.
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.FileStatusPending, entity.FileStatusProcessing}, now).
+		Order("id").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepository) Claim(file *entity.File, now, leaseUntil time.Time) (bool, error) {
+	// The attempt count doubles as the lease token: a worker whose lease ran out cannot
+	// finish the attempt once another worker has claimed the file again
+	result := r.db.Model(&entity.File{}).
+		Where("id = ? AND status IN ? AND next_attempt_at <= ? AND attempts = ?",
+			file.ID, []string{entity.FileStatusPending, entity.FileStatusProcessing}, now, file.Attempts).
+		Updates(map[string]any{
+			"status":          entity.FileStatusProcessing,
+			"attempts":        file.Attempts + 1,
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	file.Status = entity.FileStatusProcessing
+	file.Attempts++
+	file.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+func (r *fileRepository) FinishAttempt(file *entity.File) (bool, error) {
+	result := r.db.Model(&entity.File{}).
+		Where("id = ? AND status = ? AND attempts = ?", file.ID, entity.FileStatusProcessing, file.Attempts).
+		Updates(map[string]any{
+			"status":          file.Status,
+			"file_name":       file.FileName,
+			"mime_type":       file.MimeType,
+			"size":            file.Size,
+			"checksum":        file.Checksum,
+			"width":           file.Width,
+			"height":          file.Height,
+			"source_name":     file.SourceName,
+			"next_attempt_at": file.NextAttemptAt,
+			"last_error":      file.LastError,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type groupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) interfaces.GroupRepository {
+	return &groupRepository{db: db}
+}
+
+func (r *groupRepository) Create(group *entity.Group) error {
+	return r.db.Omit("Organization").Create(group).Error
+}
+
+func (r *groupRepository) FindByOrganizationID(organizationID uint) ([]entity.Group, error) {
+	var groups []entity.Group
+	err := r.db.Where("organization_id = ?", organizationID).Order("name").Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepository) FindByID(id uint) (entity.Group, error) {
+	var group entity.Group
+	err := r.db.First(&group, id).Error
+	return group, err
+}
+
+func (r *groupRepository) FindByName(organizationID uint, name string) (entity.Group, error) {
+	var group entity.Group
+	err := r.db.Where("organization_id = ? AND name = ?", organizationID, name).First(&group).Error
+	return group, err
+}
+
+func (r *groupRepository) CountChildren(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.Group{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *groupRepository) Update(group *entity.Group) error {
+	return r.db.Omit("Organization").Save(group).Error
+}
+
+func (r *groupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&entity.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Group{}, id).Error
+	})
+}
+
+func (r *groupRepository) DeleteByOrganizationID(organizationID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		groups := tx.Model(&entity.Group{}).Select("id").Where("organization_id = ?", organizationID)
+		if err := tx.Where("group_id IN (?)", groups).Delete(&entity.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ?", organizationID).Delete(&entity.Group{}).Error
+	})
+}
+
+func (r *groupRepository) AddMember(member *entity.GroupMember) error {
+	return r.db.Omit("Group").Create(member).Error
+}
+
+func (r *groupRepository) FindMember(groupID, userID uint) (entity.GroupMember, error) {
+	var member entity.GroupMember
+	err := r.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	return member, err
+}
+
+func (r *groupRepository) FindMembers(groupID uint) ([]entity.User, error) {
+	var users []entity.User
+	members := r.db.Model(&entity.GroupMember{}).Select("user_id").Where("group_id = ?", groupID)
+	err := r.db.Preload("Role").Where("id IN (?)", members).Order("name").Find(&users).Error
+	return users, err
+}
+
+func (r *groupRepository) FindMembershipsByUserID(userID, organizationID uint) ([]entity.GroupMember, error) {
+	var members []entity.GroupMember
+	groups := r.db.Model(&entity.Group{}).Select("id").Where("organization_id = ?", organizationID)
+	err := r.db.Preload("Group").
+		Where("user_id = ? AND group_id IN (?)", userID, groups).
+		Find(&members).Error
+	return members, err
+}
+
+func (r *groupRepository) RemoveMember(groupID, userID uint) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&entity.GroupMember{}).Error
+}
+
+func (r *groupRepository) RemoveMemberFromOrganization(userID, organizationID uint) error {
+	groups := r.db.Model(&entity.Group{}).Select("id").Where("organization_id = ?", organizationID)
+	return r.db.Where("user_id = ? AND group_id IN (?)", userID, groups).Delete(&entity.GroupMember{}).Error
+}
+
+func (r *groupRepository) DeleteMembershipsByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.GroupMember{}).Error
+}
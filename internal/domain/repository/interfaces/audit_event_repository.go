@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+// AuditEventFilter narrows audit event queries; zero values match everything
+type AuditEventFilter struct {
+	ActorType  string
+	ActorID    uint
+	Action     string // exact action or a prefix ending in ".", e.g. "user."
+	TargetType string
+	TargetID   uint
+	RequestID  string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditEventRepository only appends and reads; audit events are never changed or deleted
+type AuditEventRepository interface {
+	Create(event *entity.AuditEvent) error
+	// FindLast returns the most recent event, gorm.ErrRecordNotFound when the log is empty
+	FindLast() (entity.AuditEvent, error)
+	// FindAll returns matching events, newest first
+	FindAll(filter AuditEventFilter, limit, offset int) ([]entity.AuditEvent, error)
+	Count(filter AuditEventFilter) (int64, error)
+	// FindAfter returns up to limit matching events with an ID greater than afterID, oldest first
+	FindAfter(filter AuditEventFilter, afterID uint, limit int) ([]entity.AuditEvent, error)
+}
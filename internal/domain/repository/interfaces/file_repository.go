@@ -1,12 +1,25 @@
 package interfaces
 
 import (
+	"time"
+
 	"user_crud/internal/domain/entity"
 )
 
 type FileRepository interface {
 	Create(file *entity.File) error
-	FindByUserID(userID uint) (entity.File, error)
+	FindByID(id uint) (entity.File, error)
+	FindByUserID(userID uint) ([]entity.File, error)
 	Update(file *entity.File) error
+	DeleteByIDs(ids []uint) error
 	DeleteByUserID(userID uint) error
+
+	// FindDue returns pending files whose next attempt is due and processing files whose lease ran out
+	FindDue(now time.Time, limit int) ([]entity.File, error)
+	// Claim marks a due file as processing until leaseUntil and counts the attempt. It reports
+	// false when another worker claimed it first.
+	Claim(file *entity.File, now, leaseUntil time.Time) (bool, error)
+	// FinishAttempt saves the outcome of the attempt file was claimed for. It reports false
+	// when the file was deleted or claimed again in the meantime.
+	FinishAttempt(file *entity.File) (bool, error)
 }
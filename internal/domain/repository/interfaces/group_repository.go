@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+)
+
+type GroupRepository interface {
+	Create(group *entity.Group) error
+	FindByOrganizationID(organizationID uint) ([]entity.Group, error)
+	FindByID(id uint) (entity.Group, error)
+	FindByName(organizationID uint, name string) (entity.Group, error)
+	CountChildren(id uint) (int64, error)
+	Update(group *entity.Group) error
+	// Delete removes a group together with its memberships
+	Delete(id uint) error
+	DeleteByOrganizationID(organizationID uint) error
+
+	AddMember(member *entity.GroupMember) error
+	FindMember(groupID, userID uint) (entity.GroupMember, error)
+	FindMembers(groupID uint) ([]entity.User, error)
+	// FindMembershipsByUserID returns the direct memberships of a user in one organization
+	FindMembershipsByUserID(userID, organizationID uint) ([]entity.GroupMember, error)
+	RemoveMember(groupID, userID uint) error
+	RemoveMemberFromOrganization(userID, organizationID uint) error
+	DeleteMembershipsByUserID(userID uint) error
+}
@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type InvitationRepository interface {
+	Create(invitation *entity.Invitation) error
+	FindAll() ([]entity.Invitation, error)
+	FindByID(id uint) (entity.Invitation, error)
+	FindByTokenHash(tokenHash string) (entity.Invitation, error)
+	Update(invitation *entity.Invitation) error
+	// Accept marks a pending invitation as accepted by userID and reports false when it
+	// was accepted, revoked or had expired already
+	Accept(id, userID uint, now time.Time) (bool, error)
+	RevokePendingByEmail(email string) error
+	DeleteByInviterID(inviterID uint) error
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+// LoginAttemptRepository stores failed login counters. Implementations must
+// apply RecordFailure atomically so several nodes can share one store.
+type LoginAttemptRepository interface {
+	Find(key string) (entity.LoginAttempt, error)
+	// RecordFailure increments the counter for key, starting a new window
+	// when the previous one is older than window and not locked
+	RecordFailure(key string, now time.Time, window time.Duration) (entity.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+)
+
+type MembershipRepository interface {
+	Create(membership *entity.OrganizationMembership) error
+	Find(organizationID, userID uint) (entity.OrganizationMembership, error)
+	FindByOrganizationID(organizationID uint) ([]entity.OrganizationMembership, error)
+	// FindByUserID returns the memberships of a user, oldest first
+	FindByUserID(userID uint) ([]entity.OrganizationMembership, error)
+	CountByOrganizationID(organizationID uint) (int64, error)
+	CountByRole(organizationID uint, role string) (int64, error)
+	Update(membership *entity.OrganizationMembership) error
+	// Move replaces a membership in one organization with one in another
+	Move(userID, fromOrganizationID, toOrganizationID uint, role string) error
+	// AddUsersWithoutMembership adds every user that belongs to no organization
+	AddUsersWithoutMembership(organizationID uint, role string) error
+	Delete(organizationID, userID uint) error
+	DeleteByUserID(userID uint) error
+}
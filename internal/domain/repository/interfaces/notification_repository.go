@@ -0,0 +1,28 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type NotificationRepository interface {
+	Create(notification *entity.Notification) error
+	// FindByUserID lists the notifications of a user newest first, optionally only unread ones
+	FindByUserID(userID uint, unreadOnly bool, limit, offset int) ([]entity.Notification, error)
+	CountByUserID(userID uint, unreadOnly bool) (int64, error)
+	// MarkRead marks a notification of the user as read; it reports false when the user has no such notification
+	MarkRead(userID, id uint, readAt time.Time) (bool, error)
+	// MarkAllRead marks every unread notification of the user as read and returns how many there were
+	MarkAllRead(userID uint, readAt time.Time) (int64, error)
+	// DeleteCreatedBefore removes notifications older than the cutoff and returns how many were removed
+	DeleteCreatedBefore(cutoff time.Time) (int64, error)
+	DeleteByUserID(userID uint) error
+}
+
+type NotificationPreferenceRepository interface {
+	FindByUserID(userID uint) ([]entity.NotificationPreference, error)
+	// Save creates or replaces the preference of the user for its type
+	Save(preference *entity.NotificationPreference) error
+	DeleteByUserID(userID uint) error
+}
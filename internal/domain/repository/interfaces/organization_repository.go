@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+)
+
+type OrganizationRepository interface {
+	Create(organization *entity.Organization) error
+	FindAll() ([]entity.Organization, error)
+	FindByID(id uint) (entity.Organization, error)
+	FindBySlug(slug string) (entity.Organization, error)
+	Update(organization *entity.Organization) error
+	Delete(id uint) error
+}
@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type OutboxRepository interface {
+	Append(events ...*entity.OutboxEvent) error
+	// FindDue returns pending events whose next attempt is due, oldest first. Events wait
+	// while an earlier event of the same aggregate is pending, so each aggregate keeps its order.
+	FindDue(now time.Time, limit int) ([]entity.OutboxEvent, error)
+	// Claim postpones a due event until leaseUntil so no other dispatcher picks it up meanwhile.
+	// It reports false when another dispatcher claimed the event first.
+	Claim(event *entity.OutboxEvent, now, leaseUntil time.Time) (bool, error)
+	Update(event *entity.OutboxEvent) error
+	// DeletePublishedBefore removes events published before the cutoff and returns how many
+	DeletePublishedBefore(cutoff time.Time) (int64, error)
+}
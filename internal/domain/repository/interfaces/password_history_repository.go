@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+)
+
+type PasswordHistoryRepository interface {
+	Create(entry *entity.PasswordHistory) error
+	FindRecentByUserID(userID uint, limit int) ([]entity.PasswordHistory, error)
+	// DeleteOlderByUserID keeps only the newest keep entries of a user
+	DeleteOlderByUserID(userID uint, keep int) error
+	DeleteByUserID(userID uint) error
+}
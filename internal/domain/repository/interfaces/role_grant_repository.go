@@ -0,0 +1,25 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type RoleGrantRepository interface {
+	Create(grant *entity.RoleGrant) error
+	// FindAll lists grants, optionally filtered by status and grantee (empty or 0 matches all)
+	FindAll(status string, userID uint) ([]entity.RoleGrant, error)
+	FindByID(id uint) (entity.RoleGrant, error)
+	// FindActiveByUserID returns the approved grant covering now that ends last
+	FindActiveByUserID(userID uint, now time.Time) (entity.RoleGrant, error)
+	// FindOpenByUserAndRole returns a requested or approved grant of the role that has not ended yet
+	FindOpenByUserAndRole(userID, roleID uint, now time.Time) (entity.RoleGrant, error)
+	// FindEnded returns grants that are still marked approved or requested although their end time has passed
+	FindEnded(now time.Time) ([]entity.RoleGrant, error)
+	Update(grant *entity.RoleGrant) error
+	// Expire marks a grant that is still in status and ended by now as expired, and
+	// reports false when it was decided, revoked or expired in the meantime
+	Expire(id uint, status string, now time.Time) (bool, error)
+	DeleteByUserID(userID uint) error
+}
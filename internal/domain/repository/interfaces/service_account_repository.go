@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type ServiceAccountRepository interface {
+	Create(account *entity.ServiceAccount) error
+	FindAll() ([]entity.ServiceAccount, error)
+	FindByID(id uint) (entity.ServiceAccount, error)
+	FindByClientID(clientID string) (entity.ServiceAccount, error)
+	Update(account *entity.ServiceAccount) error
+	Delete(id uint) error
+	DeleteByOwnerID(ownerID uint) error
+	// UseAssertion records an assertion's jti and reports false when it was already
+	// recorded; entries past their expiry are dropped on the way
+	UseAssertion(assertion entity.UsedAssertion, now time.Time) (bool, error)
+}
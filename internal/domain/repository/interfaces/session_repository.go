@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type SessionRepository interface {
+	Create(session *entity.Session) error
+	FindByID(id uint) (entity.Session, error)
+	FindActiveByUserID(userID uint) ([]entity.Session, error)
+	// Rotate stores the session's new refresh token hash together with its organization, role
+	// grant, address and times. It reports false, writing nothing, when the session was revoked,
+	// has expired or its refresh token hash is no longer previousHash.
+	Rotate(session *entity.Session, previousHash string, now time.Time) (bool, error)
+	// Touch records when an active session was last used without rewriting the rest of the row
+	Touch(id uint, seenAt time.Time) error
+	Revoke(id uint) error
+	RevokeAllByUserID(userID uint) error
+	RevokeOthersByUserID(userID, keepSessionID uint) error
+	// MoveOrganization switches the active sessions of a user from one organization to another
+	MoveOrganization(userID, fromOrganizationID, toOrganizationID uint) error
+	// ClearRoleGrant detaches a role grant from the sessions carrying it so their access tokens stop working
+	ClearRoleGrant(roleGrantID uint) error
+	DeleteByUserID(userID uint) error
+}
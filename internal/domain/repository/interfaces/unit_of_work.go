@@ -0,0 +1,24 @@
+package interfaces
+
+// UnitOfWork runs changes to several repositories in one database transaction
+type UnitOfWork interface {
+	// Transaction commits when fn returns nil and rolls back otherwise
+	Transaction(fn func(tx Repositories) error) error
+}
+
+// Repositories gives access to repositories bound to a running transaction
+type Repositories interface {
+	Users() UserRepository
+	Files() FileRepository
+	Outbox() OutboxRepository
+	Memberships() MembershipRepository
+	Groups() GroupRepository
+	Sessions() SessionRepository
+	Invitations() InvitationRepository
+	PasswordHistory() PasswordHistoryRepository
+	VerificationTokens() VerificationTokenRepository
+	RoleGrants() RoleGrantRepository
+	Notifications() NotificationRepository
+	NotificationPreferences() NotificationPreferenceRepository
+	ServiceAccounts() ServiceAccountRepository
+}
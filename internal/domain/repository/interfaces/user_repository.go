@@ -5,10 +5,17 @@ import (
 )
 
 type UserRepository interface {
+	// InOrganization returns a repository whose queries only see members of
+	// the organization; users it creates become members
+	InOrganization(organizationID uint) UserRepository
 	Create(user *entity.User) error
 	FindAll() ([]entity.User, error)
 	FindByID(id uint) (entity.User, error)
+	// FindByEmail matches on the normalized address, see util.NormalizeEmail
 	FindByEmail(email string) (entity.User, error)
+	FindByStatus(status string) ([]entity.User, error)
 	Update(user *entity.User) error
+	// UpdateImageName changes only the image a user shows, leaving concurrent edits of the rest of the row intact
+	UpdateImageName(id uint, imageName string) error
 	Delete(id uint) error
 }
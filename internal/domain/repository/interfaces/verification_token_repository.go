@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type VerificationTokenRepository interface {
+	Create(token *entity.VerificationToken) error
+	FindByTokenHash(tokenHash string) (entity.VerificationToken, error)
+	// Use marks a token as used and reports false when it was used already or had expired
+	Use(id uint, now time.Time) (bool, error)
+	DeleteByUserIDAndPurpose(userID uint, purpose string) error
+	DeleteByUserID(userID uint) error
+}
@@ -0,0 +1,36 @@
+package interfaces
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+)
+
+type WebhookSubscriptionRepository interface {
+	Create(subscription *entity.WebhookSubscription) error
+	FindAll() ([]entity.WebhookSubscription, error)
+	FindActive() ([]entity.WebhookSubscription, error)
+	FindByID(id uint) (entity.WebhookSubscription, error)
+	Update(subscription *entity.WebhookSubscription) error
+	Delete(id uint) error
+}
+
+type WebhookDeliveryRepository interface {
+	Create(delivery *entity.WebhookDelivery) error
+	// FindBySubscriptionID lists deliveries newest first, optionally filtered by status
+	FindBySubscriptionID(subscriptionID uint, status string, limit, offset int) ([]entity.WebhookDelivery, error)
+	FindByID(id uint) (entity.WebhookDelivery, error)
+	// Enqueue creates the first delivery of an event to a subscription and reports false
+	// when the event was already queued for it
+	Enqueue(delivery *entity.WebhookDelivery) (bool, error)
+	// FindDue returns pending deliveries whose next attempt is due, oldest first
+	FindDue(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// Claim postpones a due delivery until leaseUntil so no other worker picks it up meanwhile.
+	// It reports false when another worker claimed the delivery first.
+	Claim(delivery *entity.WebhookDelivery, now, leaseUntil time.Time) (bool, error)
+	// FinishAttempt records the outcome of the attempt made under the lease claimed until
+	// leaseUntil. It reports false when the lease ran out and another worker claimed the
+	// delivery again, whose outcome then counts instead.
+	FinishAttempt(delivery *entity.WebhookDelivery, leaseUntil time.Time) (bool, error)
+	DeleteBySubscriptionID(subscriptionID uint) error
+}
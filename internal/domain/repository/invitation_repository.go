@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) interfaces.InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(invitation *entity.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *invitationRepository) FindAll() ([]entity.Invitation, error) {
+	var invitations []entity.Invitation
+	err := r.db.Preload("Role").Preload("Inviter").Order("id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) FindByID(id uint) (entity.Invitation, error) {
+	var invitation entity.Invitation
+	err := r.db.Preload("Role").Preload("Inviter").First(&invitation, id).Error
+	return invitation, err
+}
+
+func (r *invitationRepository) FindByTokenHash(tokenHash string) (entity.Invitation, error) {
+	var invitation entity.Invitation
+	err := r.db.Preload("Role").Where("token_hash = ?", tokenHash).First(&invitation).Error
+	return invitation, err
+}
+
+func (r *invitationRepository) Update(invitation *entity.Invitation) error {
+	return r.db.Omit("Role", "Inviter").Save(invitation).Error
+}
+
+func (r *invitationRepository) Accept(id, userID uint, now time.Time) (bool, error) {
+	result := r.db.Model(&entity.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Updates(map[string]any{"accepted_at": now, "accepted_user_id": userID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *invitationRepository) RevokePendingByEmail(email string) error {
+	return r.db.Model(&entity.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *invitationRepository) DeleteByInviterID(inviterID uint) error {
+	return r.db.Where("inviter_id = ?", inviterID).Delete(&entity.Invitation{}).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository creates a SQL backed store shared by every node using the database
+func NewLoginAttemptRepository(db *gorm.DB) interfaces.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Find(key string) (entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	err := r.db.Where("key = ?", key).First(&attempt).Error
+	return attempt, err
+}
+
+func (r *loginAttemptRepository) RecordFailure(key string, now time.Time, window time.Duration) (entity.LoginAttempt, error) {
+	// One upsert counts the failure, so concurrent failures on any node are all counted.
+	// Every expression sees the row as it was before, like applyFailure.
+	restart := "(login_attempts.locked_until IS NULL OR login_attempts.locked_until <= ?) AND login_attempts.first_failure_at < ?"
+	cutoff := now.Add(-window)
+	onRestart := func(restarted, continued string) clause.Expr {
+		return gorm.Expr("CASE WHEN "+restart+" THEN "+restarted+" ELSE "+continued+" END", now, cutoff)
+	}
+
+	attempt := entity.LoginAttempt{Key: key, Failures: 1, FirstFailureAt: now, LastFailureAt: now}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"failures":         onRestart("1", "login_attempts.failures + 1"),
+			"first_failure_at": onRestart("excluded.first_failure_at", "login_attempts.first_failure_at"),
+			"locked_until":     onRestart("NULL", "login_attempts.locked_until"),
+			"last_failure_at":  gorm.Expr("excluded.last_failure_at"),
+			"updated_at":       gorm.Expr("excluded.updated_at"),
+		}),
+	}, clause.Returning{}).Create(&attempt).Error
+	return attempt, err
+}
+
+func (r *loginAttemptRepository) Lock(key string, until time.Time) error {
+	return r.db.Model(&entity.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (r *loginAttemptRepository) Reset(key string) error {
+	return r.db.Where("key = ?", key).Delete(&entity.LoginAttempt{}).Error
+}
+
+// applyFailure counts one failure, restarting the window when it has elapsed
+func applyFailure(attempt *entity.LoginAttempt, key string, now time.Time, window time.Duration) {
+	if attempt.Key == "" || (!attempt.IsLocked(now) && now.Sub(attempt.FirstFailureAt) > window) {
+		*attempt = entity.LoginAttempt{Key: key, FirstFailureAt: now}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type membershipRepository struct {
+	db *gorm.DB
+}
+
+func NewMembershipRepository(db *gorm.DB) interfaces.MembershipRepository {
+	return &membershipRepository{db: db}
+}
+
+func (r *membershipRepository) Create(membership *entity.OrganizationMembership) error {
+	return r.db.Create(membership).Error
+}
+
+func (r *membershipRepository) Find(organizationID, userID uint) (entity.OrganizationMembership, error) {
+	var membership entity.OrganizationMembership
+	err := r.db.Preload("Organization").
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(&membership).Error
+	return membership, err
+}
+
+func (r *membershipRepository) FindByOrganizationID(organizationID uint) ([]entity.OrganizationMembership, error) {
+	var memberships []entity.OrganizationMembership
+	err := r.db.Preload("User").Preload("User.Role").
+		Where("organization_id = ?", organizationID).
+		Order("id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *membershipRepository) FindByUserID(userID uint) ([]entity.OrganizationMembership, error) {
+	var memberships []entity.OrganizationMembership
+	err := r.db.Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *membershipRepository) CountByOrganizationID(organizationID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.OrganizationMembership{}).Where("organization_id = ?", organizationID).Count(&count).Error
+	return count, err
+}
+
+func (r *membershipRepository) CountByRole(organizationID uint, role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.OrganizationMembership{}).
+		Where("organization_id = ? AND role = ?", organizationID, role).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *membershipRepository) Update(membership *entity.OrganizationMembership) error {
+	return r.db.Omit("Organization", "User").Save(membership).Error
+}
+
+func (r *membershipRepository) Move(userID, fromOrganizationID, toOrganizationID uint, role string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("organization_id = ? AND user_id = ?", fromOrganizationID, userID).
+			Delete(&entity.OrganizationMembership{}).Error
+		if err != nil {
+			return err
+		}
+
+		// The user may already be a member of the target organization
+		err = tx.Where("organization_id = ? AND user_id = ?", toOrganizationID, userID).
+			Delete(&entity.OrganizationMembership{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&entity.OrganizationMembership{
+			OrganizationID: toOrganizationID,
+			UserID:         userID,
+			Role:           role,
+		}).Error
+	})
+}
+
+func (r *membershipRepository) AddUsersWithoutMembership(organizationID uint, role string) error {
+	now := time.Now()
+	return r.db.Exec(
+		`INSERT INTO organization_memberships (organization_id, user_id, role, created_at, updated_at)
+		SELECT ?, id, ?, ?, ? FROM users
+		WHERE id NOT IN (SELECT user_id FROM organization_memberships)`,
+		organizationID, role, now, now,
+	).Error
+}
+
+func (r *membershipRepository) Delete(organizationID, userID uint) error {
+	return r.db.Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Delete(&entity.OrganizationMembership{}).Error
+}
+
+func (r *membershipRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.OrganizationMembership{}).Error
+}
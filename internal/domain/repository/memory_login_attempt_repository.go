@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+// memoryPruneInterval controls how often stale entries are dropped from memory
+const memoryPruneInterval = time.Minute
+
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	attempts  map[string]entity.LoginAttempt
+	retention time.Duration
+	lastPrune time.Time
+}
+
+// NewMemoryLoginAttemptRepository creates an in-process store, suitable for a single node.
+// Entries without a lock are forgotten once they are older than retention.
+func NewMemoryLoginAttemptRepository(retention time.Duration) interfaces.LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{
+		attempts:  make(map[string]entity.LoginAttempt),
+		retention: retention,
+		lastPrune: time.Now(),
+	}
+}
+
+func (r *memoryLoginAttemptRepository) Find(key string) (entity.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return entity.LoginAttempt{}, gorm.ErrRecordNotFound
+	}
+	return attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(key string, now time.Time, window time.Duration) (entity.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(now)
+
+	attempt := r.attempts[key]
+	applyFailure(&attempt, key, now, window)
+	r.attempts[key] = attempt
+	return attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// prune drops expired entries so the map cannot grow without bound; callers hold the lock
+func (r *memoryLoginAttemptRepository) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < memoryPruneInterval {
+		return
+	}
+	r.lastPrune = now
+
+	for key, attempt := range r.attempts {
+		if !attempt.IsLocked(now) && now.Sub(attempt.LastFailureAt) > r.retention {
+			delete(r.attempts, key)
+		}
+	}
+}
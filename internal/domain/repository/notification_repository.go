@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) interfaces.NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(notification *entity.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) FindByUserID(userID uint, unreadOnly bool, limit, offset int) ([]entity.Notification, error) {
+	var notifications []entity.Notification
+	err := r.byUser(userID, unreadOnly).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *notificationRepository) CountByUserID(userID uint, unreadOnly bool) (int64, error) {
+	var count int64
+	err := r.byUser(userID, unreadOnly).Count(&count).Error
+	return count, err
+}
+
+func (r *notificationRepository) MarkRead(userID, id uint, readAt time.Time) (bool, error) {
+	var count int64
+	if err := r.db.Model(&entity.Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	// Reading again keeps the time it was first read
+	err := r.db.Model(&entity.Notification{}).
+		Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", readAt).Error
+	return true, err
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint, readAt time.Time) (int64, error) {
+	result := r.db.Model(&entity.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) DeleteCreatedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&entity.Notification{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.Notification{}).Error
+}
+
+func (r *notificationRepository) byUser(userID uint, unreadOnly bool) *gorm.DB {
+	query := r.db.Model(&entity.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	return query
+}
+
+type notificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) interfaces.NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{db: db}
+}
+
+func (r *notificationPreferenceRepository) FindByUserID(userID uint) ([]entity.NotificationPreference, error) {
+	var preferences []entity.NotificationPreference
+	err := r.db.Where("user_id = ?", userID).Order("type").Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *notificationPreferenceRepository) Save(preference *entity.NotificationPreference) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "updated_at"}),
+	}).Create(preference).Error
+}
+
+func (r *notificationPreferenceRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.NotificationPreference{}).Error
+}
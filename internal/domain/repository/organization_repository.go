@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) interfaces.OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) Create(organization *entity.Organization) error {
+	return r.db.Create(organization).Error
+}
+
+func (r *organizationRepository) FindAll() ([]entity.Organization, error) {
+	var organizations []entity.Organization
+	err := r.db.Order("name").Find(&organizations).Error
+	return organizations, err
+}
+
+func (r *organizationRepository) FindByID(id uint) (entity.Organization, error) {
+	var organization entity.Organization
+	err := r.db.First(&organization, id).Error
+	return organization, err
+}
+
+func (r *organizationRepository) FindBySlug(slug string) (entity.Organization, error) {
+	var organization entity.Organization
+	err := r.db.Where("slug = ?", slug).First(&organization).Error
+	return organization, err
+}
+
+func (r *organizationRepository) Update(organization *entity.Organization) error {
+	return r.db.Save(organization).Error
+}
+
+func (r *organizationRepository) Delete(id uint) error {
+	return r.db.Delete(&entity.Organization{}, id).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) interfaces.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Append(events ...*entity.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.Create(events).Error
+}
+
+func (r *outboxRepository) FindDue(now time.Time, limit int) ([]entity.OutboxEvent, error) {
+	earlier := r.db.Table("outbox_events AS earlier").
+		Select("1").
+		Where("earlier.aggregate_type = outbox_events.aggregate_type").
+		Where("earlier.aggregate_id = outbox_events.aggregate_id").
+		Where("earlier.status = ? AND earlier.id < outbox_events.id", entity.OutboxEventPending)
+
+	var events []entity.OutboxEvent
+	err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", entity.OutboxEventPending, now).
+		Where("NOT EXISTS (?)", earlier).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepository) Claim(event *entity.OutboxEvent, now, leaseUntil time.Time) (bool, error) {
+	// Once one dispatcher has moved the attempt into the future it is no longer due for the others
+	result := r.db.Model(&entity.OutboxEvent{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", event.ID, entity.OutboxEventPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	event.NextAttemptAt = leaseUntil
+	return true, nil
+}
+
+func (r *outboxRepository) Update(event *entity.OutboxEvent) error {
+	return r.db.Save(event).Error
+}
+
+func (r *outboxRepository) DeletePublishedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND published_at < ?", entity.OutboxEventPublished, cutoff).Delete(&entity.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
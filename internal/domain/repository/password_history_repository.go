@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) interfaces.PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+func (r *passwordHistoryRepository) Create(entry *entity.PasswordHistory) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *passwordHistoryRepository) FindRecentByUserID(userID uint, limit int) ([]entity.PasswordHistory, error) {
+	var entries []entity.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *passwordHistoryRepository) DeleteOlderByUserID(userID uint, keep int) error {
+	newest := r.db.Model(&entity.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(keep)
+
+	return r.db.Where("user_id = ? AND id NOT IN (?)", userID, newest).Delete(&entity.PasswordHistory{}).Error
+}
+
+func (r *passwordHistoryRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.PasswordHistory{}).Error
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type roleGrantRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleGrantRepository(db *gorm.DB) interfaces.RoleGrantRepository {
+	return &roleGrantRepository{db: db}
+}
+
+func (r *roleGrantRepository) Create(grant *entity.RoleGrant) error {
+	return r.db.Omit("User", "Role").Create(grant).Error
+}
+
+func (r *roleGrantRepository) FindAll(status string, userID uint) ([]entity.RoleGrant, error) {
+	query := r.db.Preload("User").Preload("Role").Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var grants []entity.RoleGrant
+	err := query.Find(&grants).Error
+	return grants, err
+}
+
+func (r *roleGrantRepository) FindByID(id uint) (entity.RoleGrant, error) {
+	var grant entity.RoleGrant
+	err := r.db.Preload("User").Preload("Role").First(&grant, id).Error
+	return grant, err
+}
+
+func (r *roleGrantRepository) FindActiveByUserID(userID uint, now time.Time) (entity.RoleGrant, error) {
+	var grant entity.RoleGrant
+	err := r.db.Preload("Role").
+		Where("user_id = ? AND status = ? AND starts_at <= ? AND ends_at > ?", userID, entity.RoleGrantStatusApproved, now, now).
+		Order("ends_at DESC").
+		First(&grant).Error
+	return grant, err
+}
+
+func (r *roleGrantRepository) FindOpenByUserAndRole(userID, roleID uint, now time.Time) (entity.RoleGrant, error) {
+	var grant entity.RoleGrant
+	err := r.db.
+		Where("user_id = ? AND role_id = ? AND status IN ? AND ends_at > ?",
+			userID, roleID, []string{entity.RoleGrantStatusRequested, entity.RoleGrantStatusApproved}, now).
+		First(&grant).Error
+	return grant, err
+}
+
+func (r *roleGrantRepository) FindEnded(now time.Time) ([]entity.RoleGrant, error) {
+	var grants []entity.RoleGrant
+	err := r.db.Preload("Role").
+		Where("status IN ? AND ends_at <= ?", []string{entity.RoleGrantStatusRequested, entity.RoleGrantStatusApproved}, now).
+		Find(&grants).Error
+	return grants, err
+}
+
+func (r *roleGrantRepository) Update(grant *entity.RoleGrant) error {
+	return r.db.Omit("User", "Role").Save(grant).Error
+}
+
+func (r *roleGrantRepository) Expire(id uint, status string, now time.Time) (bool, error) {
+	result := r.db.Model(&entity.RoleGrant{}).
+		Where("id = ? AND status = ? AND ends_at <= ?", id, status, now).
+		Updates(map[string]any{"status": entity.RoleGrantStatusExpired, "expired_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *roleGrantRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.RoleGrant{}).Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type serviceAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountRepository(db *gorm.DB) interfaces.ServiceAccountRepository {
+	return &serviceAccountRepository{db: db}
+}
+
+func (r *serviceAccountRepository) Create(account *entity.ServiceAccount) error {
+	return r.db.Create(account).Error
+}
+
+func (r *serviceAccountRepository) FindAll() ([]entity.ServiceAccount, error) {
+	var accounts []entity.ServiceAccount
+	err := r.db.Preload("Owner").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *serviceAccountRepository) FindByID(id uint) (entity.ServiceAccount, error) {
+	var account entity.ServiceAccount
+	err := r.db.Preload("Owner").First(&account, id).Error
+	return account, err
+}
+
+func (r *serviceAccountRepository) FindByClientID(clientID string) (entity.ServiceAccount, error) {
+	var account entity.ServiceAccount
+	err := r.db.Preload("Owner").Where("client_id = ?", clientID).First(&account).Error
+	return account, err
+}
+
+func (r *serviceAccountRepository) Update(account *entity.ServiceAccount) error {
+	return r.db.Save(account).Error
+}
+
+func (r *serviceAccountRepository) Delete(id uint) error {
+	return r.db.Delete(&entity.ServiceAccount{}, id).Error
+}
+
+func (r *serviceAccountRepository) DeleteByOwnerID(ownerID uint) error {
+	return r.db.Where("owner_id = ?", ownerID).Delete(&entity.ServiceAccount{}).Error
+}
+
+func (r *serviceAccountRepository) UseAssertion(assertion entity.UsedAssertion, now time.Time) (bool, error) {
+	if err := r.db.Where("expires_at <= ?", now).Delete(&entity.UsedAssertion{}).Error; err != nil {
+		return false, err
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&assertion)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
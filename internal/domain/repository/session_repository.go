@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) interfaces.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *entity.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(id uint) (entity.Session, error) {
+	var session entity.Session
+	err := r.db.First(&session, id).Error
+	return session, err
+}
+
+func (r *sessionRepository) FindActiveByUserID(userID uint) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Rotate(session *entity.Session, previousHash string, now time.Time) (bool, error) {
+	result := r.db.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND refresh_token_hash = ?", session.ID, now, previousHash).
+		Updates(map[string]any{
+			"refresh_token_hash": session.RefreshTokenHash,
+			"organization_id":    session.OrganizationID,
+			"role_grant_id":      session.RoleGrantID,
+			"ip_address":         session.IPAddress,
+			"last_seen_at":       session.LastSeenAt,
+			"expires_at":         session.ExpiresAt,
+			"updated_at":         now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *sessionRepository) Touch(id uint, seenAt time.Time) error {
+	return r.db.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("last_seen_at", seenAt).Error
+}
+
+func (r *sessionRepository) Revoke(id uint) error {
+	return r.db.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeAllByUserID(userID uint) error {
+	return r.db.Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeOthersByUserID(userID, keepSessionID uint) error {
+	return r.db.Model(&entity.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) MoveOrganization(userID, fromOrganizationID, toOrganizationID uint) error {
+	return r.db.Model(&entity.Session{}).
+		Where("user_id = ? AND organization_id = ? AND revoked_at IS NULL", userID, fromOrganizationID).
+		Update("organization_id", toOrganizationID).Error
+}
+
+func (r *sessionRepository) ClearRoleGrant(roleGrantID uint) error {
+	return r.db.Model(&entity.Session{}).
+		Where("role_grant_id = ?", roleGrantID).
+		Update("role_grant_id", 0).Error
+}
+
+func (r *sessionRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.Session{}).Error
+}
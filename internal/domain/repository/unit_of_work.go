@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) interfaces.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Transaction(fn func(tx interfaces.Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&transactionRepositories{tx: tx})
+	})
+}
+
+// transactionRepositories hands out repositories whose statements run in the transaction
+type transactionRepositories struct {
+	tx *gorm.DB
+}
+
+func (r *transactionRepositories) Users() interfaces.UserRepository {
+	return NewUserRepository(r.tx)
+}
+
+func (r *transactionRepositories) Files() interfaces.FileRepository {
+	return NewFileRepository(r.tx)
+}
+
+func (r *transactionRepositories) Outbox() interfaces.OutboxRepository {
+	return NewOutboxRepository(r.tx)
+}
+
+func (r *transactionRepositories) Memberships() interfaces.MembershipRepository {
+	return NewMembershipRepository(r.tx)
+}
+
+func (r *transactionRepositories) Groups() interfaces.GroupRepository {
+	return NewGroupRepository(r.tx)
+}
+
+func (r *transactionRepositories) Sessions() interfaces.SessionRepository {
+	return NewSessionRepository(r.tx)
+}
+
+func (r *transactionRepositories) Invitations() interfaces.InvitationRepository {
+	return NewInvitationRepository(r.tx)
+}
+
+func (r *transactionRepositories) PasswordHistory() interfaces.PasswordHistoryRepository {
+	return NewPasswordHistoryRepository(r.tx)
+}
+
+func (r *transactionRepositories) VerificationTokens() interfaces.VerificationTokenRepository {
+	return NewVerificationTokenRepository(r.tx)
+}
+
+func (r *transactionRepositories) RoleGrants() interfaces.RoleGrantRepository {
+	return NewRoleGrantRepository(r.tx)
+}
+
+func (r *transactionRepositories) Notifications() interfaces.NotificationRepository {
+	return NewNotificationRepository(r.tx)
+}
+
+func (r *transactionRepositories) NotificationPreferences() interfaces.NotificationPreferenceRepository {
+	return NewNotificationPreferenceRepository(r.tx)
+}
+
+func (r *transactionRepositories) ServiceAccounts() interfaces.ServiceAccountRepository {
+	return NewServiceAccountRepository(r.tx)
+}
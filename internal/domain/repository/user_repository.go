@@ -3,44 +3,100 @@ package repository
 import (
 	"user_crud/internal/domain/entity"
 	"user_crud/internal/domain/repository/interfaces"
+	"user_crud/internal/util"
 
 	"gorm.io/gorm"
 )
 
 type userRepository struct {
 	db *gorm.DB
+	// When scoped, every query only sees members of organizationID
+	scoped         bool
+	organizationID uint
 }
 
 func NewUserRepository(db *gorm.DB) interfaces.UserRepository {
 	return &userRepository{db: db}
 }
 
+func (r *userRepository) InOrganization(organizationID uint) interfaces.UserRepository {
+	return &userRepository{db: r.db, scoped: true, organizationID: organizationID}
+}
+
+// query starts a statement restricted to the tenant of a scoped repository
+func (r *userRepository) query() *gorm.DB {
+	if !r.scoped {
+		return r.db
+	}
+
+	members := r.db.Model(&entity.OrganizationMembership{}).
+		Select("user_id").
+		Where("organization_id = ?", r.organizationID)
+	return r.db.Where("users.id IN (?)", members)
+}
+
 func (r *userRepository) Create(user *entity.User) error {
-	return r.db.Create(user).Error
+	user.NormalizedEmail = util.NormalizeEmail(user.Email)
+	if !r.scoped {
+		return r.db.Create(user).Error
+	}
+
+	// Users created through a scoped repository join its organization
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.OrganizationMembership{
+			OrganizationID: r.organizationID,
+			UserID:         user.ID,
+			Role:           entity.OrganizationRoleMember,
+		}).Error
+	})
 }
 
 func (r *userRepository) FindAll() ([]entity.User, error) {
 	var users []entity.User
-	err := r.db.Preload("Role").Find(&users).Error
+	err := r.query().Preload("Role").Preload("GroupMemberships.Group").Preload("Files").Find(&users).Error
 	return users, err
 }
 
 func (r *userRepository) FindByID(id uint) (entity.User, error) {
 	var user entity.User
-	err := r.db.Preload("Role").First(&user, id).Error
+	err := r.query().Preload("Role").Preload("GroupMemberships.Group").Preload("Files").First(&user, id).Error
 	return user, err
 }
 
 func (r *userRepository) FindByEmail(email string) (entity.User, error) {
 	var user entity.User
-	err := r.db.Preload("Role").Where("email = ?", email).First(&user).Error
+	err := r.query().Preload("Role").Preload("GroupMemberships.Group").Preload("Files").Where("normalized_email = ?", util.NormalizeEmail(email)).First(&user).Error
 	return user, err
 }
 
+func (r *userRepository) FindByStatus(status string) ([]entity.User, error) {
+	var users []entity.User
+	err := r.query().Preload("Role").Preload("GroupMemberships.Group").Preload("Files").Where("status = ?", status).Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) Update(user *entity.User) error {
-	return r.db.Save(user).Error
+	if r.scoped {
+		var count int64
+		if err := r.query().Model(&entity.User{}).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+
+	user.NormalizedEmail = util.NormalizeEmail(user.Email)
+	return r.db.Omit("GroupMemberships", "Files").Save(user).Error
+}
+
+func (r *userRepository) UpdateImageName(id uint, imageName string) error {
+	return r.query().Model(&entity.User{}).Where("id = ?", id).UpdateColumn("image_name", imageName).Error
 }
 
 func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&entity.User{}, id).Error
+	return r.query().Delete(&entity.User{}, id).Error
 }
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type verificationTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewVerificationTokenRepository(db *gorm.DB) interfaces.VerificationTokenRepository {
+	return &verificationTokenRepository{db: db}
+}
+
+func (r *verificationTokenRepository) Create(token *entity.VerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *verificationTokenRepository) FindByTokenHash(tokenHash string) (entity.VerificationToken, error) {
+	var token entity.VerificationToken
+	err := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&token).Error
+	return token, err
+}
+
+func (r *verificationTokenRepository) Use(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&entity.VerificationToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *verificationTokenRepository) DeleteByUserIDAndPurpose(userID uint, purpose string) error {
+	return r.db.Where("user_id = ? AND purpose = ?", userID, purpose).Delete(&entity.VerificationToken{}).Error
+}
+
+func (r *verificationTokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.VerificationToken{}).Error
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"time"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookSubscriptionRepository(db *gorm.DB) interfaces.WebhookSubscriptionRepository {
+	return &webhookSubscriptionRepository{db: db}
+}
+
+func (r *webhookSubscriptionRepository) Create(subscription *entity.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) FindAll() ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	err := r.db.Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookSubscriptionRepository) FindActive() ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	err := r.db.Where("active = ?", true).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookSubscriptionRepository) FindByID(id uint) (entity.WebhookSubscription, error) {
+	var subscription entity.WebhookSubscription
+	err := r.db.First(&subscription, id).Error
+	return subscription, err
+}
+
+func (r *webhookSubscriptionRepository) Update(subscription *entity.WebhookSubscription) error {
+	return r.db.Save(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) Delete(id uint) error {
+	return r.db.Delete(&entity.WebhookSubscription{}, id).Error
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) interfaces.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Create(delivery *entity.WebhookDelivery) error {
+	return r.db.Omit("Subscription").Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) FindBySubscriptionID(subscriptionID uint, status string, limit, offset int) ([]entity.WebhookDelivery, error) {
+	query := r.db.Where("subscription_id = ?", subscriptionID).Order("id DESC").Limit(limit).Offset(offset)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []entity.WebhookDelivery
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) FindByID(id uint) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := r.db.First(&delivery, id).Error
+	return delivery, err
+}
+
+func (r *webhookDeliveryRepository) Enqueue(delivery *entity.WebhookDelivery) (bool, error) {
+	// idx_webhook_delivery_event rejects a second first delivery of the same event
+	result := r.db.Omit("Subscription").Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *webhookDeliveryRepository) FindDue(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := r.db.Preload("Subscription").
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookDeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) Claim(delivery *entity.WebhookDelivery, now, leaseUntil time.Time) (bool, error) {
+	// Once one worker has moved the attempt into the future it is no longer due for the others
+	result := r.db.Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, entity.WebhookDeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	delivery.NextAttemptAt = leaseUntil
+	return true, nil
+}
+
+func (r *webhookDeliveryRepository) FinishAttempt(delivery *entity.WebhookDelivery, leaseUntil time.Time) (bool, error) {
+	// The claim left the attempt count as it was and moved the next attempt to the lease end
+	result := r.db.Model(&entity.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ? AND next_attempt_at = ?",
+			delivery.ID, entity.WebhookDeliveryPending, delivery.Attempts-1, leaseUntil).
+		Updates(map[string]any{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_attempt_at": delivery.LastAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"response_body":   delivery.ResponseBody,
+			"error":           delivery.Error,
+			"delivered_at":    delivery.DeliveredAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *webhookDeliveryRepository) DeleteBySubscriptionID(subscriptionID uint) error {
+	return r.db.Where("subscription_id = ?", subscriptionID).Delete(&entity.WebhookDelivery{}).Error
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+	auditBatchSize       = 500
+	// auditAppendAttempts bounds retries when another writer appended to the chain concurrently
+	auditAppendAttempts = 3
+)
+
+type auditService struct {
+	auditRepo interfaces.AuditEventRepository
+	// mu serializes appends so every event links to the one before it
+	mu sync.Mutex
+}
+
+func NewAuditService(auditRepo interfaces.AuditEventRepository) serviceInterfaces.AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *auditService) Record(record dto.AuditRecord) {
+	event := entity.AuditEvent{
+		Action:     record.Action,
+		TargetType: record.TargetType,
+		TargetID:   record.TargetID,
+		IPAddress:  record.Actor.Client.IPAddress,
+		UserAgent:  truncate(record.Actor.Client.UserAgent, 512),
+		RequestID:  truncate(record.Actor.Client.RequestID, 64),
+	}
+	event.ActorType, event.ActorID = auditActor(record.Actor)
+	if record.Actor.ImpersonatorID != 0 {
+		impersonatorID := record.Actor.ImpersonatorID
+		event.ImpersonatorID = &impersonatorID
+	}
+
+	if changes := auditDiff(record.Before, record.After); len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			log.Printf("failed to encode changes of audit event %s: %v", record.Action, err)
+		}
+		event.Changes = string(data)
+	}
+	if len(record.Details) > 0 {
+		data, err := json.Marshal(record.Details)
+		if err != nil {
+			log.Printf("failed to encode details of audit event %s: %v", record.Action, err)
+		}
+		event.Details = string(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The unique previous hash rejects a second event linking to the same predecessor,
+	// so a concurrent writer on another node makes this attempt fail and retry
+	var err error
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		var last entity.AuditEvent
+		last, err = s.auditRepo.FindLast()
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+
+		event.ID = 0
+		event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		event.PrevHash = last.Hash
+		event.Hash = auditHash(event)
+		if err = s.auditRepo.Create(&event); err == nil {
+			return
+		}
+	}
+	log.Printf("failed to record audit event %s by %s:%d: %v", event.Action, event.ActorType, event.ActorID, err)
+}
+
+func (s *auditService) GetEvents(query dto.AuditEventQuery) (dto.AuditEventListResponse, error, int) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		return dto.AuditEventListResponse{}, fmt.Errorf("limit must be at most %d", maxAuditPageSize), fiber.StatusBadRequest
+	}
+	if query.Offset < 0 {
+		return dto.AuditEventListResponse{}, errors.New("offset must not be negative"), fiber.StatusBadRequest
+	}
+
+	filter := auditFilter(query)
+	events, err := s.auditRepo.FindAll(filter, limit, query.Offset)
+	if err != nil {
+		return dto.AuditEventListResponse{}, fmt.Errorf("failed to retrieve audit events: %w", err), fiber.StatusInternalServerError
+	}
+	total, err := s.auditRepo.Count(filter)
+	if err != nil {
+		return dto.AuditEventListResponse{}, fmt.Errorf("failed to count audit events: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := dto.AuditEventListResponse{
+		Events: make([]dto.AuditEventResponse, 0, len(events)),
+		Total:  total,
+		Limit:  limit,
+		Offset: query.Offset,
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, toAuditEventResponse(event))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *auditService) ExportEvents(query dto.AuditEventQuery, w io.Writer) error {
+	filter := auditFilter(query)
+	encoder := json.NewEncoder(w)
+
+	var afterID uint
+	for {
+		events, err := s.auditRepo.FindAfter(filter, afterID, auditBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve audit events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		for _, event := range events {
+			if err := encoder.Encode(toAuditEventResponse(event)); err != nil {
+				return err
+			}
+			afterID = event.ID
+		}
+	}
+}
+
+func (s *auditService) VerifyChain() (dto.AuditVerifyResponse, error, int) {
+	response := dto.AuditVerifyResponse{Valid: true}
+
+	var afterID uint
+	for {
+		events, err := s.auditRepo.FindAfter(interfaces.AuditEventFilter{}, afterID, auditBatchSize)
+		if err != nil {
+			return dto.AuditVerifyResponse{}, fmt.Errorf("failed to retrieve audit events: %w", err), fiber.StatusInternalServerError
+		}
+		if len(events) == 0 {
+			return response, nil, fiber.StatusOK
+		}
+
+		for _, event := range events {
+			switch {
+			case event.PrevHash != response.LastHash:
+				response.Reason = "previous hash does not match the preceding event"
+			case auditHash(event) != event.Hash:
+				response.Reason = "event content does not match its hash"
+			}
+			if response.Reason != "" {
+				response.Valid = false
+				response.BrokenAtID = event.ID
+				return response, nil, fiber.StatusOK
+			}
+
+			response.Checked++
+			response.LastHash = event.Hash
+			afterID = event.ID
+		}
+	}
+}
+
+// auditActor identifies who an event is recorded for
+func auditActor(actor dto.Actor) (string, uint) {
+	switch {
+	case actor.ServiceAccountID != 0:
+		return entity.PrincipalTypeServiceAccount, actor.ServiceAccountID
+	case actor.UserID != 0:
+		return entity.PrincipalTypeUser, actor.UserID
+	case actor.Client.IPAddress != "":
+		return entity.AuditActorAnonymous, 0
+	default:
+		return entity.AuditActorSystem, 0
+	}
+}
+
+// auditHash covers every stored field of the event except its ID and own hash
+func auditHash(event entity.AuditEvent) string {
+	var impersonatorID uint
+	if event.ImpersonatorID != nil {
+		impersonatorID = *event.ImpersonatorID
+	}
+
+	content, _ := json.Marshal(struct {
+		PrevHash       string `json:"prev_hash"`
+		ActorType      string `json:"actor_type"`
+		ActorID        uint   `json:"actor_id"`
+		ImpersonatorID uint   `json:"impersonator_id"`
+		Action         string `json:"action"`
+		TargetType     string `json:"target_type"`
+		TargetID       uint   `json:"target_id"`
+		Changes        string `json:"changes"`
+		Details        string `json:"details"`
+		IPAddress      string `json:"ip_address"`
+		UserAgent      string `json:"user_agent"`
+		RequestID      string `json:"request_id"`
+		CreatedAt      string `json:"created_at"`
+	}{
+		PrevHash:       event.PrevHash,
+		ActorType:      event.ActorType,
+		ActorID:        event.ActorID,
+		ImpersonatorID: impersonatorID,
+		Action:         event.Action,
+		TargetType:     event.TargetType,
+		TargetID:       event.TargetID,
+		Changes:        event.Changes,
+		Details:        event.Details,
+		IPAddress:      event.IPAddress,
+		UserAgent:      event.UserAgent,
+		RequestID:      event.RequestID,
+		CreatedAt:      event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditDiff compares two snapshots field by field; a nil snapshot has no fields
+func auditDiff(before, after any) map[string]dto.AuditChange {
+	from := auditFields(before)
+	to := auditFields(after)
+
+	changes := make(map[string]dto.AuditChange)
+	for name, value := range from {
+		if !reflect.DeepEqual(value, to[name]) {
+			changes[name] = dto.AuditChange{From: value, To: to[name]}
+		}
+	}
+	for name, value := range to {
+		if _, ok := from[name]; !ok {
+			changes[name] = dto.AuditChange{To: value}
+		}
+	}
+	return changes
+}
+
+// auditFields turns a snapshot into a map of its JSON fields
+func auditFields(snapshot any) map[string]any {
+	if snapshot == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// auditUser is the part of a user recorded in audit log diffs; credentials are left out
+type auditUser struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Age       int    `json:"age"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	ImageName string `json:"image_name"`
+}
+
+func userSnapshot(user entity.User) auditUser {
+	return auditUser{
+		Name:      user.Name,
+		Email:     user.Email,
+		Age:       user.Age,
+		Role:      user.Role.Name,
+		Status:    user.Status,
+		ImageName: user.ImageName,
+	}
+}
+
+func auditFilter(query dto.AuditEventQuery) interfaces.AuditEventFilter {
+	return interfaces.AuditEventFilter{
+		ActorType:  query.ActorType,
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+		RequestID:  query.RequestID,
+		From:       query.From,
+		To:         query.To,
+	}
+}
+
+func toAuditEventResponse(event entity.AuditEvent) dto.AuditEventResponse {
+	response := dto.AuditEventResponse{
+		ID:             event.ID,
+		ActorType:      event.ActorType,
+		ActorID:        event.ActorID,
+		ImpersonatorID: event.ImpersonatorID,
+		Action:         event.Action,
+		TargetType:     event.TargetType,
+		TargetID:       event.TargetID,
+		IPAddress:      event.IPAddress,
+		UserAgent:      event.UserAgent,
+		RequestID:      event.RequestID,
+		CreatedAt:      event.CreatedAt,
+		PrevHash:       event.PrevHash,
+		Hash:           event.Hash,
+	}
+	if event.Changes != "" {
+		response.Changes = json.RawMessage(event.Changes)
+	}
+	if event.Details != "" {
+		response.Details = json.RawMessage(event.Details)
+	}
+	return response
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	"user_crud/internal/dto"
+)
+
+// memoryAuditRepository keeps audit events in a slice, ordered by ID
+type memoryAuditRepository struct {
+	events []entity.AuditEvent
+}
+
+func (r *memoryAuditRepository) Create(event *entity.AuditEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *memoryAuditRepository) FindLast() (entity.AuditEvent, error) {
+	if len(r.events) == 0 {
+		return entity.AuditEvent{}, gorm.ErrRecordNotFound
+	}
+	return r.events[len(r.events)-1], nil
+}
+
+func (r *memoryAuditRepository) FindAll(interfaces.AuditEventFilter, int, int) ([]entity.AuditEvent, error) {
+	return nil, nil
+}
+
+func (r *memoryAuditRepository) Count(interfaces.AuditEventFilter) (int64, error) {
+	return int64(len(r.events)), nil
+}
+
+func (r *memoryAuditRepository) FindAfter(_ interfaces.AuditEventFilter, afterID uint, limit int) ([]entity.AuditEvent, error) {
+	var events []entity.AuditEvent
+	for _, event := range r.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// recordAuditEvents appends count events through the service, so they are chained as in production
+func recordAuditEvents(t *testing.T, count int) (*auditService, *memoryAuditRepository) {
+	t.Helper()
+
+	repo := &memoryAuditRepository{}
+	service := NewAuditService(repo).(*auditService)
+	for i := 0; i < count; i++ {
+		service.Record(dto.AuditRecord{
+			Actor:      dto.Actor{UserID: 1, Client: dto.ClientInfo{IPAddress: "127.0.0.1", RequestID: "req"}},
+			Action:     "user.updated",
+			TargetType: "user",
+			TargetID:   uint(i + 2),
+			Details:    map[string]any{"index": i},
+		})
+	}
+	if len(repo.events) != count {
+		t.Fatalf("expected %d recorded events, got %d", count, len(repo.events))
+	}
+	return service, repo
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		count      int
+		tamper     func(events []entity.AuditEvent) []entity.AuditEvent
+		brokenAtID uint
+		reason     string
+	}{
+		{name: "empty log", count: 0},
+		{name: "intact chain", count: 3},
+		{name: "intact chain across batches", count: auditBatchSize*2 + 1},
+		{
+			name:  "changed content",
+			count: 3,
+			tamper: func(events []entity.AuditEvent) []entity.AuditEvent {
+				events[1].Details = `{"index":42}`
+				return events
+			},
+			brokenAtID: 2,
+			reason:     "event content does not match its hash",
+		},
+		{
+			name:  "rehashed event",
+			count: 3,
+			tamper: func(events []entity.AuditEvent) []entity.AuditEvent {
+				events[1].Action = "user.deleted"
+				events[1].Hash = auditHash(events[1])
+				return events
+			},
+			brokenAtID: 3,
+			reason:     "previous hash does not match the preceding event",
+		},
+		{
+			name:  "removed event",
+			count: 3,
+			tamper: func(events []entity.AuditEvent) []entity.AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			brokenAtID: 3,
+			reason:     "previous hash does not match the preceding event",
+		},
+		{
+			name:  "removed first event",
+			count: 2,
+			tamper: func(events []entity.AuditEvent) []entity.AuditEvent {
+				return events[1:]
+			},
+			brokenAtID: 2,
+			reason:     "previous hash does not match the preceding event",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := recordAuditEvents(t, tt.count)
+			if tt.tamper != nil {
+				repo.events = tt.tamper(repo.events)
+			}
+
+			response, err, status := service.VerifyChain()
+			if err != nil || status != fiber.StatusOK {
+				t.Fatalf("expected status 200, got %d: %v", status, err)
+			}
+
+			if tt.brokenAtID == 0 {
+				if !response.Valid || response.Checked != len(repo.events) {
+					t.Errorf("expected %d valid events, got %+v", len(repo.events), response)
+				}
+				if len(repo.events) > 0 && response.LastHash != repo.events[len(repo.events)-1].Hash {
+					t.Errorf("expected the last hash of the chain, got %q", response.LastHash)
+				}
+				return
+			}
+			if response.Valid || response.BrokenAtID != tt.brokenAtID || response.Reason != tt.reason {
+				t.Errorf("expected the chain to break at %d with %q, got %+v", tt.brokenAtID, tt.reason, response)
+			}
+		})
+	}
+}
+
+func TestAuditHash(t *testing.T) {
+	impersonatorID := uint(9)
+	event := entity.AuditEvent{
+		ID:             1,
+		PrevHash:       "prev",
+		ActorType:      entity.PrincipalTypeUser,
+		ActorID:        1,
+		ImpersonatorID: &impersonatorID,
+		Action:         "user.updated",
+		TargetType:     "user",
+		TargetID:       2,
+		Changes:        `{"name":{"from":"a","to":"b"}}`,
+		Details:        `{"reason":"test"}`,
+		IPAddress:      "127.0.0.1",
+		UserAgent:      "agent",
+		RequestID:      "req",
+		CreatedAt:      time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}
+
+	hash := auditHash(event)
+	if len(hash) != 64 {
+		t.Fatalf("expected a hex SHA-256, got %q", hash)
+	}
+
+	t.Run("ignores the ID, own hash and time zone", func(t *testing.T) {
+		other := event
+		other.ID = 100
+		other.Hash = "stored"
+		other.CreatedAt = event.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+		if auditHash(other) != hash {
+			t.Error("expected the same hash")
+		}
+	})
+
+	t.Run("impersonator", func(t *testing.T) {
+		other := event
+		otherID := uint(10)
+		other.ImpersonatorID = &otherID
+		if auditHash(other) == hash {
+			t.Error("expected another impersonator to change the hash")
+		}
+		other.ImpersonatorID = nil
+		if auditHash(other) == hash {
+			t.Error("expected a missing impersonator to change the hash")
+		}
+	})
+
+	// Every other stored field is covered by the hash
+	fields := reflect.TypeOf(event)
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Name
+		if name == "ID" || name == "Hash" || name == "ImpersonatorID" {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			other := event
+			field := reflect.ValueOf(&other).Elem().Field(i)
+			switch field.Kind() {
+			case reflect.String:
+				field.SetString(field.String() + "x")
+			case reflect.Uint:
+				field.SetUint(field.Uint() + 1)
+			default:
+				if name != "CreatedAt" {
+					t.Fatalf("unexpected field type %s", field.Type())
+				}
+				other.CreatedAt = event.CreatedAt.Add(time.Microsecond)
+			}
+			if auditHash(other) == hash {
+				t.Errorf("expected a changed %s to change the hash", name)
+			}
+		})
+	}
+}
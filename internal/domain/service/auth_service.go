@@ -2,11 +2,13 @@ package service
 
 import (
 	"errors"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 
+	"user_crud/internal/config"
 	"user_crud/internal/domain/entity"
 	"user_crud/internal/domain/repository/interfaces"
 	serviceInterfaces "user_crud/internal/domain/service/interfaces"
@@ -15,22 +17,54 @@ import (
 )
 
 type authService struct {
-	userRepo interfaces.UserRepository
-	roleRepo interfaces.RoleRepository
+	userRepo       interfaces.UserRepository
+	roleRepo       interfaces.RoleRepository
+	unitOfWork     interfaces.UnitOfWork
+	sessionService serviceInterfaces.SessionService
+	loginGuard     serviceInterfaces.LoginGuardService
+	passwordPolicy serviceInterfaces.PasswordPolicyService
+	registration   serviceInterfaces.RegistrationService
+	organizations  serviceInterfaces.OrganizationService
+	notifications  serviceInterfaces.NotificationService
+	auditService   serviceInterfaces.AuditService
+	cfg            *config.Config
 }
 
 func NewAuthService(
 	userRepo interfaces.UserRepository,
 	roleRepo interfaces.RoleRepository,
+	unitOfWork interfaces.UnitOfWork,
+	sessionService serviceInterfaces.SessionService,
+	loginGuard serviceInterfaces.LoginGuardService,
+	passwordPolicy serviceInterfaces.PasswordPolicyService,
+	registration serviceInterfaces.RegistrationService,
+	organizations serviceInterfaces.OrganizationService,
+	notifications serviceInterfaces.NotificationService,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
 ) serviceInterfaces.AuthService {
 	return &authService{
-		userRepo: userRepo,
-		roleRepo: roleRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		unitOfWork:     unitOfWork,
+		sessionService: sessionService,
+		loginGuard:     loginGuard,
+		passwordPolicy: passwordPolicy,
+		registration:   registration,
+		organizations:  organizations,
+		notifications:  notifications,
+		auditService:   auditService,
+		cfg:            cfg,
 	}
 }
 
-func (s *authService) Register(req dto.RegisterRequest) (dto.TokenResponse, error, int) {
-	// Check if email already exists
+func (s *authService) Register(req dto.RegisterRequest, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	// Apply the registration mode and email domain rules
+	if err, status := s.registration.CheckAllowed(req.Email); err != nil {
+		return dto.TokenResponse{}, err, status
+	}
+
+	// Check if email already exists, ignoring case and plus-addressing
 	_, err := s.userRepo.FindByEmail(req.Email)
 	if err == nil {
 		return dto.TokenResponse{}, errors.New("email already exists"), fiber.StatusConflict
@@ -38,6 +72,11 @@ func (s *authService) Register(req dto.RegisterRequest) (dto.TokenResponse, erro
 		return dto.TokenResponse{}, errors.New("failed to check email existence"), fiber.StatusInternalServerError
 	}
 
+	// Enforce password policy
+	if err, status := s.passwordPolicy.ValidatePassword(req.Password, entity.User{Name: req.Name, Email: req.Email}); err != nil {
+		return dto.TokenResponse{}, err, status
+	}
+
 	// Hash password
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
@@ -57,36 +96,72 @@ func (s *authService) Register(req dto.RegisterRequest) (dto.TokenResponse, erro
 		Password: hashedPassword,
 		RoleID:   role.ID,
 		Age:      0, // Set default age
+		Status:   s.registration.InitialStatus(),
 	}
 
-	if err := s.userRepo.Create(&user); err != nil {
+	// The user and its registration event are saved together
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Users().Create(&user); err != nil {
+			return err
+		}
+
+		data := dto.UserEventData{User: eventUser(user)}
+		data.User.Role = role.Name
+		return stageUserEvent(tx.Outbox(), entity.EventUserRegistered, data)
+	})
+	// Another request may have taken the address since it was checked
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return dto.TokenResponse{}, errors.New("email already exists"), fiber.StatusConflict
+	}
+	if err != nil {
 		return dto.TokenResponse{}, errors.New("failed to create user"), fiber.StatusInternalServerError
 	}
 
-	// Generate tokens
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Email, role.Name)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+	if err := s.passwordPolicy.RecordPassword(user.ID, hashedPassword); err != nil {
+		log.Printf("failed to record password history of user:%d: %v", user.ID, err)
 	}
 
-	refreshToken, err := util.GenerateRefreshToken(user.ID)
+	if err := s.organizations.JoinDefaultOrganization(user.ID); err != nil {
+		log.Printf("failed to add user:%d to the default organization: %v", user.ID, err)
+	}
+
+	snapshot := userSnapshot(user)
+	snapshot.Role = role.Name
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      dto.Actor{UserID: user.ID, Client: client},
+		Action:     entity.AuditActionRegistered,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+		After:      snapshot,
+	})
+
+	// Accounts awaiting approval get no session until an admin lets them in
+	if user.Status == entity.UserStatusPendingApproval {
+		return dto.TokenResponse{}, nil, fiber.StatusAccepted
+	}
+
+	// Start a session and generate tokens
+	response, err, status := s.sessionService.StartSession(user, role.Name, client)
 	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate refresh token"), fiber.StatusInternalServerError
+		return dto.TokenResponse{}, err, status
 	}
 
-	return dto.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(util.AccessTokenExpiry / time.Second),
-	}, nil, fiber.StatusCreated
+	return response, nil, fiber.StatusCreated
 }
 
-func (s *authService) Login(req dto.LoginRequest) (dto.TokenResponse, error, int) {
+func (s *authService) Login(req dto.LoginRequest, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	// Refuse attempts while the account or address is locked out or throttled
+	if err, status := s.loginGuard.CheckAllowed(req.Email, client.IPAddress); err != nil {
+		return dto.TokenResponse{}, err, status
+	}
+
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			util.CheckDummyPassword(req.Password)
+			s.loginGuard.RecordFailure(req.Email, client)
+			s.recordLoginFailure(req.Email, 0, "unknown email", client)
 			return dto.TokenResponse{}, errors.New("invalid email or password"), fiber.StatusUnauthorized
 		}
 		return dto.TokenResponse{}, errors.New("failed to retrieve user"), fiber.StatusInternalServerError
@@ -94,68 +169,79 @@ func (s *authService) Login(req dto.LoginRequest) (dto.TokenResponse, error, int
 
 	// Verify password
 	if !util.CheckPassword(req.Password, user.Password) {
+		s.loginGuard.RecordFailure(req.Email, client)
+		s.recordLoginFailure(req.Email, user.ID, "wrong password", client)
 		return dto.TokenResponse{}, errors.New("invalid email or password"), fiber.StatusUnauthorized
 	}
 
+	s.loginGuard.RecordSuccess(req.Email)
+
+	// Only reveal the account status once the password has been proven
+	if err, status := checkUserStatus(user); err != nil {
+		s.recordLoginFailure(req.Email, user.ID, "account "+user.Status, client)
+		return dto.TokenResponse{}, err, status
+	}
+
+	// Upgrade hashes created with an older algorithm or weaker parameters
+	if util.PasswordNeedsRehash(user.Password) {
+		if hashedPassword, err := util.HashPassword(req.Password); err == nil {
+			user.Password = hashedPassword
+			if err := s.userRepo.Update(&user); err != nil {
+				log.Printf("failed to rehash password of user:%d: %v", user.ID, err)
+			}
+		}
+	}
+
 	// Get role name
 	role, err := s.roleRepo.FindByID(user.RoleID)
 	if err != nil {
 		return dto.TokenResponse{}, errors.New("failed to retrieve role"), fiber.StatusInternalServerError
 	}
 
-	// Generate tokens
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Email, role.Name)
+	// Start a session and generate tokens
+	response, err, status := s.sessionService.StartSession(user, role.Name, client)
 	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+		return dto.TokenResponse{}, err, status
 	}
 
-	refreshToken, err := util.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate refresh token"), fiber.StatusInternalServerError
-	}
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      dto.Actor{UserID: user.ID, Client: client},
+		Action:     entity.AuditActionLoginSucceeded,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+	})
+	s.notifications.Notify(user.ID, newLoginNotification(client, time.Now()))
 
-	return dto.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(util.AccessTokenExpiry / time.Second),
-	}, nil, fiber.StatusOK
+	return response, nil, status
 }
 
-func (s *authService) RefreshToken(refreshToken string) (dto.TokenResponse, error, int) {
-	// Verify refresh token
-	userID, err := util.VerifyRefreshToken(refreshToken)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("invalid refresh token"), fiber.StatusUnauthorized
-	}
-
-	// Get user
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("user not found"), fiber.StatusUnauthorized
-	}
+func (s *authService) RefreshToken(refreshToken string, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	// Rotate the refresh token within its session
+	return s.sessionService.RefreshSession(refreshToken, client)
+}
 
-	// Get role
-	role, err := s.roleRepo.FindByID(user.RoleID)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to retrieve role"), fiber.StatusInternalServerError
+// recordLoginFailure adds a failed login to the audit log. The attempt is anonymous
+// until the password is proven; the user is only the target when the email matched.
+func (s *authService) recordLoginFailure(email string, userID uint, reason string, client dto.ClientInfo) {
+	record := dto.AuditRecord{
+		Actor:   dto.Actor{Client: client},
+		Action:  entity.AuditActionLoginFailed,
+		Details: map[string]any{"email": email, "reason": reason},
 	}
-
-	// Generate new tokens
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Email, role.Name)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+	if userID != 0 {
+		record.TargetType = entity.AuditTargetUser
+		record.TargetID = userID
 	}
+	s.auditService.Record(record)
+}
 
-	newRefreshToken, err := util.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return dto.TokenResponse{}, errors.New("failed to generate refresh token"), fiber.StatusInternalServerError
+// checkUserStatus refuses sign-in to accounts that are not active
+func checkUserStatus(user entity.User) (error, int) {
+	switch user.Status {
+	case entity.UserStatusPendingApproval:
+		return errors.New("account is pending approval"), fiber.StatusForbidden
+	case entity.UserStatusRejected:
+		return errors.New("account registration was rejected"), fiber.StatusForbidden
 	}
-
-	return dto.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    int64(util.AccessTokenExpiry / time.Second),
-	}, nil, fiber.StatusOK
+	return nil, fiber.StatusOK
 }
@@ -0,0 +1,718 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/blobstore"
+)
+
+// errAttemptSuperseded means a processed file was deleted, or claimed by another worker,
+// before its attempt could be saved
+var errAttemptSuperseded = errors.New("file was deleted or claimed again")
+
+type fileService struct {
+	fileRepo     interfaces.FileRepository
+	userRepo     interfaces.UserRepository
+	unitOfWork   interfaces.UnitOfWork
+	auditService serviceInterfaces.AuditService
+	uploads      blobstore.BlobStore
+	images       blobstore.BlobStore
+	attachments  blobstore.BlobStore
+	cfg          *config.Config
+	wake         chan struct{}
+}
+
+func NewFileService(
+	fileRepo interfaces.FileRepository,
+	userRepo interfaces.UserRepository,
+	unitOfWork interfaces.UnitOfWork,
+	auditService serviceInterfaces.AuditService,
+	uploads blobstore.BlobStore,
+	images blobstore.BlobStore,
+	attachments blobstore.BlobStore,
+	cfg *config.Config,
+) serviceInterfaces.FileService {
+	return &fileService{
+		fileRepo:     fileRepo,
+		userRepo:     userRepo,
+		unitOfWork:   unitOfWork,
+		auditService: auditService,
+		uploads:      uploads,
+		images:       images,
+		attachments:  attachments,
+		cfg:          cfg,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+func (s *fileService) UploadImage(c *fiber.Ctx, uploader dto.Actor, image *multipart.FileHeader) (entity.File, error, int) {
+	sourceName, err := util.StoreUpload(c, s.uploads, image, s.imageLimits())
+	if errors.Is(err, util.ErrInvalidImage) {
+		return entity.File{}, err, fiber.StatusBadRequest
+	}
+	if err != nil {
+		return entity.File{}, fmt.Errorf("failed to save image: %w", err), fiber.StatusInternalServerError
+	}
+
+	now := time.Now()
+	file := entity.File{
+		OriginalName:  util.SanitizeFileName(image.Filename),
+		Purpose:       entity.FilePurposeAvatar,
+		Status:        entity.FileStatusPending,
+		SourceName:    sourceName,
+		NextAttemptAt: &now,
+	}
+	setUploader(&file, uploader)
+	return file, nil, fiber.StatusOK
+}
+
+func (s *fileService) DiscardUpload(sourceName string) {
+	_ = util.DeleteFile(s.uploads, sourceName)
+}
+
+func (s *fileService) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// A wake-up is pending already
+	}
+}
+
+func (s *fileService) ListFiles(c *fiber.Ctx, actor dto.Actor, userID uint, purpose string) ([]dto.FileResponse, error, int) {
+	user, err, status := s.findOwner(actor, userID)
+	if err != nil {
+		return nil, err, status
+	}
+	if purpose == entity.FilePurposeDocument && !canManageFiles(actor, userID) {
+		return nil, errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	// Newest first; thumbnails are listed with the image they were made of
+	response := make([]dto.FileResponse, 0, len(user.Files))
+	for i := len(user.Files) - 1; i >= 0; i-- {
+		file := user.Files[i]
+		if file.ParentID != nil || (purpose != "" && file.Purpose != purpose) || !canReadFile(actor, file) {
+			continue
+		}
+		response = append(response, s.toFileResponse(c, file, user.Files))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *fileService) UploadFile(c *fiber.Ctx, actor dto.Actor, userID uint) (dto.FileResponse, error, int) {
+	user, err, status := s.findOwner(actor, userID)
+	if err != nil {
+		return dto.FileResponse{}, err, status
+	}
+	if !canManageFiles(actor, userID) {
+		return dto.FileResponse{}, errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	if purpose := c.FormValue("purpose", entity.FilePurposeDocument); purpose != entity.FilePurposeDocument {
+		return dto.FileResponse{}, errors.New("only documents can be uploaded here, avatars are uploaded to /api/me/avatar"), fiber.StatusBadRequest
+	}
+
+	upload, err := c.FormFile("file")
+	if err != nil {
+		return dto.FileResponse{}, errors.New("file is required"), fiber.StatusBadRequest
+	}
+
+	documents := 0
+	for _, file := range user.Files {
+		if file.Purpose == entity.FilePurposeDocument {
+			documents++
+		}
+	}
+	if documents >= s.cfg.FileMaxPerUser {
+		return dto.FileResponse{}, fmt.Errorf("user has reached the limit of %d files", s.cfg.FileMaxPerUser), fiber.StatusConflict
+	}
+
+	stored, err := util.StoreAttachment(c, s.attachments, upload, util.AttachmentLimits{
+		MaxBytes:     s.cfg.FileMaxBytes,
+		AllowedTypes: s.cfg.FileAllowedTypes,
+	})
+	if errors.Is(err, util.ErrInvalidFile) {
+		return dto.FileResponse{}, err, fiber.StatusBadRequest
+	}
+	if err != nil {
+		return dto.FileResponse{}, fmt.Errorf("failed to save file: %w", err), fiber.StatusInternalServerError
+	}
+
+	file := entity.File{
+		FileName:     stored.Name,
+		OriginalName: util.SanitizeFileName(upload.Filename),
+		Purpose:      entity.FilePurposeDocument,
+		MimeType:     stored.ContentType,
+		Size:         stored.Size,
+		Checksum:     stored.Checksum,
+		Width:        stored.Width,
+		Height:       stored.Height,
+		UserID:       userID,
+		Status:       entity.FileStatusReady,
+	}
+	setUploader(&file, actor)
+	if err := s.fileRepo.Create(&file); err != nil {
+		_ = util.DeleteFile(s.attachments, stored.Name)
+		return dto.FileResponse{}, fmt.Errorf("failed to create file record: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.recordFile(actor, entity.AuditActionFileUploaded, file)
+
+	return s.toFileResponse(c, file, nil), nil, fiber.StatusCreated
+}
+
+func (s *fileService) GetFile(c *fiber.Ctx, actor dto.Actor, userID, fileID uint) (dto.FileResponse, error, int) {
+	user, err, status := s.findOwner(actor, userID)
+	if err != nil {
+		return dto.FileResponse{}, err, status
+	}
+
+	file, err, status := s.findFile(userID, fileID)
+	if err != nil {
+		return dto.FileResponse{}, err, status
+	}
+	if !canReadFile(actor, file) {
+		return dto.FileResponse{}, errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	// The user was loaded before the file, so a just finished image may lack its thumbnails
+	files := user.Files
+	if file.Status == entity.FileStatusReady && file.Purpose == entity.FilePurposeAvatar {
+		if files, err = s.fileRepo.FindByUserID(userID); err != nil {
+			return dto.FileResponse{}, fmt.Errorf("failed to retrieve files: %w", err), fiber.StatusInternalServerError
+		}
+	}
+
+	return s.toFileResponse(c, file, files), nil, fiber.StatusOK
+}
+
+func (s *fileService) DownloadFile(c *fiber.Ctx, actor dto.Actor, userID, fileID uint) (dto.FileDownload, error, int) {
+	if _, err, status := s.findOwner(actor, userID); err != nil {
+		return dto.FileDownload{}, err, status
+	}
+
+	file, err, status := s.findFile(userID, fileID)
+	if err != nil {
+		return dto.FileDownload{}, err, status
+	}
+	if !canReadFile(actor, file) {
+		return dto.FileDownload{}, errors.New("permission denied"), fiber.StatusForbidden
+	}
+	if file.Status != entity.FileStatusReady {
+		return dto.FileDownload{}, errors.New("file is not ready"), fiber.StatusConflict
+	}
+
+	body, err := s.storeOf(file).Get(c.UserContext(), file.FileName)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return dto.FileDownload{}, errors.New("file content not found"), fiber.StatusNotFound
+	}
+	if err != nil {
+		return dto.FileDownload{}, fmt.Errorf("failed to read file: %w", err), fiber.StatusInternalServerError
+	}
+
+	download := dto.FileDownload{
+		Name:        file.OriginalName,
+		ContentType: file.MimeType,
+		Size:        file.Size,
+		Body:        body,
+	}
+	// Images stored before their metadata was recorded
+	if download.Name == "" {
+		download.Name = path.Base(file.FileName)
+	}
+	if download.ContentType == "" {
+		download.ContentType = "application/octet-stream"
+	}
+	if download.Size == 0 {
+		download.Size = -1
+	}
+	return download, nil, fiber.StatusOK
+}
+
+func (s *fileService) DeleteFile(actor dto.Actor, userID, fileID uint) (error, int) {
+	user, err, status := s.findOwner(actor, userID)
+	if err != nil {
+		return err, status
+	}
+
+	file, err, status := s.findFile(userID, fileID)
+	if err != nil {
+		return err, status
+	}
+
+	// Whoever uploaded a file may take it back
+	if !canManageFiles(actor, userID) && !uploadedBy(file, actor) {
+		return errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	ids := []uint{file.ID}
+	files := []entity.File{file}
+	for _, thumbnail := range user.Files {
+		if thumbnail.ParentID != nil && *thumbnail.ParentID == file.ID {
+			ids = append(ids, thumbnail.ID)
+			files = append(files, thumbnail)
+		}
+	}
+
+	// Deleting the avatar image shown removes the avatar
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Files().DeleteByIDs(ids); err != nil {
+			return err
+		}
+		if current, ok := currentImage(user); !ok || current.ID != file.ID {
+			return nil
+		}
+
+		previousImage := user.ImageName
+		user.ImageName = ""
+		return saveImageChange(tx, &user, previousImage)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.DeleteBlobs(files)
+
+	s.recordFile(actor, entity.AuditActionFileDeleted, file)
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *fileService) ImageURLs(c *fiber.Ctx, user entity.User) map[string]string {
+	if user.ImageName == "" {
+		return make(map[string]string)
+	}
+
+	// Images stored before they had a record of their own come without thumbnails
+	original, _ := currentImage(user)
+	original.FileName = user.ImageName
+	return s.imageURLs(c, original, user.Files)
+}
+
+func (s *fileService) DeleteBlobs(files []entity.File) {
+	for _, file := range files {
+		_ = util.DeleteFile(s.storeOf(file), file.FileName)
+		_ = util.DeleteFile(s.uploads, file.SourceName)
+	}
+}
+
+func (s *fileService) RunWorkers(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Every running worker holds a slot until its attempt is saved
+	slots := make(chan struct{}, max(s.cfg.MediaWorkers, 1))
+	var workers sync.WaitGroup
+	defer workers.Wait()
+
+	for {
+		if err := s.startDue(ctx, slots, &workers); err != nil {
+			log.Printf("media processing failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// startDue claims as many due files as there are free slots and processes each of them
+// in a worker of its own
+func (s *fileService) startDue(ctx context.Context, slots chan struct{}, workers *sync.WaitGroup) error {
+	free := cap(slots) - len(slots)
+	if free == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	due, err := s.fileRepo.FindDue(now, free)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve due files: %w", err)
+	}
+
+	// Claimed files are not due again before the attempt has timed out, so several
+	// instances can process from the same queue
+	leaseUntil := now.Add(s.cfg.MediaProcessingTimeout)
+	for i := range due {
+		file := due[i]
+		claimed, err := s.fileRepo.Claim(&file, now, leaseUntil)
+		if err != nil {
+			return fmt.Errorf("failed to claim file %d: %w", file.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		// Only this loop takes slots, so there is one free for every claimed file
+		slots <- struct{}{}
+		workers.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				workers.Done()
+				// More files may be waiting for a free slot
+				s.Wake()
+			}()
+			s.process(ctx, &file)
+		}()
+	}
+
+	return nil
+}
+
+// process makes one attempt at sanitizing the claimed file and storing its thumbnails,
+// and records the outcome
+func (s *fileService) process(ctx context.Context, file *entity.File) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.MediaProcessingTimeout)
+	defer cancel()
+
+	sourceName := file.SourceName
+	stored, err := util.ProcessImage(ctx, s.uploads, s.images, sourceName, s.imageLimits(), s.cfg.ImageVariantSizes)
+	if err == nil {
+		err = s.complete(file, stored)
+		if err != nil {
+			util.DeleteFiles(s.images, stored)
+		}
+	}
+	if errors.Is(err, errAttemptSuperseded) {
+		return
+	}
+	if err != nil {
+		s.fail(file, err)
+		return
+	}
+
+	s.DiscardUpload(sourceName)
+	s.removeObsolete(file.UserID)
+}
+
+// complete saves a processed image with the records of its thumbnails and makes it the
+// user's image, unless a newer one is shown already
+func (s *fileService) complete(file *entity.File, stored []util.StoredFile) error {
+	return s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		// Leave file as claimed, so a failure is recorded against the upload
+		ready := *file
+		ready.Status = entity.FileStatusReady
+		ready.FileName = stored[0].Name
+		ready.MimeType = stored[0].ContentType
+		ready.Size = stored[0].Size
+		ready.Checksum = stored[0].Checksum
+		ready.Width, ready.Height = stored[0].Width, stored[0].Height
+		ready.SourceName = ""
+		ready.NextAttemptAt = nil
+		ready.LastError = ""
+		finished, err := tx.Files().FinishAttempt(&ready)
+		if err != nil {
+			return fmt.Errorf("failed to update file record: %w", err)
+		}
+		if !finished {
+			return errAttemptSuperseded
+		}
+
+		for _, variant := range stored[1:] {
+			thumbnail := entity.File{
+				FileName:                 variant.Name,
+				Purpose:                  entity.FilePurposeAvatar,
+				MimeType:                 variant.ContentType,
+				Size:                     variant.Size,
+				Checksum:                 variant.Checksum,
+				Width:                    variant.Width,
+				Height:                   variant.Height,
+				Variant:                  variant.Variant,
+				ParentID:                 &file.ID,
+				UserID:                   file.UserID,
+				UploaderID:               file.UploaderID,
+				UploaderServiceAccountID: file.UploaderServiceAccountID,
+				Status:                   entity.FileStatusReady,
+			}
+			if err := tx.Files().Create(&thumbnail); err != nil {
+				return fmt.Errorf("failed to create file record: %w", err)
+			}
+		}
+
+		user, err := tx.Users().FindByID(file.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve user: %w", err)
+		}
+		if current, ok := currentImage(user); ok && current.ID > ready.ID {
+			return nil
+		}
+
+		previousImage := user.ImageName
+		user.ImageName = ready.FileName
+		return saveImageChange(tx, &user, previousImage)
+	})
+}
+
+// fail records a failed attempt. Invalid images and files out of attempts are given up,
+// the others retried later.
+func (s *fileService) fail(file *entity.File, cause error) {
+	file.LastError = truncate(cause.Error(), 1024)
+
+	if !errors.Is(cause, util.ErrInvalidImage) && file.Attempts < s.cfg.MediaMaxAttempts {
+		next := time.Now().Add(s.retryDelay(file.Attempts))
+		file.Status = entity.FileStatusPending
+		file.NextAttemptAt = &next
+		if _, err := s.fileRepo.FinishAttempt(file); err != nil {
+			log.Printf("failed to record attempt of file %d: %v", file.ID, err)
+		}
+		return
+	}
+
+	sourceName := file.SourceName
+	file.Status = entity.FileStatusFailed
+	file.SourceName = ""
+	file.NextAttemptAt = nil
+	log.Printf("file %d of user:%d given up after %d attempts: %s", file.ID, file.UserID, file.Attempts, file.LastError)
+
+	finished, err := s.fileRepo.FinishAttempt(file)
+	if err != nil {
+		log.Printf("failed to record attempt of file %d: %v", file.ID, err)
+		return
+	}
+	if finished {
+		s.DiscardUpload(sourceName)
+	}
+}
+
+// removeObsolete removes the images of the user older than the one shown, together with
+// their thumbnails, once they are done processing
+func (s *fileService) removeObsolete(userID uint) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		log.Printf("failed to retrieve user:%d to remove old images: %v", userID, err)
+		return
+	}
+	current, ok := currentImage(user)
+	if !ok {
+		return
+	}
+
+	obsolete := make(map[uint]bool)
+	for _, file := range user.Files {
+		done := file.Status == entity.FileStatusReady || file.Status == entity.FileStatusFailed
+		avatar := file.Purpose == entity.FilePurposeAvatar && file.ParentID == nil && file.Variant == 0
+		if avatar && file.ID < current.ID && done {
+			obsolete[file.ID] = true
+		}
+	}
+
+	var ids []uint
+	var files []entity.File
+	for _, file := range user.Files {
+		// Thumbnails stored before they were linked to their image belong to an old one, too
+		legacy := file.Purpose == entity.FilePurposeAvatar && file.ParentID == nil && file.Variant > 0
+		if obsolete[file.ID] || legacy || (file.ParentID != nil && obsolete[*file.ParentID]) {
+			ids = append(ids, file.ID)
+			files = append(files, file)
+		}
+	}
+
+	if err := s.fileRepo.DeleteByIDs(ids); err != nil {
+		log.Printf("failed to remove old images of user:%d: %v", userID, err)
+		return
+	}
+	s.DeleteBlobs(files)
+}
+
+// retryDelay doubles the wait with every failed attempt, starting at MediaRetryBaseDelay
+func (s *fileService) retryDelay(attempts int) time.Duration {
+	delay := s.cfg.MediaRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.cfg.MediaRetryMaxDelay {
+			return s.cfg.MediaRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// findOwner loads the user whose files the actor wants to reach. Everyone reaches their
+// own files, others those of the users they can see.
+func (s *fileService) findOwner(actor dto.Actor, userID uint) (entity.User, error, int) {
+	users := s.userRepo
+	if actor.UserID != userID && !hasAdminRights(actor) {
+		users = s.userRepo.InOrganization(actor.OrganizationID)
+	}
+
+	user, err := users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.User{}, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return entity.User{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+	return user, nil, fiber.StatusOK
+}
+
+// findFile loads a file of the user; thumbnails are reached through their image
+func (s *fileService) findFile(userID, fileID uint) (entity.File, error, int) {
+	file, err := s.fileRepo.FindByID(fileID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.File{}, fmt.Errorf("failed to retrieve file: %w", err), fiber.StatusInternalServerError
+	}
+	if err != nil || file.UserID != userID || file.ParentID != nil {
+		return entity.File{}, errors.New("file not found"), fiber.StatusNotFound
+	}
+	return file, nil, fiber.StatusOK
+}
+
+// storeOf returns the blob store holding the files of the file's purpose
+func (s *fileService) storeOf(file entity.File) blobstore.BlobStore {
+	if file.Purpose == entity.FilePurposeDocument {
+		return s.attachments
+	}
+	return s.images
+}
+
+func (s *fileService) toFileResponse(c *fiber.Ctx, file entity.File, files []entity.File) dto.FileResponse {
+	response := dto.FileResponse{
+		ID:                       file.ID,
+		Purpose:                  file.Purpose,
+		Name:                     file.OriginalName,
+		MimeType:                 file.MimeType,
+		Size:                     file.Size,
+		Checksum:                 file.Checksum,
+		Width:                    file.Width,
+		Height:                   file.Height,
+		UploaderID:               file.UploaderID,
+		UploaderServiceAccountID: file.UploaderServiceAccountID,
+		Status:                   file.Status,
+		Attempts:                 file.Attempts,
+		Error:                    file.LastError,
+		CreatedAt:                file.CreatedAt,
+		UpdatedAt:                file.UpdatedAt,
+	}
+	if file.Status == entity.FileStatusReady && file.Purpose == entity.FilePurposeAvatar {
+		response.Images = s.imageURLs(c, file, files)
+	}
+	return response
+}
+
+// recordFile adds a change of a user's file to the audit log
+func (s *fileService) recordFile(actor dto.Actor, action string, file entity.File) {
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     action,
+		TargetType: entity.AuditTargetFile,
+		TargetID:   file.ID,
+		Details: map[string]any{
+			"user_id":  file.UserID,
+			"purpose":  file.Purpose,
+			"name":     file.OriginalName,
+			"size":     file.Size,
+			"checksum": file.Checksum,
+		},
+	})
+}
+
+func (s *fileService) imageLimits() util.ImageLimits {
+	return util.ImageLimits{
+		MaxBytes:  s.cfg.ImageMaxBytes,
+		MaxWidth:  s.cfg.ImageMaxWidth,
+		MaxHeight: s.cfg.ImageMaxHeight,
+	}
+}
+
+// imageURLs maps the thumbnails of original among files by edge length, and original
+// itself by "original", to their URLs, ready for an srcset
+func (s *fileService) imageURLs(c *fiber.Ctx, original entity.File, files []entity.File) map[string]string {
+	urls := map[string]string{
+		dto.ImageOriginal: util.BuildImageURL(c, s.images, original.FileName, s.cfg.ImageURLExpiry),
+	}
+	for _, file := range files {
+		if file.ParentID != nil && *file.ParentID == original.ID && original.ID != 0 {
+			urls[strconv.Itoa(file.Variant)] = util.BuildImageURL(c, s.images, file.FileName, s.cfg.ImageURLExpiry)
+		}
+	}
+	return urls
+}
+
+// canManageFiles reports whether the actor may add and remove files of the user
+func canManageFiles(actor dto.Actor, userID uint) bool {
+	return actor.UserID == userID || hasAdminRights(actor) || hasPermission(actor, entity.ScopeUsersWrite)
+}
+
+// setUploader records the principal uploading the file
+func setUploader(file *entity.File, actor dto.Actor) {
+	if actor.ServiceAccountID != 0 {
+		file.UploaderServiceAccountID = &actor.ServiceAccountID
+	} else if actor.UserID != 0 {
+		file.UploaderID = &actor.UserID
+	}
+}
+
+// uploadedBy reports whether the actor uploaded the file. Users and service accounts
+// have separate IDs, so both the kind of principal and its ID have to match.
+func uploadedBy(file entity.File, actor dto.Actor) bool {
+	if actor.ServiceAccountID != 0 {
+		return file.UploaderServiceAccountID != nil && *file.UploaderServiceAccountID == actor.ServiceAccountID
+	}
+	return actor.UserID != 0 && file.UploaderID != nil && *file.UploaderID == actor.UserID
+}
+
+// canReadFile reports whether the actor may see a file. Avatars are shown to everyone who
+// can see the user; documents only to those who may manage the user's files.
+func canReadFile(actor dto.Actor, file entity.File) bool {
+	return file.Purpose == entity.FilePurposeAvatar || canManageFiles(actor, file.UserID)
+}
+
+// currentImage returns the record of the image the user shows
+func currentImage(user entity.User) (entity.File, bool) {
+	if user.ImageName == "" {
+		return entity.File{}, false
+	}
+	for _, file := range user.Files {
+		if file.Purpose == entity.FilePurposeAvatar && file.ParentID == nil && file.FileName == user.ImageName {
+			return file, true
+		}
+	}
+	return entity.File{}, false
+}
+
+// pendingImageID returns the latest image of the user still being processed, 0 without one
+func pendingImageID(user entity.User) uint {
+	var id uint
+	for _, file := range user.Files {
+		queued := file.Status == entity.FileStatusPending || file.Status == entity.FileStatusProcessing
+		if file.Purpose == entity.FilePurposeAvatar && file.ParentID == nil && queued && file.ID > id {
+			id = file.ID
+		}
+	}
+	return id
+}
+
+// saveImageChange persists a replaced or removed image of the user together with its
+// events, in the transaction of tx
+func saveImageChange(tx interfaces.Repositories, user *entity.User, previousImage string) error {
+	if err := tx.Users().UpdateImageName(user.ID, user.ImageName); err != nil {
+		return err
+	}
+
+	data := dto.UserEventData{User: eventUser(*user)}
+	if err := stageUserEvent(tx.Outbox(), entity.EventUserUpdated, data); err != nil {
+		return err
+	}
+	return stageUserEvent(tx.Outbox(), entity.EventImageReplaced, dto.UserEventData{User: data.User, PreviousImage: previousImage})
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+)
+
+func TestUploadedBy(t *testing.T) {
+	user := dto.Actor{UserID: 1, Role: "user"}
+	account := dto.Actor{ServiceAccountID: 1, Role: entity.ServiceAccountRole}
+	otherAccount := dto.Actor{ServiceAccountID: 2, Role: entity.ServiceAccountRole}
+
+	var byUser, byAccount entity.File
+	setUploader(&byUser, user)
+	setUploader(&byAccount, account)
+	if byAccount.UploaderID != nil {
+		t.Fatalf("UploaderID = %d for a service account upload, want nil", *byAccount.UploaderID)
+	}
+
+	tests := []struct {
+		name  string
+		file  entity.File
+		actor dto.Actor
+		want  bool
+	}{
+		{"user deleting their upload", byUser, user, true},
+		{"service account deleting its upload", byAccount, account, true},
+		{"service account deleting another service account's upload", byAccount, otherAccount, false},
+		{"service account deleting a user's upload with the same ID", byUser, account, false},
+		{"user deleting a service account's upload with the same ID", byAccount, user, false},
+	}
+	for _, test := range tests {
+		if got := uploadedBy(test.file, test.actor); got != test.want {
+			t.Errorf("%s: uploadedBy = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type groupService struct {
+	groupRepo      interfaces.GroupRepository
+	membershipRepo interfaces.MembershipRepository
+	userRepo       interfaces.UserRepository
+	auditService   serviceInterfaces.AuditService
+}
+
+func NewGroupService(
+	groupRepo interfaces.GroupRepository,
+	membershipRepo interfaces.MembershipRepository,
+	userRepo interfaces.UserRepository,
+	auditService serviceInterfaces.AuditService,
+) serviceInterfaces.GroupService {
+	return &groupService{
+		groupRepo:      groupRepo,
+		membershipRepo: membershipRepo,
+		userRepo:       userRepo,
+		auditService:   auditService,
+	}
+}
+
+func (s *groupService) CreateGroup(req dto.GroupRequest, actor dto.Actor) (dto.GroupResponse, error, int) {
+	organizationID := actor.OrganizationID
+	if hasAdminRights(actor) && req.OrganizationID != 0 {
+		organizationID = req.OrganizationID
+	}
+	if organizationID == 0 {
+		return dto.GroupResponse{}, errors.New("no organization selected"), fiber.StatusBadRequest
+	}
+
+	if !canManageGroups(actor, organizationID) {
+		return dto.GroupResponse{}, errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	group := entity.Group{OrganizationID: organizationID}
+	if err, status := s.applyGroupRequest(&group, req, actor); err != nil {
+		return dto.GroupResponse{}, err, status
+	}
+
+	if err := s.groupRepo.Create(&group); err != nil {
+		return dto.GroupResponse{}, fmt.Errorf("failed to create group: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionGroupCreated,
+		TargetType: entity.AuditTargetGroup,
+		TargetID:   group.ID,
+		After:      groupSnapshot(group),
+	})
+	return toGroupResponse(group), nil, fiber.StatusCreated
+}
+
+func (s *groupService) GetGroups(actor dto.Actor) ([]dto.GroupResponse, error, int) {
+	groups, err := s.groupRepo.FindByOrganizationID(actor.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve groups: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.GroupResponse, 0, len(groups))
+	for _, group := range groups {
+		response = append(response, toGroupResponse(group))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *groupService) GetGroup(id uint, actor dto.Actor) (dto.GroupResponse, error, int) {
+	group, err, status := s.findGroup(id, actor)
+	if err != nil {
+		return dto.GroupResponse{}, err, status
+	}
+
+	return toGroupResponse(group), nil, fiber.StatusOK
+}
+
+func (s *groupService) UpdateGroup(id uint, req dto.GroupRequest, actor dto.Actor) (dto.GroupResponse, error, int) {
+	group, err, status := s.findManagedGroup(id, actor)
+	if err != nil {
+		return dto.GroupResponse{}, err, status
+	}
+	before := groupSnapshot(group)
+
+	if err, status := s.applyGroupRequest(&group, req, actor); err != nil {
+		return dto.GroupResponse{}, err, status
+	}
+
+	if err := s.groupRepo.Update(&group); err != nil {
+		return dto.GroupResponse{}, fmt.Errorf("failed to update group: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionGroupUpdated,
+		TargetType: entity.AuditTargetGroup,
+		TargetID:   group.ID,
+		Before:     before,
+		After:      groupSnapshot(group),
+	})
+	return toGroupResponse(group), nil, fiber.StatusOK
+}
+
+func (s *groupService) DeleteGroup(id uint, actor dto.Actor) (error, int) {
+	group, err, status := s.findManagedGroup(id, actor)
+	if err != nil {
+		return err, status
+	}
+
+	children, err := s.groupRepo.CountChildren(group.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count child groups: %w", err), fiber.StatusInternalServerError
+	}
+	if children > 0 {
+		return errors.New("group still has child groups"), fiber.StatusConflict
+	}
+
+	if err := s.groupRepo.Delete(group.ID); err != nil {
+		return fmt.Errorf("failed to delete group: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionGroupDeleted,
+		TargetType: entity.AuditTargetGroup,
+		TargetID:   group.ID,
+		Before:     groupSnapshot(group),
+	})
+	return nil, fiber.StatusNoContent
+}
+
+func (s *groupService) GetMembers(id uint, actor dto.Actor) ([]dto.GroupMemberResponse, error, int) {
+	if _, err, status := s.findGroup(id, actor); err != nil {
+		return nil, err, status
+	}
+
+	users, err := s.groupRepo.FindMembers(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve members: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.GroupMemberResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, dto.GroupMemberResponse{
+			UserID: user.ID,
+			Name:   user.Name,
+			Email:  user.Email,
+		})
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *groupService) AddMember(id uint, req dto.AddGroupMemberRequest, actor dto.Actor) (error, int) {
+	group, err, status := s.findManagedGroup(id, actor)
+	if err != nil {
+		return err, status
+	}
+
+	// Groups only contain members of their organization
+	if _, err := s.membershipRepo.Find(group.OrganizationID, req.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found"), fiber.StatusNotFound
+		}
+		return fmt.Errorf("failed to retrieve membership: %w", err), fiber.StatusInternalServerError
+	}
+
+	if _, err := s.groupRepo.FindMember(group.ID, req.UserID); err == nil {
+		return errors.New("user is already a member"), fiber.StatusConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to retrieve group member: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Members gain the permissions of the group and its ancestors
+	permissions, err := s.inheritedPermissions(group)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve group permissions: %w", err), fiber.StatusInternalServerError
+	}
+	if err, status := checkGrantable(actor, permissions); err != nil {
+		return err, status
+	}
+
+	if err := s.groupRepo.AddMember(&entity.GroupMember{GroupID: group.ID, UserID: req.UserID}); err != nil {
+		return fmt.Errorf("failed to add member: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionGroupMemberAdded,
+		TargetType: entity.AuditTargetGroup,
+		TargetID:   group.ID,
+		Details:    map[string]any{"user_id": req.UserID},
+	})
+	return nil, fiber.StatusNoContent
+}
+
+func (s *groupService) RemoveMember(id, userID uint, actor dto.Actor) (error, int) {
+	group, err, status := s.findManagedGroup(id, actor)
+	if err != nil {
+		return err, status
+	}
+
+	if _, err := s.groupRepo.FindMember(group.ID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("member not found"), fiber.StatusNotFound
+		}
+		return fmt.Errorf("failed to retrieve group member: %w", err), fiber.StatusInternalServerError
+	}
+
+	if err := s.groupRepo.RemoveMember(group.ID, userID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionGroupMemberRemoved,
+		TargetType: entity.AuditTargetGroup,
+		TargetID:   group.ID,
+		Details:    map[string]any{"user_id": userID},
+	})
+	return nil, fiber.StatusNoContent
+}
+
+func (s *groupService) GetEffectiveGroups(userID, organizationID uint) ([]entity.Group, error) {
+	memberships, err := s.groupRepo.FindMembershipsByUserID(userID, organizationID)
+	if err != nil || len(memberships) == 0 {
+		return nil, err
+	}
+
+	groups, err := s.groupRepo.FindByOrganizationID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]entity.Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+
+	// Walk up from every direct group; members of a group are members of its ancestors
+	seen := make(map[uint]bool)
+	var effective []entity.Group
+	for _, membership := range memberships {
+		for id := &membership.GroupID; id != nil && !seen[*id]; {
+			group, ok := byID[*id]
+			if !ok {
+				break
+			}
+			seen[group.ID] = true
+			effective = append(effective, group)
+			id = group.ParentID
+		}
+	}
+
+	return effective, nil
+}
+
+func (s *groupService) GetPermissions(userID, organizationID uint) ([]string, error) {
+	groups, err := s.GetEffectiveGroups(userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return groupPermissions(groups), nil
+}
+
+// applyGroupRequest validates a create or update request and copies it onto group.
+// Whatever permissions the change hands to the group's members, directly or through
+// a new parent, the actor must hold themselves.
+func (s *groupService) applyGroupRequest(group *entity.Group, req dto.GroupRequest, actor dto.Actor) (error, int) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("name is required"), fiber.StatusBadRequest
+	}
+
+	if existing, err := s.groupRepo.FindByName(group.OrganizationID, name); err == nil && existing.ID != group.ID {
+		return errors.New("group name already exists"), fiber.StatusConflict
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("failed to check group name"), fiber.StatusInternalServerError
+	}
+
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return err, fiber.StatusBadRequest
+	}
+
+	if req.ParentID != nil {
+		if err, status := s.checkParent(group, *req.ParentID); err != nil {
+			return err, status
+		}
+	}
+
+	changed := *group
+	changed.Name = name
+	changed.Description = strings.TrimSpace(req.Description)
+	changed.ParentID = req.ParentID
+	changed.Permissions = strings.Join(permissions, " ")
+
+	granted, err := s.inheritedPermissions(changed)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve group permissions: %w", err), fiber.StatusInternalServerError
+	}
+	if group.ID != 0 {
+		current, err := s.inheritedPermissions(*group)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve group permissions: %w", err), fiber.StatusInternalServerError
+		}
+		granted = slices.DeleteFunc(granted, func(permission string) bool {
+			return slices.Contains(current, permission)
+		})
+	}
+	if err, status := checkGrantable(actor, granted); err != nil {
+		return err, status
+	}
+
+	*group = changed
+	return nil, fiber.StatusOK
+}
+
+// inheritedPermissions returns the permissions members of group hold through it and its ancestors
+func (s *groupService) inheritedPermissions(group entity.Group) ([]string, error) {
+	groups := []entity.Group{group}
+	for id := group.ParentID; id != nil; {
+		ancestor, err := s.groupRepo.FindByID(*id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, ancestor)
+		id = ancestor.ParentID
+	}
+	return groupPermissions(groups), nil
+}
+
+// checkParent makes sure parentID is a group of the same organization and does not create a cycle
+func (s *groupService) checkParent(group *entity.Group, parentID uint) (error, int) {
+	for id := parentID; ; {
+		if group.ID != 0 && id == group.ID {
+			return errors.New("a group cannot be nested inside itself"), fiber.StatusBadRequest
+		}
+
+		ancestor, err := s.groupRepo.FindByID(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("parent group not found"), fiber.StatusBadRequest
+			}
+			return fmt.Errorf("failed to retrieve parent group: %w", err), fiber.StatusInternalServerError
+		}
+		if ancestor.OrganizationID != group.OrganizationID {
+			return errors.New("parent group not found"), fiber.StatusBadRequest
+		}
+
+		if ancestor.ParentID == nil {
+			return nil, fiber.StatusOK
+		}
+		id = *ancestor.ParentID
+	}
+}
+
+// findGroup loads a group of the actor's current organization; global admins see every group
+func (s *groupService) findGroup(id uint, actor dto.Actor) (entity.Group, error, int) {
+	group, err := s.groupRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.Group{}, errors.New("group not found"), fiber.StatusNotFound
+		}
+		return entity.Group{}, fmt.Errorf("failed to retrieve group: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Do not reveal groups of other tenants
+	if !hasAdminRights(actor) && group.OrganizationID != actor.OrganizationID {
+		return entity.Group{}, errors.New("group not found"), fiber.StatusNotFound
+	}
+
+	return group, nil, fiber.StatusOK
+}
+
+func (s *groupService) findManagedGroup(id uint, actor dto.Actor) (entity.Group, error, int) {
+	group, err, status := s.findGroup(id, actor)
+	if err != nil {
+		return entity.Group{}, err, status
+	}
+
+	if !canManageGroups(actor, group.OrganizationID) {
+		return entity.Group{}, errors.New("permission denied"), fiber.StatusForbidden
+	}
+
+	return group, nil, fiber.StatusOK
+}
+
+// canManageGroups reports whether the actor may change the groups of an organization
+func canManageGroups(actor dto.Actor, organizationID uint) bool {
+	if hasAdminRights(actor) {
+		return true
+	}
+	return actor.OrganizationID == organizationID && hasPermission(actor, entity.PermissionGroupsManage)
+}
+
+// checkGrantable rejects handing out permissions the actor does not hold; global admins hold them all
+func checkGrantable(actor dto.Actor, permissions []string) (error, int) {
+	if hasAdminRights(actor) {
+		return nil, fiber.StatusOK
+	}
+	for _, permission := range permissions {
+		if !hasPermission(actor, permission) {
+			return fmt.Errorf("cannot grant permission %q you do not hold", permission), fiber.StatusForbidden
+		}
+	}
+	return nil, fiber.StatusOK
+}
+
+// normalizePermissions validates permissions against entity.AvailablePermissions and removes duplicates
+func normalizePermissions(permissions []string) ([]string, error) {
+	var result []string
+	for _, permission := range permissions {
+		if !slices.Contains(entity.AvailablePermissions, permission) {
+			return nil, fmt.Errorf("unknown permission %q", permission)
+		}
+		if !slices.Contains(result, permission) {
+			result = append(result, permission)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// groupPermissions returns the union of the permissions of groups
+func groupPermissions(groups []entity.Group) []string {
+	var permissions []string
+	for _, group := range groups {
+		for _, permission := range strings.Fields(group.Permissions) {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+func toGroupResponse(group entity.Group) dto.GroupResponse {
+	permissions := strings.Fields(group.Permissions)
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return dto.GroupResponse{
+		ID:             group.ID,
+		OrganizationID: group.OrganizationID,
+		ParentID:       group.ParentID,
+		Name:           group.Name,
+		Description:    group.Description,
+		Permissions:    permissions,
+		CreatedAt:      group.CreatedAt,
+	}
+}
+
+// groupSnapshot is the part of a group recorded in audit log diffs
+func groupSnapshot(group entity.Group) map[string]any {
+	return map[string]any{
+		"organization_id": group.OrganizationID,
+		"parent_id":       group.ParentID,
+		"name":            group.Name,
+		"permissions":     group.Permissions,
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository"
+	"user_crud/pkg/storage"
+)
+
+// newTestDB opens a migrated SQLite database in a temporary directory with the roles seeded
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := storage.NewDatabaseConnection(filepath.Join(t.TempDir(), "test.db"))
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection serializes writers, as SQLite would otherwise answer "database is locked"
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, name := range []string{"admin", "moderator", "user"} {
+		if err := db.Create(&entity.Role{Name: name}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// createTestUser stores a user with the role of the given name
+func createTestUser(t *testing.T, db *gorm.DB, email, roleName string) entity.User {
+	t.Helper()
+
+	role, err := repository.NewRoleRepository(db).FindByName(roleName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := entity.User{Name: email, Email: email, Password: "x", Age: 30, RoleID: role.ID}
+	if err := repository.NewUserRepository(db).Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	user.Role = role
+	return user
+}
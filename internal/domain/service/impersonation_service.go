@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type impersonationService struct {
+	userRepo       interfaces.UserRepository
+	roleGrantRepo  interfaces.RoleGrantRepository
+	sessionService serviceInterfaces.SessionService
+	auditService   serviceInterfaces.AuditService
+}
+
+func NewImpersonationService(
+	userRepo interfaces.UserRepository,
+	roleGrantRepo interfaces.RoleGrantRepository,
+	sessionService serviceInterfaces.SessionService,
+	auditService serviceInterfaces.AuditService,
+) serviceInterfaces.ImpersonationService {
+	return &impersonationService{
+		userRepo:       userRepo,
+		roleGrantRepo:  roleGrantRepo,
+		sessionService: sessionService,
+		auditService:   auditService,
+	}
+}
+
+func (s *impersonationService) Impersonate(userID uint, req dto.ImpersonateRequest, actor dto.Actor, client dto.ClientInfo) (dto.ImpersonationResponse, error, int) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return dto.ImpersonationResponse{}, errors.New("reason is required"), fiber.StatusBadRequest
+	}
+
+	if userID == actor.UserID {
+		return dto.ImpersonationResponse{}, errors.New("cannot impersonate yourself"), fiber.StatusBadRequest
+	}
+
+	impersonator, err := s.userRepo.FindByID(actor.UserID)
+	if err != nil {
+		return dto.ImpersonationResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.ImpersonationResponse{}, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return dto.ImpersonationResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Admins, including users temporarily granted the role, cannot be impersonated
+	isAdmin, err := s.isAdmin(user)
+	if err != nil {
+		return dto.ImpersonationResponse{}, fmt.Errorf("failed to retrieve role grants: %w", err), fiber.StatusInternalServerError
+	}
+	if isAdmin {
+		return dto.ImpersonationResponse{}, errors.New("admins cannot be impersonated"), fiber.StatusForbidden
+	}
+
+	if user.Status != entity.UserStatusActive {
+		return dto.ImpersonationResponse{}, errors.New("user account is not active"), fiber.StatusConflict
+	}
+
+	response, err, status := s.sessionService.StartImpersonation(user, impersonator, client)
+	if err != nil {
+		return dto.ImpersonationResponse{}, err, status
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionImpersonationStarted,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+		Details: map[string]any{
+			"session_id": response.SessionID,
+			"expires_at": response.ExpiresAt.UTC().Format(time.RFC3339),
+			"reason":     reason,
+		},
+	})
+
+	return response, nil, status
+}
+
+// isAdmin reports whether the user holds the admin role, permanently or through an active grant
+func (s *impersonationService) isAdmin(user entity.User) (bool, error) {
+	if user.Role.Name == "admin" {
+		return true, nil
+	}
+
+	grant, err := s.roleGrantRepo.FindActiveByUserID(user.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return grant.Role.Name == "admin", nil
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"io"
+
+	"user_crud/internal/dto"
+)
+
+type AuditService interface {
+	// Record appends an event to the audit log. Failures are logged and never fail the caller.
+	Record(record dto.AuditRecord)
+	GetEvents(query dto.AuditEventQuery) (dto.AuditEventListResponse, error, int)
+	// ExportEvents writes the matching events, oldest first, as JSON Lines
+	ExportEvents(query dto.AuditEventQuery, w io.Writer) error
+	// VerifyChain recomputes the hash chain over the whole log
+	VerifyChain() (dto.AuditVerifyResponse, error, int)
+}
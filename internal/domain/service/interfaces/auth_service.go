@@ -5,7 +5,7 @@ import (
 )
 
 type AuthService interface {
-	Register(req dto.RegisterRequest) (dto.TokenResponse, error, int)
-	Login(req dto.LoginRequest) (dto.TokenResponse, error, int)
-	RefreshToken(refreshToken string) (dto.TokenResponse, error, int)
+	Register(req dto.RegisterRequest, client dto.ClientInfo) (dto.TokenResponse, error, int)
+	Login(req dto.LoginRequest, client dto.ClientInfo) (dto.TokenResponse, error, int)
+	RefreshToken(refreshToken string, client dto.ClientInfo) (dto.TokenResponse, error, int)
 }
@@ -0,0 +1,44 @@
+package interfaces
+
+import (
+	"context"
+	"mime/multipart"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+)
+
+type FileService interface {
+	// UploadImage keeps an uploaded avatar image for processing and returns the record to
+	// queue it with; set its UserID and create it with the change the image belongs to.
+	// Only cheap checks run here; the image is sanitized by the workers.
+	UploadImage(c *fiber.Ctx, uploader dto.Actor, image *multipart.FileHeader) (entity.File, error, int)
+	// DiscardUpload removes an upload that never made it into the processing queue
+	DiscardUpload(sourceName string)
+	// Wake lets the workers pick up newly queued files without waiting for the next poll
+	Wake()
+	// The files of a user are reached by the user, and by whoever can see the user.
+	// Uploading and deleting takes the permission to manage users, except for the user
+	// themselves and, on deletion, whoever uploaded the file.
+
+	// ListFiles lists the user's files newest first, of the purpose unless it is empty
+	ListFiles(c *fiber.Ctx, actor dto.Actor, userID uint, purpose string) ([]dto.FileResponse, error, int)
+	// UploadFile stores the document in the "file" form field as an attachment of the user
+	UploadFile(c *fiber.Ctx, actor dto.Actor, userID uint) (dto.FileResponse, error, int)
+	// GetFile describes a file of the user and reports its processing status
+	GetFile(c *fiber.Ctx, actor dto.Actor, userID, fileID uint) (dto.FileResponse, error, int)
+	DownloadFile(c *fiber.Ctx, actor dto.Actor, userID, fileID uint) (dto.FileDownload, error, int)
+	// DeleteFile removes a file with its thumbnails; deleting the avatar image shown
+	// removes the avatar
+	DeleteFile(actor dto.Actor, userID, fileID uint) (error, int)
+	// ImageURLs maps the user's current image and its thumbnails to their URLs
+	ImageURLs(c *fiber.Ctx, user entity.User) map[string]string
+	// DeleteBlobs removes the stored data of files whose records are gone, ignoring failures
+	DeleteBlobs(files []entity.File)
+	// RunWorkers processes queued files, at most MediaWorkers at a time, until the context
+	// is cancelled. It looks for due files every interval and whenever it is woken up.
+	RunWorkers(ctx context.Context, interval time.Duration)
+}
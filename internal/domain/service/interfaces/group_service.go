@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+)
+
+type GroupService interface {
+	CreateGroup(req dto.GroupRequest, actor dto.Actor) (dto.GroupResponse, error, int)
+	GetGroups(actor dto.Actor) ([]dto.GroupResponse, error, int)
+	GetGroup(id uint, actor dto.Actor) (dto.GroupResponse, error, int)
+	UpdateGroup(id uint, req dto.GroupRequest, actor dto.Actor) (dto.GroupResponse, error, int)
+	DeleteGroup(id uint, actor dto.Actor) (error, int)
+	GetMembers(id uint, actor dto.Actor) ([]dto.GroupMemberResponse, error, int)
+	AddMember(id uint, req dto.AddGroupMemberRequest, actor dto.Actor) (error, int)
+	RemoveMember(id, userID uint, actor dto.Actor) (error, int)
+	// GetEffectiveGroups returns the groups of a user in an organization including all ancestors of their direct groups
+	GetEffectiveGroups(userID, organizationID uint) ([]entity.Group, error)
+	// GetPermissions returns the permissions a user receives through their effective groups
+	GetPermissions(userID, organizationID uint) ([]string, error)
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type ImpersonationService interface {
+	// Impersonate lets an admin act as another, non-admin user for a short time
+	Impersonate(userID uint, req dto.ImpersonateRequest, actor dto.Actor, client dto.ClientInfo) (dto.ImpersonationResponse, error, int)
+}
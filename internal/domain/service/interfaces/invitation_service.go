@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type InvitationService interface {
+	CreateInvitation(req dto.CreateInvitationRequest, inviterID uint) (dto.InvitationResponse, error, int)
+	GetAllInvitations(status string) ([]dto.InvitationResponse, error, int)
+	RevokeInvitation(id uint) (error, int)
+	AcceptInvitation(req dto.AcceptInvitationRequest, client dto.ClientInfo) (dto.TokenResponse, error, int)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type LoginGuardService interface {
+	CheckAllowed(email, ip string) (error, int)
+	RecordFailure(email string, client dto.ClientInfo)
+	RecordSuccess(email string)
+	UnlockUser(userID uint, ip string, actor dto.Actor) (error, int)
+}
@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"user_crud/internal/dto"
+)
+
+type NotificationService interface {
+	// Notify delivers a notification to the user on the channels their preferences enable
+	// for its type: stored and pushed to their connected clients in-app, and by email.
+	// Failures are logged rather than returned, so they never fail the action notified about.
+	Notify(userID uint, notification dto.Notification)
+	// GetNotifications lists the actor's notifications newest first, with their unread count
+	GetNotifications(actor dto.Actor, query dto.NotificationQuery) (dto.NotificationListResponse, error, int)
+	MarkRead(actor dto.Actor, id uint) (error, int)
+	MarkAllRead(actor dto.Actor) (error, int)
+	// GetPreferences lists the channels of every notification type, defaults included
+	GetPreferences(actor dto.Actor) ([]dto.NotificationPreferenceResponse, error, int)
+	UpdatePreferences(actor dto.Actor, req dto.UpdateNotificationPreferencesRequest) ([]dto.NotificationPreferenceResponse, error, int)
+	// DeleteExpired removes notifications past the retention period and returns how many were removed
+	DeleteExpired() (int64, error)
+	// RunRetentionSweeper calls DeleteExpired every interval until the context is cancelled
+	RunRetentionSweeper(ctx context.Context, interval time.Duration)
+}
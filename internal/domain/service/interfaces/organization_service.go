@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type OrganizationService interface {
+	// EnsureDefaultOrganization creates the configured default organization on first start
+	// and adds every existing user to it
+	EnsureDefaultOrganization() error
+	// JoinDefaultOrganization adds a self-registered or invited user to the default organization
+	JoinDefaultOrganization(userID uint) error
+	CreateOrganization(req dto.CreateOrganizationRequest, actor dto.Actor) (dto.OrganizationResponse, error, int)
+	GetAllOrganizations() ([]dto.OrganizationResponse, error, int)
+	GetOrganization(id uint, actor dto.Actor) (dto.OrganizationResponse, error, int)
+	UpdateOrganization(id uint, req dto.UpdateOrganizationRequest, actor dto.Actor) (dto.OrganizationResponse, error, int)
+	DeleteOrganization(id uint, actor dto.Actor) (error, int)
+	GetMembers(id uint, actor dto.Actor) ([]dto.MembershipResponse, error, int)
+	AddMember(id uint, req dto.AddMemberRequest, actor dto.Actor) (dto.MembershipResponse, error, int)
+	UpdateMember(id, userID uint, req dto.UpdateMemberRequest, actor dto.Actor) (dto.MembershipResponse, error, int)
+	RemoveMember(id, userID uint, actor dto.Actor) (error, int)
+	MoveMember(id, userID uint, req dto.MoveMemberRequest, actor dto.Actor) (dto.MembershipResponse, error, int)
+	GetUserOrganizations(userID, currentOrganizationID uint) ([]dto.MembershipResponse, error, int)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type OutboxService interface {
+	// DispatchDue publishes every due event to the sinks that have not accepted it yet
+	// and returns how many events were attempted
+	DispatchDue() (int, error)
+	// RunDispatcher calls DispatchDue every interval until the context is cancelled and
+	// removes published events once they are older than the retention period
+	RunDispatcher(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+)
+
+type PasswordPolicyService interface {
+	// ValidatePassword checks a new password for user, who may not be saved yet
+	ValidatePassword(password string, user entity.User) (error, int)
+	// RecordPassword adds a password hash to the user's history
+	RecordPassword(userID uint, passwordHash string) error
+}
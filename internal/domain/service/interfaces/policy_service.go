@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+	"user_crud/pkg/policy"
+)
+
+type PolicyService interface {
+	// Authorize decides whether the actor may perform the action on the resource
+	Authorize(actor dto.Actor, action string, resource, environment policy.Attributes) policy.Decision
+	// UserResource describes a user as a policy resource
+	UserResource(user entity.User) (policy.Attributes, error)
+	// Explain evaluates a request and reports the result of every rule
+	Explain(req dto.PolicyExplainRequest, actor dto.Actor) (dto.PolicyExplainResponse, error, int)
+	GetPolicy() policy.Policy
+	// Reload reads the policy file again; the current policy stays in place when it is invalid
+	Reload(actor dto.Actor) (policy.Policy, error, int)
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"user_crud/internal/dto"
+)
+
+type RealtimeService interface {
+	// Connect registers a WebSocket client of the actor, who is online until every one of
+	// their connections is closed. The first message lists the visible users online.
+	Connect(actor dto.Actor) (dto.RealtimeConnection, error, int)
+	// GetPresence reports which of the users are online, leaving out users the actor cannot see
+	GetPresence(actor dto.Actor, userIDs []uint) ([]dto.PresenceResponse, error, int)
+	// Push sends a message to every connection of the user, on any instance
+	Push(userID uint, messageType string, data any) error
+	// RunPresenceAnnouncer exchanges presence and pushed messages with the other instances
+	// through the hub, announcing this instance's users every interval, until the context
+	// is cancelled
+	RunPresenceAnnouncer(ctx context.Context, interval time.Duration)
+}
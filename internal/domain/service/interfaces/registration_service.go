@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type RegistrationService interface {
+	// CheckAllowed applies the registration mode and domain rules to a self-registration
+	CheckAllowed(email string) (error, int)
+	// InitialStatus is the account status given to self-registered users
+	InitialStatus() string
+	GetPendingRegistrations() ([]dto.RegistrationResponse, error, int)
+	ApproveRegistration(id uint, actor dto.Actor) (dto.RegistrationResponse, error, int)
+	RejectRegistration(id uint, req dto.RejectRegistrationRequest, actor dto.Actor) (dto.RegistrationResponse, error, int)
+}
@@ -0,0 +1,23 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"user_crud/internal/dto"
+)
+
+type RoleGrantService interface {
+	// RequestGrant asks for a temporary role for the actor; admins may grant one to another user directly
+	RequestGrant(req dto.RoleGrantRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int)
+	GetGrants(status string, userID uint) ([]dto.RoleGrantResponse, error, int)
+	GetUserGrants(userID uint) ([]dto.RoleGrantResponse, error, int)
+	ApproveGrant(id uint, req dto.RoleGrantDecisionRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int)
+	RejectGrant(id uint, req dto.RoleGrantDecisionRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int)
+	// RevokeGrant ends a grant early; admins and the grantee may revoke
+	RevokeGrant(id uint, actor dto.Actor) (dto.RoleGrantResponse, error, int)
+	// ExpireGrants marks grants whose time window has passed as expired and returns how many were changed
+	ExpireGrants() (int, error)
+	// RunExpirySweeper calls ExpireGrants every interval until the context is done
+	RunExpirySweeper(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"user_crud/internal/dto"
+)
+
+type ServiceAccountService interface {
+	CreateServiceAccount(req dto.CreateServiceAccountRequest, actor dto.Actor) (dto.ServiceAccountCredentialsResponse, error, int)
+	GetAllServiceAccounts() ([]dto.ServiceAccountResponse, error, int)
+	GetServiceAccount(id uint) (dto.ServiceAccountResponse, error, int)
+	UpdateServiceAccount(id uint, req dto.UpdateServiceAccountRequest, actor dto.Actor) (dto.ServiceAccountResponse, error, int)
+	RotateSecret(id uint, actor dto.Actor) (dto.ServiceAccountCredentialsResponse, error, int)
+	DeleteServiceAccount(id uint, actor dto.Actor) (error, int)
+	IssueToken(req dto.ServiceTokenRequest, client dto.ClientInfo) (dto.TokenResponse, error, int)
+	ValidateServiceAccount(id uint, scopes []string) ([]string, error)
+}
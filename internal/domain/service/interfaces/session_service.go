@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+)
+
+type SessionService interface {
+	StartSession(user entity.User, roleName string, client dto.ClientInfo) (dto.TokenResponse, error, int)
+	// StartImpersonation issues a short-lived, non-refreshable access token acting as the user on behalf of an admin
+	StartImpersonation(user, impersonator entity.User, client dto.ClientInfo) (dto.ImpersonationResponse, error, int)
+	RefreshSession(refreshToken string, client dto.ClientInfo) (dto.TokenResponse, error, int)
+	// ValidateSession checks the session is active and still matches the organization, organization role
+	// and role grant of the token
+	ValidateSession(sessionID, userID, organizationID uint, organizationRole string, roleGrantID uint) error
+	// SwitchOrganization moves a session to another organization of the user and issues new tokens
+	SwitchOrganization(sessionID, userID, organizationID uint) (dto.TokenResponse, error, int)
+	GetUserSessions(userID, currentSessionID uint) ([]dto.SessionResponse, error, int)
+	RevokeSession(userID, sessionID uint) (error, int)
+	RevokeAllSessions(userID uint) (error, int)
+}
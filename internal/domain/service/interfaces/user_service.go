@@ -7,9 +7,19 @@ import (
 )
 
 type UserService interface {
-	CreateUser(c *fiber.Ctx, currentUserRole string) (dto.UserResponse, error, int)
-	GetAllUsers(c *fiber.Ctx) ([]dto.UserResponse, error, int)
-	GetUser(id uint, c *fiber.Ctx) (dto.UserResponse, error, int)
-	UpdateUser(c *fiber.Ctx, id uint, currentUserID uint, currentUserRole string) (dto.UserResponse, error, int)
-	DeleteUser(id uint, currentUserID uint, currentUserRole string) (error, int)
+	// User management is scoped to the actor's organization unless the actor is a global admin
+	CreateUser(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int)
+	GetAllUsers(c *fiber.Ctx, actor dto.Actor) ([]dto.UserResponse, error, int)
+	GetUser(id uint, c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int)
+	UpdateUser(c *fiber.Ctx, id uint, actor dto.Actor) (dto.UserResponse, error, int)
+	DeleteUser(id uint, actor dto.Actor) (error, int)
+	ChangePassword(actor dto.Actor, currentSessionID uint, req dto.ChangePasswordRequest) (error, int)
+	RequestEmailChange(actor dto.Actor, req dto.ChangeEmailRequest) (error, int)
+	ConfirmEmailChange(token string, client dto.ClientInfo) (error, int)
+	CompletePasswordSetup(req dto.SetPasswordRequest, client dto.ClientInfo) (error, int)
+	// UpdateAvatar queues an uploaded image; the avatar switches to it once it is processed
+	UpdateAvatar(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int)
+	// DeleteAvatar removes the avatar and cancels images still being processed
+	DeleteAvatar(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int)
+	DeleteOwnAccount(actor dto.Actor, req dto.DeleteAccountRequest) (error, int)
 }
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"user_crud/internal/dto"
+	"user_crud/pkg/events"
+)
+
+type UserStreamService interface {
+	// Handle adds a user event to the stream and the bounded replay buffer.
+	// It is subscribed to the in-process event bus.
+	Handle(ctx context.Context, event events.Event) error
+	// Subscribe streams the user changes visible to the actor, replaying the buffered
+	// events after lastEventID first
+	Subscribe(actor dto.Actor, lastEventID string) (dto.UserStreamSubscription, error, int)
+}
@@ -0,0 +1,30 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"user_crud/internal/dto"
+	"user_crud/pkg/events"
+)
+
+type WebhookService interface {
+	// Sink queues a delivery of the user events for every active subscription to them.
+	// Subscriptions that already have a delivery of an event are skipped, so publishing
+	// an event again queues no duplicates.
+	events.Sink
+	CreateSubscription(req dto.CreateWebhookRequest, actor dto.Actor) (dto.WebhookSecretResponse, error, int)
+	GetSubscriptions() ([]dto.WebhookResponse, error, int)
+	GetSubscription(id uint) (dto.WebhookResponse, error, int)
+	UpdateSubscription(id uint, req dto.UpdateWebhookRequest, actor dto.Actor) (dto.WebhookResponse, error, int)
+	RotateSecret(id uint, actor dto.Actor) (dto.WebhookSecretResponse, error, int)
+	DeleteSubscription(id uint, actor dto.Actor) (error, int)
+	// GetDeliveries lists the delivery log of a subscription, newest first
+	GetDeliveries(id uint, status string, limit, offset int) ([]dto.WebhookDeliveryResponse, error, int)
+	// Redeliver queues the payload of a previous delivery again as a new delivery
+	Redeliver(id, deliveryID uint, actor dto.Actor) (dto.WebhookDeliveryResponse, error, int)
+	// DeliverDue makes one attempt at every due delivery and returns how many were attempted
+	DeliverDue() (int, error)
+	// RunDispatcher calls DeliverDue every interval until the context is cancelled
+	RunDispatcher(ctx context.Context, interval time.Duration)
+}
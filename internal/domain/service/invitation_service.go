@@ -0,0 +1,267 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/mailer"
+)
+
+const (
+	defaultInvitationExpiry = time.Hour * 24 * 7
+	maxInvitationExpiry     = time.Hour * 24 * 30
+)
+
+var errInvalidInvitation = errors.New("invalid or expired invitation")
+
+type invitationService struct {
+	invitationRepo interfaces.InvitationRepository
+	userRepo       interfaces.UserRepository
+	roleRepo       interfaces.RoleRepository
+	unitOfWork     interfaces.UnitOfWork
+	sessionService serviceInterfaces.SessionService
+	passwordPolicy serviceInterfaces.PasswordPolicyService
+	organizations  serviceInterfaces.OrganizationService
+	mailer         mailer.Mailer
+	cfg            *config.Config
+}
+
+func NewInvitationService(
+	invitationRepo interfaces.InvitationRepository,
+	userRepo interfaces.UserRepository,
+	roleRepo interfaces.RoleRepository,
+	unitOfWork interfaces.UnitOfWork,
+	sessionService serviceInterfaces.SessionService,
+	passwordPolicy serviceInterfaces.PasswordPolicyService,
+	organizations serviceInterfaces.OrganizationService,
+	mailer mailer.Mailer,
+	cfg *config.Config,
+) serviceInterfaces.InvitationService {
+	return &invitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		unitOfWork:     unitOfWork,
+		sessionService: sessionService,
+		passwordPolicy: passwordPolicy,
+		organizations:  organizations,
+		mailer:         mailer,
+		cfg:            cfg,
+	}
+}
+
+func (s *invitationService) CreateInvitation(req dto.CreateInvitationRequest, inviterID uint) (dto.InvitationResponse, error, int) {
+	email := strings.TrimSpace(req.Email)
+	if !util.IsValidEmail(email) {
+		return dto.InvitationResponse{}, errors.New("invalid email address"), fiber.StatusBadRequest
+	}
+
+	role, err := s.roleRepo.FindByName(req.RoleName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.InvitationResponse{}, errors.New("role not found"), fiber.StatusBadRequest
+		}
+		return dto.InvitationResponse{}, fmt.Errorf("failed to retrieve role: %w", err), fiber.StatusInternalServerError
+	}
+
+	expiry := defaultInvitationExpiry
+	if req.ExpiresInHours > 0 {
+		expiry = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if expiry > maxInvitationExpiry {
+		return dto.InvitationResponse{}, errors.New("invitations can be valid for at most 30 days"), fiber.StatusBadRequest
+	}
+
+	// Check if email already exists
+	_, err = s.userRepo.FindByEmail(email)
+	if err == nil {
+		return dto.InvitationResponse{}, errors.New("a user with this email already exists"), fiber.StatusConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.InvitationResponse{}, errors.New("failed to check email existence"), fiber.StatusInternalServerError
+	}
+
+	inviter, err := s.userRepo.FindByID(inviterID)
+	if err != nil {
+		return dto.InvitationResponse{}, fmt.Errorf("failed to retrieve inviter: %w", err), fiber.StatusInternalServerError
+	}
+
+	token, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return dto.InvitationResponse{}, errors.New("failed to generate invitation token"), fiber.StatusInternalServerError
+	}
+
+	// A new invitation replaces any pending one for the same address
+	if err := s.invitationRepo.RevokePendingByEmail(email); err != nil {
+		return dto.InvitationResponse{}, fmt.Errorf("failed to revoke previous invitations: %w", err), fiber.StatusInternalServerError
+	}
+
+	invitation := entity.Invitation{
+		Email:     email,
+		RoleID:    role.ID,
+		InviterID: inviter.ID,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := s.invitationRepo.Create(&invitation); err != nil {
+		return dto.InvitationResponse{}, fmt.Errorf("failed to create invitation: %w", err), fiber.StatusInternalServerError
+	}
+
+	body := fmt.Sprintf(
+		"Hello,\n\n%s invited you to join. Accept the invitation before %s:\n\n%s/accept-invitation?token=%s\n",
+		inviter.Name, invitation.ExpiresAt.Format(time.RFC1123), s.cfg.AppBaseURL, token,
+	)
+	if err := s.mailer.Send(email, "You have been invited", body); err != nil {
+		return dto.InvitationResponse{}, fmt.Errorf("failed to send invitation email: %w", err), fiber.StatusInternalServerError
+	}
+
+	invitation.Role = role
+	invitation.Inviter = inviter
+	return toInvitationResponse(invitation), nil, fiber.StatusCreated
+}
+
+func (s *invitationService) GetAllInvitations(status string) ([]dto.InvitationResponse, error, int) {
+	invitations, err := s.invitationRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve invitations: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.InvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		if status != "" && invitation.Status() != status {
+			continue
+		}
+		response = append(response, toInvitationResponse(invitation))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *invitationService) RevokeInvitation(id uint) (error, int) {
+	invitation, err := s.invitationRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invitation not found"), fiber.StatusNotFound
+		}
+		return fmt.Errorf("failed to retrieve invitation: %w", err), fiber.StatusInternalServerError
+	}
+
+	if invitation.Status() != entity.InvitationStatusPending {
+		return fmt.Errorf("invitation is already %s", invitation.Status()), fiber.StatusConflict
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := s.invitationRepo.Update(&invitation); err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err), fiber.StatusInternalServerError
+	}
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *invitationService) AcceptInvitation(req dto.AcceptInvitationRequest, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	invitation, err := s.invitationRepo.FindByTokenHash(util.HashToken(req.Token))
+	if err != nil || invitation.Status() != entity.InvitationStatusPending {
+		return dto.TokenResponse{}, errInvalidInvitation, fiber.StatusBadRequest
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return dto.TokenResponse{}, errors.New("name is required"), fiber.StatusBadRequest
+	}
+
+	// The address may have registered through another path since the invitation was sent
+	_, err = s.userRepo.FindByEmail(invitation.Email)
+	if err == nil {
+		return dto.TokenResponse{}, errors.New("email already exists"), fiber.StatusConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.TokenResponse{}, errors.New("failed to check email existence"), fiber.StatusInternalServerError
+	}
+
+	user := entity.User{
+		Name:   req.Name,
+		Email:  invitation.Email,
+		RoleID: invitation.RoleID,
+		Age:    0, // Set default age
+	}
+
+	// Enforce password policy
+	if err, status := s.passwordPolicy.ValidatePassword(req.Password, user); err != nil {
+		return dto.TokenResponse{}, err, status
+	}
+
+	user.Password, err = util.HashPassword(req.Password)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to hash password"), fiber.StatusInternalServerError
+	}
+
+	// The user, its registration event and the accepted invitation are saved together,
+	// so an invitation accepted twice at the same time only creates one user
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Users().Create(&user); err != nil {
+			return err
+		}
+
+		accepted, err := tx.Invitations().Accept(invitation.ID, user.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return errInvalidInvitation
+		}
+
+		data := dto.UserEventData{User: eventUser(user)}
+		data.User.Role = invitation.Role.Name
+		return stageUserEvent(tx.Outbox(), entity.EventUserRegistered, data)
+	})
+	if errors.Is(err, errInvalidInvitation) {
+		return dto.TokenResponse{}, err, fiber.StatusBadRequest
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return dto.TokenResponse{}, errors.New("email already exists"), fiber.StatusConflict
+	}
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to create user"), fiber.StatusInternalServerError
+	}
+
+	if err := s.passwordPolicy.RecordPassword(user.ID, user.Password); err != nil {
+		log.Printf("failed to record password history of user:%d: %v", user.ID, err)
+	}
+
+	if err := s.organizations.JoinDefaultOrganization(user.ID); err != nil {
+		log.Printf("failed to add user:%d to the default organization: %v", user.ID, err)
+	}
+
+	// Start a session and generate tokens
+	response, err, status := s.sessionService.StartSession(user, invitation.Role.Name, client)
+	if err != nil {
+		return dto.TokenResponse{}, err, status
+	}
+
+	return response, nil, fiber.StatusCreated
+}
+
+func toInvitationResponse(invitation entity.Invitation) dto.InvitationResponse {
+	return dto.InvitationResponse{
+		ID:           invitation.ID,
+		Email:        invitation.Email,
+		Role:         invitation.Role.Name,
+		InviterID:    invitation.InviterID,
+		InviterEmail: invitation.Inviter.Email,
+		Status:       invitation.Status(),
+		ExpiresAt:    invitation.ExpiresAt,
+		AcceptedAt:   invitation.AcceptedAt,
+		RevokedAt:    invitation.RevokedAt,
+		CreatedAt:    invitation.CreatedAt,
+	}
+}
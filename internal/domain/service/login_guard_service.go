@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/mailer"
+)
+
+type loginGuardService struct {
+	attemptRepo  interfaces.LoginAttemptRepository
+	userRepo     interfaces.UserRepository
+	mailer       mailer.Mailer
+	auditService serviceInterfaces.AuditService
+	cfg          *config.Config
+}
+
+func NewLoginGuardService(
+	attemptRepo interfaces.LoginAttemptRepository,
+	userRepo interfaces.UserRepository,
+	mailer mailer.Mailer,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.LoginGuardService {
+	return &loginGuardService{
+		attemptRepo:  attemptRepo,
+		userRepo:     userRepo,
+		mailer:       mailer,
+		auditService: auditService,
+		cfg:          cfg,
+	}
+}
+
+// CheckAllowed rejects a login attempt while the account or client address
+// is locked out or still inside its progressive delay
+func (s *loginGuardService) CheckAllowed(email, ip string) (error, int) {
+	now := time.Now()
+
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		attempt, err := s.attemptRepo.Find(key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return errors.New("failed to check login attempts"), fiber.StatusInternalServerError
+		}
+
+		if attempt.IsLocked(now) {
+			return fmt.Errorf("too many failed login attempts, try again in %s", roundUp(attempt.LockedUntil.Sub(now))),
+				fiber.StatusTooManyRequests
+		}
+
+		if retryAt := attempt.LastFailureAt.Add(s.delayFor(attempt.Failures)); now.Before(retryAt) {
+			return fmt.Errorf("too many failed login attempts, try again in %s", roundUp(retryAt.Sub(now))),
+				fiber.StatusTooManyRequests
+		}
+	}
+
+	return nil, fiber.StatusOK
+}
+
+func (s *loginGuardService) RecordFailure(email string, client dto.ClientInfo) {
+	now := time.Now()
+	ip := client.IPAddress
+
+	account, err := s.attemptRepo.RecordFailure(accountKey(email), now, s.cfg.LoginFailureWindow)
+	if err != nil {
+		log.Printf("failed to record login failure for %s: %v", email, err)
+	} else if account.Failures >= s.cfg.LoginMaxAccountFailures && !account.IsLocked(now) {
+		if err := s.attemptRepo.Lock(account.Key, now.Add(s.cfg.LoginLockoutDuration)); err != nil {
+			log.Printf("failed to lock account %s: %v", email, err)
+		} else {
+			s.auditService.Record(dto.AuditRecord{
+				Actor:   dto.Actor{Client: client},
+				Action:  entity.AuditActionAccountLocked,
+				Details: map[string]any{"email": email, "failures": account.Failures},
+			})
+			go s.notifyLockout(email, ip)
+		}
+	}
+
+	address, err := s.attemptRepo.RecordFailure(ipKey(ip), now, s.cfg.LoginFailureWindow)
+	if err != nil {
+		log.Printf("failed to record login failure for %s: %v", ip, err)
+	} else if address.Failures >= s.cfg.LoginMaxIPFailures && !address.IsLocked(now) {
+		if err := s.attemptRepo.Lock(address.Key, now.Add(s.cfg.LoginLockoutDuration)); err != nil {
+			log.Printf("failed to lock address %s: %v", ip, err)
+		} else {
+			s.auditService.Record(dto.AuditRecord{
+				Actor:   dto.Actor{Client: client},
+				Action:  entity.AuditActionAddressLocked,
+				Details: map[string]any{"ip_address": ip, "failures": address.Failures},
+			})
+		}
+	}
+}
+
+// RecordSuccess clears the account counter. The address counter is kept so a
+// single valid account cannot be used to reset guessing from that address.
+func (s *loginGuardService) RecordSuccess(email string) {
+	if err := s.attemptRepo.Reset(accountKey(email)); err != nil {
+		log.Printf("failed to reset login failures for %s: %v", email, err)
+	}
+}
+
+func (s *loginGuardService) UnlockUser(userID uint, ip string, actor dto.Actor) (error, int) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found"), fiber.StatusNotFound
+		}
+		return fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	if err := s.attemptRepo.Reset(accountKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err), fiber.StatusInternalServerError
+	}
+
+	if ip != "" {
+		if err := s.attemptRepo.Reset(ipKey(ip)); err != nil {
+			return fmt.Errorf("failed to unlock address: %w", err), fiber.StatusInternalServerError
+		}
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionAccountUnlocked,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"ip_address": ip},
+	})
+
+	return nil, fiber.StatusNoContent
+}
+
+// delayFor returns the wait enforced after the given number of failures,
+// doubling with every failure past LoginDelayAfterFailures up to LoginMaxDelay
+func (s *loginGuardService) delayFor(failures int) time.Duration {
+	excess := failures - s.cfg.LoginDelayAfterFailures
+	if excess < 0 {
+		return 0
+	}
+
+	delay := float64(s.cfg.LoginBaseDelay) * math.Pow(2, float64(excess))
+	if delay > float64(s.cfg.LoginMaxDelay) {
+		return s.cfg.LoginMaxDelay
+	}
+	return time.Duration(delay)
+}
+
+func (s *loginGuardService) notifyLockout(email, ip string) {
+	// Only existing accounts are notified; unknown emails are tracked silently
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nYour account was temporarily locked for %s after too many failed login attempts. "+
+			"The last attempt came from %s.\n\nIf this wasn't you, consider changing your password once the lock expires.",
+		user.Name, s.cfg.LoginLockoutDuration, ip,
+	)
+	if err := s.mailer.Send(user.Email, "Your account has been temporarily locked", body); err != nil {
+		log.Printf("failed to send lockout notification to %s: %v", user.Email, err)
+	}
+}
+
+func accountKey(email string) string {
+	return "account:" + util.NormalizeEmail(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func roundUp(d time.Duration) time.Duration {
+	return d.Truncate(time.Second) + time.Second
+}
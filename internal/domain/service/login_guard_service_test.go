@@ -0,0 +1,72 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"user_crud/internal/domain/repository"
+)
+
+func TestRecordFailureCountsConcurrentFailures(t *testing.T) {
+	attempts := repository.NewLoginAttemptRepository(newTestDB(t))
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := attempts.RecordFailure("account:bob@example.com", now, time.Minute); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	attempt, err := attempts.Find("account:bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 20 {
+		t.Errorf("failures = %d, want 20", attempt.Failures)
+	}
+}
+
+func TestRecordFailureRestartsElapsedWindow(t *testing.T) {
+	attempts := repository.NewLoginAttemptRepository(newTestDB(t))
+	key := "ip:192.0.2.1"
+	start := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		attempt, err := attempts.RecordFailure(key, start.Add(time.Duration(i)*time.Second), time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempt.Failures != i {
+			t.Fatalf("failure %d: failures = %d", i, attempt.Failures)
+		}
+	}
+
+	// A locked key keeps counting past the window
+	if err := attempts.Lock(key, start.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	attempt, err := attempts.RecordFailure(key, start.Add(5*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 4 || !attempt.IsLocked(start.Add(5*time.Minute)) {
+		t.Fatalf("while locked: failures = %d, locked = %v; want 4, true", attempt.Failures, attempt.IsLocked(start.Add(5*time.Minute)))
+	}
+
+	// Once the lock is over, a failure after the window starts a new one
+	later := start.Add(11 * time.Minute)
+	if attempt, err = attempts.RecordFailure(key, later, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 1 || attempt.LockedUntil != nil || !attempt.FirstFailureAt.Equal(later) {
+		t.Errorf("after the window: failures = %d, locked until %v, first failure %v; want 1, nil, %v",
+			attempt.Failures, attempt.LockedUntil, attempt.FirstFailureAt, later)
+	}
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/pkg/mailer"
+)
+
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 200
+)
+
+// notificationChannels tells where notifications of a type are delivered
+type notificationChannels struct {
+	inApp bool
+	email bool
+}
+
+// notificationDefaults are the channels of each type until the user sets a preference.
+// Logins are frequent, so they are only emailed on request.
+var notificationDefaults = map[string]notificationChannels{
+	entity.NotificationNewLogin:        {inApp: true, email: false},
+	entity.NotificationPasswordChanged: {inApp: true, email: true},
+	entity.NotificationRoleChanged:     {inApp: true, email: true},
+}
+
+type notificationService struct {
+	notificationRepo interfaces.NotificationRepository
+	preferenceRepo   interfaces.NotificationPreferenceRepository
+	userRepo         interfaces.UserRepository
+	realtime         serviceInterfaces.RealtimeService
+	mailer           mailer.Mailer
+	cfg              *config.Config
+}
+
+func NewNotificationService(
+	notificationRepo interfaces.NotificationRepository,
+	preferenceRepo interfaces.NotificationPreferenceRepository,
+	userRepo interfaces.UserRepository,
+	realtime serviceInterfaces.RealtimeService,
+	mailer mailer.Mailer,
+	cfg *config.Config,
+) serviceInterfaces.NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		preferenceRepo:   preferenceRepo,
+		userRepo:         userRepo,
+		realtime:         realtime,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+func (s *notificationService) Notify(userID uint, notification dto.Notification) {
+	channels, err := s.channels(userID, notification.Type)
+	if err != nil {
+		log.Printf("failed to load notification preferences of user:%d: %v", userID, err)
+		channels = notificationDefaults[notification.Type]
+	}
+
+	if channels.inApp {
+		if err := s.notifyInApp(userID, notification); err != nil {
+			log.Printf("failed to notify user:%d of %s: %v", userID, notification.Type, err)
+		}
+	}
+
+	if channels.email {
+		if err := s.notifyByEmail(userID, notification); err != nil {
+			log.Printf("failed to email user:%d about %s: %v", userID, notification.Type, err)
+		}
+	}
+}
+
+func (s *notificationService) notifyInApp(userID uint, notification dto.Notification) error {
+	record := entity.Notification{
+		UserID: userID,
+		Type:   notification.Type,
+		Title:  notification.Title,
+		Body:   notification.Body,
+	}
+	if len(notification.Data) > 0 {
+		data, err := json.Marshal(notification.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode data: %w", err)
+		}
+		record.Data = string(data)
+	}
+
+	if err := s.notificationRepo.Create(&record); err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+
+	// Connected clients show it right away, the others find it in the list
+	return s.realtime.Push(userID, dto.RealtimeTypeNotification, toNotificationResponse(record))
+}
+
+func (s *notificationService) notifyByEmail(userID uint, notification dto.Notification) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return s.mailer.Send(user.Email, notification.Title, notification.Body)
+}
+
+// channels returns where the user receives notifications of the type
+func (s *notificationService) channels(userID uint, notificationType string) (notificationChannels, error) {
+	channels, ok := notificationDefaults[notificationType]
+	if !ok {
+		channels = notificationChannels{inApp: true}
+	}
+
+	preferences, err := s.preferenceRepo.FindByUserID(userID)
+	if err != nil {
+		return channels, err
+	}
+	for _, preference := range preferences {
+		if preference.Type == notificationType {
+			return notificationChannels{inApp: preference.InApp, email: preference.Email}, nil
+		}
+	}
+	return channels, nil
+}
+
+func (s *notificationService) GetNotifications(actor dto.Actor, query dto.NotificationQuery) (dto.NotificationListResponse, error, int) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultNotificationPageSize
+	}
+	if limit > maxNotificationPageSize {
+		return dto.NotificationListResponse{}, fmt.Errorf("limit must be at most %d", maxNotificationPageSize), fiber.StatusBadRequest
+	}
+	if query.Offset < 0 {
+		return dto.NotificationListResponse{}, errors.New("offset must not be negative"), fiber.StatusBadRequest
+	}
+
+	notifications, err := s.notificationRepo.FindByUserID(actor.UserID, query.UnreadOnly, limit, query.Offset)
+	if err != nil {
+		return dto.NotificationListResponse{}, fmt.Errorf("failed to retrieve notifications: %w", err), fiber.StatusInternalServerError
+	}
+	total, err := s.notificationRepo.CountByUserID(actor.UserID, query.UnreadOnly)
+	if err != nil {
+		return dto.NotificationListResponse{}, fmt.Errorf("failed to count notifications: %w", err), fiber.StatusInternalServerError
+	}
+	unread := total
+	if !query.UnreadOnly {
+		if unread, err = s.notificationRepo.CountByUserID(actor.UserID, true); err != nil {
+			return dto.NotificationListResponse{}, fmt.Errorf("failed to count notifications: %w", err), fiber.StatusInternalServerError
+		}
+	}
+
+	response := dto.NotificationListResponse{
+		Notifications: make([]dto.NotificationResponse, 0, len(notifications)),
+		UnreadCount:   unread,
+		Total:         total,
+		Limit:         limit,
+		Offset:        query.Offset,
+	}
+	for _, notification := range notifications {
+		response.Notifications = append(response.Notifications, toNotificationResponse(notification))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *notificationService) MarkRead(actor dto.Actor, id uint) (error, int) {
+	found, err := s.notificationRepo.MarkRead(actor.UserID, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark notification as read: %w", err), fiber.StatusInternalServerError
+	}
+	if !found {
+		return errors.New("notification not found"), fiber.StatusNotFound
+	}
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *notificationService) MarkAllRead(actor dto.Actor) (error, int) {
+	if _, err := s.notificationRepo.MarkAllRead(actor.UserID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark notifications as read: %w", err), fiber.StatusInternalServerError
+	}
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *notificationService) GetPreferences(actor dto.Actor) ([]dto.NotificationPreferenceResponse, error, int) {
+	preferences, err := s.preferenceRepo.FindByUserID(actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve notification preferences: %w", err), fiber.StatusInternalServerError
+	}
+
+	return toNotificationPreferenceResponses(preferences), nil, fiber.StatusOK
+}
+
+func (s *notificationService) UpdatePreferences(actor dto.Actor, req dto.UpdateNotificationPreferencesRequest) ([]dto.NotificationPreferenceResponse, error, int) {
+	for _, preference := range req.Preferences {
+		if !slices.Contains(entity.NotificationTypes, preference.Type) {
+			return nil, fmt.Errorf("unknown notification type %q", preference.Type), fiber.StatusBadRequest
+		}
+	}
+
+	preferences, err := s.preferenceRepo.FindByUserID(actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve notification preferences: %w", err), fiber.StatusInternalServerError
+	}
+	current := toNotificationPreferenceResponses(preferences)
+
+	for _, change := range req.Preferences {
+		i := slices.IndexFunc(current, func(preference dto.NotificationPreferenceResponse) bool {
+			return preference.Type == change.Type
+		})
+		if change.InApp != nil {
+			current[i].InApp = *change.InApp
+		}
+		if change.Email != nil {
+			current[i].Email = *change.Email
+		}
+
+		preference := entity.NotificationPreference{
+			UserID: actor.UserID,
+			Type:   change.Type,
+			InApp:  current[i].InApp,
+			Email:  current[i].Email,
+		}
+		if err := s.preferenceRepo.Save(&preference); err != nil {
+			return nil, fmt.Errorf("failed to save notification preference: %w", err), fiber.StatusInternalServerError
+		}
+	}
+
+	return current, nil, fiber.StatusOK
+}
+
+func (s *notificationService) DeleteExpired() (int64, error) {
+	return s.notificationRepo.DeleteCreatedBefore(time.Now().Add(-s.cfg.NotificationRetention))
+}
+
+func (s *notificationService) RunRetentionSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.DeleteExpired(); err != nil {
+			log.Printf("notification retention sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// toNotificationPreferenceResponses lists the channels of every type, applying the defaults
+// to types without a preference
+func toNotificationPreferenceResponses(preferences []entity.NotificationPreference) []dto.NotificationPreferenceResponse {
+	responses := make([]dto.NotificationPreferenceResponse, 0, len(entity.NotificationTypes))
+	for _, notificationType := range entity.NotificationTypes {
+		defaults := notificationDefaults[notificationType]
+		response := dto.NotificationPreferenceResponse{
+			Type:  notificationType,
+			InApp: defaults.inApp,
+			Email: defaults.email,
+		}
+		for _, preference := range preferences {
+			if preference.Type == notificationType {
+				response.InApp = preference.InApp
+				response.Email = preference.Email
+			}
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func toNotificationResponse(notification entity.Notification) dto.NotificationResponse {
+	response := dto.NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Read:      notification.ReadAt != nil,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+	}
+	if notification.Data != "" {
+		response.Data = json.RawMessage(notification.Data)
+	}
+	return response
+}
+
+// newLoginNotification tells a user their account was signed in to, so they notice logins that were not theirs
+func newLoginNotification(client dto.ClientInfo, at time.Time) dto.Notification {
+	return dto.Notification{
+		Type:  entity.NotificationNewLogin,
+		Title: "New sign-in to your account",
+		Body: fmt.Sprintf(
+			"Your account was signed in to on %s from %s (%s).\n\nIf this was not you, change your password and sign out of your other sessions.",
+			at.UTC().Format(time.RFC1123), client.IPAddress, deviceName(client),
+		),
+		Data: map[string]any{
+			"ip_address": client.IPAddress,
+			"user_agent": client.UserAgent,
+		},
+	}
+}
+
+func passwordChangedNotification(client dto.ClientInfo, at time.Time) dto.Notification {
+	return dto.Notification{
+		Type:  entity.NotificationPasswordChanged,
+		Title: "Your password was changed",
+		Body: fmt.Sprintf(
+			"The password of your account was changed on %s from %s (%s) and your other sessions were signed out.\n\nIf this was not you, contact an administrator right away.",
+			at.UTC().Format(time.RFC1123), client.IPAddress, deviceName(client),
+		),
+		Data: map[string]any{
+			"ip_address": client.IPAddress,
+			"user_agent": client.UserAgent,
+		},
+	}
+}
+
+func roleChangedNotification(previousRole, role string) dto.Notification {
+	return dto.Notification{
+		Type:  entity.NotificationRoleChanged,
+		Title: "Your role was changed",
+		Body: fmt.Sprintf(
+			"An administrator changed your role from %s to %s. Sign in again to continue with the new role.",
+			previousRole, role,
+		),
+		Data: map[string]any{
+			"previous_role": previousRole,
+			"role":          role,
+		},
+	}
+}
+
+func deviceName(client dto.ClientInfo) string {
+	if client.UserAgent == "" {
+		return "unknown device"
+	}
+	return client.UserAgent
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/events"
+)
+
+// outboxDispatchBatch is the number of due events picked up per dispatch run
+const outboxDispatchBatch = 100
+
+type outboxService struct {
+	outboxRepo interfaces.OutboxRepository
+	sinks      []events.Sink
+	cfg        *config.Config
+}
+
+func NewOutboxService(
+	outboxRepo interfaces.OutboxRepository,
+	sinks []events.Sink,
+	cfg *config.Config,
+) serviceInterfaces.OutboxService {
+	return &outboxService{
+		outboxRepo: outboxRepo,
+		sinks:      sinks,
+		cfg:        cfg,
+	}
+}
+
+func (s *outboxService) DispatchDue() (int, error) {
+	due, err := s.outboxRepo.FindDue(time.Now(), outboxDispatchBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve due events: %w", err)
+	}
+
+	// Claimed events are not due again before every sink has had time to answer. Each
+	// lease starts when its event is claimed, as publishing the events before it may
+	// have taken a while.
+	lease := time.Duration(len(s.sinks)+1)*s.cfg.EventSinkTimeout + time.Minute
+
+	// Events are published one after the other; once an event of an aggregate fails,
+	// the later ones in the batch wait for it
+	blocked := make(map[string]bool)
+	attempted := 0
+	for i := range due {
+		event := due[i]
+		aggregate := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateID)
+		if blocked[aggregate] {
+			continue
+		}
+
+		now := time.Now()
+		claimed, err := s.outboxRepo.Claim(&event, now, now.Add(lease))
+		if err != nil {
+			return attempted, fmt.Errorf("failed to claim event %s: %w", event.EventID, err)
+		}
+		if !claimed {
+			blocked[aggregate] = true
+			continue
+		}
+
+		attempted++
+		if !s.publish(&event) {
+			blocked[aggregate] = true
+		}
+	}
+
+	return attempted, nil
+}
+
+func (s *outboxService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+
+	for {
+		if _, err := s.DispatchDue(); err != nil {
+			log.Printf("outbox dispatch failed: %v", err)
+		}
+
+		if time.Since(lastCleanup) >= time.Hour {
+			lastCleanup = time.Now()
+			if _, err := s.outboxRepo.DeletePublishedBefore(lastCleanup.Add(-s.cfg.OutboxRetention)); err != nil {
+				log.Printf("failed to remove published outbox events: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish hands the event to every sink that has not accepted it yet and records the
+// outcome. It reports whether every sink has accepted the event.
+func (s *outboxService) publish(event *entity.OutboxEvent) bool {
+	published := events.Event{
+		ID:            event.EventID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.OccurredAt,
+		Data:          json.RawMessage(event.Payload),
+	}
+
+	delivered := strings.Fields(event.Delivered)
+	var failures []string
+	for _, sink := range s.sinks {
+		if slices.Contains(delivered, sink.Name()) {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.EventSinkTimeout)
+		err := sink.Publish(ctx, published)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", sink.Name(), err))
+			continue
+		}
+		delivered = append(delivered, sink.Name())
+	}
+
+	now := time.Now()
+	event.Attempts++
+	event.Delivered = strings.Join(delivered, " ")
+	event.LastError = truncate(strings.Join(failures, "; "), 1024)
+
+	switch {
+	case len(failures) == 0:
+		event.Status = entity.OutboxEventPublished
+		event.PublishedAt = &now
+	case event.Attempts >= s.cfg.OutboxMaxAttempts:
+		event.Status = entity.OutboxEventFailed
+		log.Printf("outbox event %s (%s of %s:%d) given up after %d attempts: %s",
+			event.EventID, event.Type, event.AggregateType, event.AggregateID, event.Attempts, event.LastError)
+	default:
+		event.NextAttemptAt = now.Add(s.retryDelay(event.Attempts))
+	}
+
+	if err := s.outboxRepo.Update(event); err != nil {
+		log.Printf("failed to record attempt of outbox event %s: %v", event.EventID, err)
+		return false
+	}
+	return len(failures) == 0
+}
+
+// retryDelay doubles the wait with every failed attempt, starting at OutboxRetryBaseDelay
+func (s *outboxService) retryDelay(attempts int) time.Duration {
+	delay := s.cfg.OutboxRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.cfg.OutboxRetryMaxDelay {
+			return s.cfg.OutboxRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// stageUserEvent adds a domain event about the user to the outbox. Pass the outbox of the
+// transaction that saves the change, so the event is published if and only if the change is.
+func stageUserEvent(outbox interfaces.OutboxRepository, eventType string, data dto.UserEventData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	eventID, err := util.GenerateRandomToken(16)
+	if err != nil {
+		return fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	now := time.Now()
+	return outbox.Append(&entity.OutboxEvent{
+		EventID:       "evt_" + eventID,
+		Type:          eventType,
+		AggregateType: entity.AggregateUser,
+		AggregateID:   data.User.ID,
+		Payload:       string(payload),
+		OccurredAt:    now,
+		Status:        entity.OutboxEventPending,
+		NextAttemptAt: now,
+	})
+}
+
+// eventUser describes a user in domain events
+func eventUser(user entity.User) dto.EventUser {
+	return dto.EventUser{
+		ID:     user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Age:    user.Age,
+		Role:   user.Role.Name,
+		Status: user.Status,
+		Image:  user.ImageName,
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/util"
+)
+
+// Passwords at least this similar to the user's name or email are rejected
+const maxPasswordSimilarity = 0.7
+
+type passwordPolicyService struct {
+	historyRepo interfaces.PasswordHistoryRepository
+	breached    util.BreachedPasswordChecker
+	cfg         *config.Config
+}
+
+func NewPasswordPolicyService(
+	historyRepo interfaces.PasswordHistoryRepository,
+	breached util.BreachedPasswordChecker,
+	cfg *config.Config,
+) serviceInterfaces.PasswordPolicyService {
+	return &passwordPolicyService{
+		historyRepo: historyRepo,
+		breached:    breached,
+		cfg:         cfg,
+	}
+}
+
+func (s *passwordPolicyService) ValidatePassword(password string, user entity.User) (error, int) {
+	var violations []string
+
+	length := len([]rune(password))
+	if length < s.cfg.PasswordMinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", s.cfg.PasswordMinLength))
+	}
+	if s.cfg.PasswordMaxLength > 0 && length > s.cfg.PasswordMaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", s.cfg.PasswordMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if s.cfg.PasswordRequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if s.cfg.PasswordRequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if s.cfg.PasswordRequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if s.cfg.PasswordRequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if s.cfg.PasswordCheckSimilarity && resemblesUser(password, user) {
+		violations = append(violations, "must not resemble your name or email")
+	}
+
+	if len(violations) > 0 {
+		return errors.New("password " + strings.Join(violations, ", ")), fiber.StatusBadRequest
+	}
+
+	// Reuse of a recent password
+	if user.ID != 0 && s.cfg.PasswordHistorySize > 0 {
+		if user.Password != "" && util.CheckPassword(password, user.Password) {
+			return errors.New("password was used recently, choose a different one"), fiber.StatusBadRequest
+		}
+
+		history, err := s.historyRepo.FindRecentByUserID(user.ID, s.cfg.PasswordHistorySize)
+		if err != nil {
+			return errors.New("failed to check password history"), fiber.StatusInternalServerError
+		}
+		for _, entry := range history {
+			if util.CheckPassword(password, entry.PasswordHash) {
+				return errors.New("password was used recently, choose a different one"), fiber.StatusBadRequest
+			}
+		}
+	}
+
+	breached, err := s.breached.IsBreached(password)
+	if err != nil {
+		// The breach corpus is a defence in depth; do not block users when it is unreadable
+		log.Printf("failed to check breached passwords: %v", err)
+	} else if breached {
+		return errors.New("password appears in a known data breach, choose a different one"), fiber.StatusBadRequest
+	}
+
+	return nil, fiber.StatusOK
+}
+
+func (s *passwordPolicyService) RecordPassword(userID uint, passwordHash string) error {
+	if s.cfg.PasswordHistorySize <= 0 || passwordHash == "" {
+		return nil
+	}
+
+	if err := s.historyRepo.Create(&entity.PasswordHistory{UserID: userID, PasswordHash: passwordHash}); err != nil {
+		return err
+	}
+
+	return s.historyRepo.DeleteOlderByUserID(userID, s.cfg.PasswordHistorySize)
+}
+
+// resemblesUser reports whether the password contains, or is nearly equal to,
+// the user's name parts or the local part of their email
+func resemblesUser(password string, user entity.User) bool {
+	lowered := strings.ToLower(password)
+
+	candidates := strings.FieldsFunc(strings.ToLower(user.Name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if local, _, ok := strings.Cut(strings.ToLower(user.Email), "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		if len([]rune(candidate)) < 3 {
+			continue
+		}
+		if strings.Contains(lowered, candidate) || similarity(lowered, candidate) >= maxPasswordSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity returns 1 - levenshtein(a, b) / max(len(a), len(b))
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
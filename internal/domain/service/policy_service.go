@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/pkg/policy"
+)
+
+type policyService struct {
+	engine         *policy.Engine
+	userRepo       interfaces.UserRepository
+	membershipRepo interfaces.MembershipRepository
+	groupService   serviceInterfaces.GroupService
+	auditService   serviceInterfaces.AuditService
+	cfg            *config.Config
+}
+
+func NewPolicyService(
+	engine *policy.Engine,
+	userRepo interfaces.UserRepository,
+	membershipRepo interfaces.MembershipRepository,
+	groupService serviceInterfaces.GroupService,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.PolicyService {
+	return &policyService{
+		engine:         engine,
+		userRepo:       userRepo,
+		membershipRepo: membershipRepo,
+		groupService:   groupService,
+		auditService:   auditService,
+		cfg:            cfg,
+	}
+}
+
+// LoadPolicy reads the configured policy file, or the default policy when none is set
+func LoadPolicy(cfg *config.Config) (policy.Policy, error) {
+	if cfg.PolicyFile == "" {
+		return policy.Parse(config.DefaultPolicy)
+	}
+	return policy.LoadFile(cfg.PolicyFile)
+}
+
+func (s *policyService) Authorize(actor dto.Actor, action string, resource, environment policy.Attributes) policy.Decision {
+	return s.engine.Evaluate(policy.Request{
+		Subject:     actorSubject(actor),
+		Action:      action,
+		Resource:    resource,
+		Environment: environment,
+	})
+}
+
+func (s *policyService) UserResource(user entity.User) (policy.Attributes, error) {
+	memberships, err := s.membershipRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationIDs := make([]uint, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationID)
+	}
+
+	return policy.Attributes{
+		"type":             entity.ResourceTypeUser,
+		"id":               user.ID,
+		"role":             user.Role.Name,
+		"status":           user.Status,
+		"organization_ids": organizationIDs,
+	}, nil
+}
+
+func (s *policyService) Explain(req dto.PolicyExplainRequest, actor dto.Actor) (dto.PolicyExplainResponse, error, int) {
+	if req.Action == "" {
+		return dto.PolicyExplainResponse{}, errors.New("action is required"), fiber.StatusBadRequest
+	}
+
+	subject := actorSubject(actor)
+	if req.UserID != 0 {
+		var err error
+		var status int
+		subject, err, status = s.userSubject(req.UserID, req.OrganizationID)
+		if err != nil {
+			return dto.PolicyExplainResponse{}, err, status
+		}
+	}
+	for name, value := range req.Subject {
+		subject[name] = value
+	}
+
+	resource := policy.Attributes{}
+	if resourceType, _ := req.Resource["type"].(string); resourceType == entity.ResourceTypeUser {
+		if id, ok := req.Resource["id"].(float64); ok && id > 0 {
+			user, err := s.userRepo.FindByID(uint(id))
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return dto.PolicyExplainResponse{}, errors.New("resource user not found"), fiber.StatusNotFound
+				}
+				return dto.PolicyExplainResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+			}
+			if resource, err = s.UserResource(user); err != nil {
+				return dto.PolicyExplainResponse{}, fmt.Errorf("failed to retrieve memberships: %w", err), fiber.StatusInternalServerError
+			}
+		}
+	}
+	for name, value := range req.Resource {
+		resource[name] = value
+	}
+
+	request := s.engine.Prepare(policy.Request{
+		Subject:     subject,
+		Action:      req.Action,
+		Resource:    resource,
+		Environment: req.Environment,
+	})
+
+	return dto.PolicyExplainResponse{
+		Request:  request,
+		Decision: s.engine.Explain(request),
+	}, nil, fiber.StatusOK
+}
+
+func (s *policyService) GetPolicy() policy.Policy {
+	return s.engine.Policy()
+}
+
+func (s *policyService) Reload(actor dto.Actor) (policy.Policy, error, int) {
+	loaded, err := LoadPolicy(s.cfg)
+	if err != nil {
+		return policy.Policy{}, fmt.Errorf("failed to load policy: %w", err), fiber.StatusUnprocessableEntity
+	}
+	if err := s.engine.Replace(loaded); err != nil {
+		return policy.Policy{}, fmt.Errorf("failed to load policy: %w", err), fiber.StatusUnprocessableEntity
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionPolicyReloaded,
+		TargetType: entity.AuditTargetPolicy,
+		Details:    map[string]any{"rules": len(loaded.Rules)},
+	})
+	return loaded, nil, fiber.StatusOK
+}
+
+// userSubject describes a user acting in an organization, as if they had logged in there
+func (s *policyService) userSubject(userID, organizationID uint) (policy.Attributes, error, int) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	actor := dto.Actor{UserID: user.ID, Role: user.Role.Name}
+
+	memberships, err := s.membershipRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve memberships: %w", err), fiber.StatusInternalServerError
+	}
+	for _, membership := range memberships {
+		if organizationID == 0 || membership.OrganizationID == organizationID {
+			actor.OrganizationID = membership.OrganizationID
+			actor.OrganizationRole = membership.Role
+			break
+		}
+	}
+	if organizationID != 0 && actor.OrganizationID != organizationID {
+		return nil, errors.New("user is not a member of this organization"), fiber.StatusBadRequest
+	}
+
+	if actor.OrganizationID != 0 {
+		if actor.Permissions, err = s.groupService.GetPermissions(user.ID, actor.OrganizationID); err != nil {
+			return nil, fmt.Errorf("failed to retrieve permissions: %w", err), fiber.StatusInternalServerError
+		}
+	}
+
+	return actorSubject(actor), nil, fiber.StatusOK
+}
+
+// actorSubject describes the authenticated principal as a policy subject
+func actorSubject(actor dto.Actor) policy.Attributes {
+	principalType := entity.PrincipalTypeUser
+	if actor.Role == entity.ServiceAccountRole {
+		principalType = entity.PrincipalTypeServiceAccount
+	}
+
+	permissions := actor.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	scopes := actor.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return policy.Attributes{
+		"id":                actor.UserID,
+		"type":              principalType,
+		"role":              actor.Role,
+		"organization_id":   actor.OrganizationID,
+		"organization_role": actor.OrganizationRole,
+		"permissions":       permissions,
+		"scopes":            scopes,
+		"impersonated":      actor.ImpersonatorID != 0,
+	}
+}
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/pkg/events"
+)
+
+// Hub topics shared by the instances
+const (
+	realtimeTopicPresence = "presence"
+	realtimeTopicPush     = "push"
+)
+
+const (
+	// realtimeConnectionBuffer is how many messages a client may lag behind before it is dropped
+	realtimeConnectionBuffer = 64
+	// presenceExpiryIntervals is how many announcements an instance may miss before its users are considered offline
+	presenceExpiryIntervals = 3
+	// maxPresenceQuery is how many users a presence query may ask for
+	maxPresenceQuery = 100
+	// presenceQueueSize is how many announcements may wait to be published; changes beyond
+	// it reach the other instances with the next full announcement
+	presenceQueueSize = 256
+)
+
+// presenceAnnouncement tells the other instances how many connections users have on an instance
+type presenceAnnouncement struct {
+	Instance string `json:"instance"`
+	// Full replaces everything known about the instance, otherwise only the listed users change
+	Full bool `json:"full"`
+	// Hello is sent by a starting instance; the others answer with a full announcement
+	Hello bool           `json:"hello,omitempty"`
+	Users []presenceUser `json:"users"`
+}
+
+type presenceUser struct {
+	UserID          uint   `json:"user_id"`
+	Connections     int    `json:"connections"` // 0 once the last connection is closed
+	OrganizationIDs []uint `json:"organization_ids"`
+}
+
+// realtimePush is a message for the connections of one user, on whichever instance they are
+type realtimePush struct {
+	UserID  uint                `json:"user_id"`
+	Message dto.RealtimeMessage `json:"message"`
+}
+
+// instancePresence is what another instance last announced
+type instancePresence struct {
+	users   map[uint]presenceUser
+	heardAt time.Time
+}
+
+type realtimeConnection struct {
+	actor    dto.Actor
+	messages chan dto.RealtimeMessage
+	dropped  bool
+}
+
+type realtimeService struct {
+	hub            events.Hub
+	membershipRepo interfaces.MembershipRepository
+	cfg            *config.Config
+	// Identifies this instance in presence announcements
+	instance string
+	// Announcements in the order the changes were made, published by RunPresenceAnnouncer
+	announcements chan presenceAnnouncement
+
+	mu          sync.Mutex
+	connections map[uint]map[*realtimeConnection]struct{} // by user
+	local       map[uint]presenceUser                     // users connected to this instance
+	remote      map[string]*instancePresence              // by instance
+	lastSeen    map[uint]time.Time
+}
+
+func NewRealtimeService(
+	hub events.Hub,
+	membershipRepo interfaces.MembershipRepository,
+	cfg *config.Config,
+) serviceInterfaces.RealtimeService {
+	return &realtimeService{
+		hub:            hub,
+		membershipRepo: membershipRepo,
+		cfg:            cfg,
+		instance:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		announcements:  make(chan presenceAnnouncement, presenceQueueSize),
+		connections:    make(map[uint]map[*realtimeConnection]struct{}),
+		local:          make(map[uint]presenceUser),
+		remote:         make(map[string]*instancePresence),
+		lastSeen:       make(map[uint]time.Time),
+	}
+}
+
+func (s *realtimeService) Connect(actor dto.Actor) (dto.RealtimeConnection, error, int) {
+	if actor.UserID == 0 {
+		return dto.RealtimeConnection{}, errors.New("only users can connect"), fiber.StatusForbidden
+	}
+
+	organizationIDs, err := s.organizationIDs(actor.UserID)
+	if err != nil {
+		return dto.RealtimeConnection{}, err, fiber.StatusInternalServerError
+	}
+
+	connection := &realtimeConnection{
+		actor:    actor,
+		messages: make(chan dto.RealtimeMessage, realtimeConnectionBuffer),
+	}
+
+	s.mu.Lock()
+	// The client learns who is online first, then every change
+	if snapshot, err := realtimeMessage(dto.RealtimeTypePresenceSnapshot, s.visiblePresence(actor)); err == nil {
+		connection.messages <- snapshot
+	}
+	if s.connections[actor.UserID] == nil {
+		s.connections[actor.UserID] = make(map[*realtimeConnection]struct{})
+	}
+	s.connections[actor.UserID][connection] = struct{}{}
+	user := presenceUser{
+		UserID:          actor.UserID,
+		Connections:     s.local[actor.UserID].Connections + 1,
+		OrganizationIDs: organizationIDs,
+	}
+	s.changePresence([]uint{actor.UserID}, func() {
+		s.local[actor.UserID] = user
+	})
+	s.queueAnnouncement(presenceAnnouncement{Instance: s.instance, Users: []presenceUser{user}})
+	s.mu.Unlock()
+
+	return dto.RealtimeConnection{
+		Messages: connection.messages,
+		Close: func() {
+			s.disconnect(connection)
+		},
+	}, nil, fiber.StatusOK
+}
+
+func (s *realtimeService) disconnect(connection *realtimeConnection) {
+	userID := connection.actor.UserID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.connections[userID][connection]; !ok {
+		return
+	}
+	delete(s.connections[userID], connection)
+	if !connection.dropped {
+		close(connection.messages)
+	}
+	user := s.local[userID]
+	user.Connections--
+	s.changePresence([]uint{userID}, func() {
+		if user.Connections > 0 {
+			s.local[userID] = user
+		} else {
+			delete(s.connections, userID)
+			delete(s.local, userID)
+		}
+	})
+	s.queueAnnouncement(presenceAnnouncement{Instance: s.instance, Users: []presenceUser{user}})
+}
+
+func (s *realtimeService) GetPresence(actor dto.Actor, userIDs []uint) ([]dto.PresenceResponse, error, int) {
+	if len(userIDs) > maxPresenceQuery {
+		return nil, fmt.Errorf("at most %d users can be queried at once", maxPresenceQuery), fiber.StatusBadRequest
+	}
+
+	presence := make([]dto.PresenceResponse, 0, len(userIDs))
+	for _, userID := range userIDs {
+		// Users outside the caller's view are left out as if they did not exist
+		if !hasAdminRights(actor) && userID != actor.UserID {
+			organizationIDs, err := s.organizationIDs(userID)
+			if err != nil {
+				return nil, err, fiber.StatusInternalServerError
+			}
+			if !canSeeUser(actor, userID, organizationIDs) {
+				continue
+			}
+		}
+
+		s.mu.Lock()
+		presence = append(presence, s.presenceOf(userID))
+		s.mu.Unlock()
+	}
+	return presence, nil, fiber.StatusOK
+}
+
+func (s *realtimeService) Push(userID uint, messageType string, data any) error {
+	message, err := realtimeMessage(messageType, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(realtimePush{UserID: userID, Message: message})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.EventSinkTimeout)
+	defer cancel()
+	if err := s.hub.Publish(ctx, realtimeTopicPush, payload); err != nil {
+		return fmt.Errorf("failed to push message: %w", err)
+	}
+	return nil
+}
+
+func (s *realtimeService) RunPresenceAnnouncer(ctx context.Context, interval time.Duration) {
+	cancelPresence := s.hub.Subscribe(realtimeTopicPresence, s.handlePresence)
+	defer cancelPresence()
+	cancelPush := s.hub.Subscribe(realtimeTopicPush, s.handlePush)
+	defer cancelPush()
+
+	s.mu.Lock()
+	s.queueAnnouncement(presenceAnnouncement{Instance: s.instance, Full: true, Hello: true, Users: []presenceUser{}})
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case announcement := <-s.announcements:
+			s.announce(announcement)
+		case <-ticker.C:
+			s.announceAll()
+			s.expireInstances(time.Now().Add(-presenceExpiryIntervals * interval))
+		}
+	}
+}
+
+// announceAll tells the other instances about every user connected to this one
+func (s *realtimeService) announceAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	announcement := presenceAnnouncement{Instance: s.instance, Full: true, Users: make([]presenceUser, 0, len(s.local))}
+	for _, user := range s.local {
+		announcement.Users = append(announcement.Users, user)
+	}
+	s.queueAnnouncement(announcement)
+}
+
+// queueAnnouncement hands an announcement to RunPresenceAnnouncer; the caller holds the lock,
+// so announcements are queued in the order of the changes
+func (s *realtimeService) queueAnnouncement(announcement presenceAnnouncement) {
+	select {
+	case s.announcements <- announcement:
+	default:
+		log.Printf("presence announcement queue is full, dropping an announcement")
+	}
+}
+
+// announce publishes presence to the other instances
+func (s *realtimeService) announce(announcement presenceAnnouncement) {
+	payload, err := json.Marshal(announcement)
+	if err != nil {
+		log.Printf("failed to encode presence announcement: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.EventSinkTimeout)
+	defer cancel()
+	if err := s.hub.Publish(ctx, realtimeTopicPresence, payload); err != nil {
+		log.Printf("failed to announce presence: %v", err)
+	}
+}
+
+// expireInstances forgets the users of instances that have not announced them since the cutoff
+func (s *realtimeService) expireInstances(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for instance, presence := range s.remote {
+		if presence.heardAt.After(cutoff) {
+			continue
+		}
+		s.changePresence(userIDsOf(presence.users), func() {
+			delete(s.remote, instance)
+		})
+	}
+}
+
+func (s *realtimeService) handlePresence(payload []byte) {
+	var announcement presenceAnnouncement
+	if err := json.Unmarshal(payload, &announcement); err != nil {
+		log.Printf("invalid presence announcement: %v", err)
+		return
+	}
+	// This instance knows its own users best
+	if announcement.Instance == s.instance {
+		return
+	}
+	if announcement.Hello {
+		s.announceAll()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presence := s.remote[announcement.Instance]
+	if presence == nil {
+		presence = &instancePresence{users: make(map[uint]presenceUser)}
+		s.remote[announcement.Instance] = presence
+	}
+	presence.heardAt = time.Now()
+
+	affected := make([]uint, 0, len(announcement.Users))
+	for _, user := range announcement.Users {
+		affected = append(affected, user.UserID)
+	}
+	if announcement.Full {
+		affected = append(affected, userIDsOf(presence.users)...)
+	}
+	s.changePresence(affected, func() {
+		if announcement.Full {
+			clear(presence.users)
+		}
+		for _, user := range announcement.Users {
+			if user.Connections > 0 {
+				presence.users[user.UserID] = user
+			} else {
+				delete(presence.users, user.UserID)
+			}
+		}
+	})
+}
+
+func (s *realtimeService) handlePush(payload []byte) {
+	var push realtimePush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		log.Printf("invalid realtime push: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for connection := range s.connections[push.UserID] {
+		s.send(connection, push.Message)
+	}
+}
+
+// changePresence applies a change and tells the connected clients about users that came
+// online or went offline through it; the caller holds the lock
+func (s *realtimeService) changePresence(userIDs []uint, change func()) {
+	wasOnline := make(map[uint]bool, len(userIDs))
+	organizationIDs := make(map[uint][]uint, len(userIDs))
+	for _, userID := range userIDs {
+		wasOnline[userID] = s.online(userID)
+		organizationIDs[userID] = s.presenceOrganizations(userID)
+	}
+
+	change()
+
+	for userID, online := range wasOnline {
+		if s.online(userID) == online {
+			continue
+		}
+		if online {
+			s.lastSeen[userID] = time.Now()
+		} else {
+			organizationIDs[userID] = s.presenceOrganizations(userID)
+		}
+
+		message, err := realtimeMessage(dto.RealtimeTypePresence, s.presenceOf(userID))
+		if err != nil {
+			continue
+		}
+		for _, connections := range s.connections {
+			for connection := range connections {
+				if canSeeUser(connection.actor, userID, organizationIDs[userID]) {
+					s.send(connection, message)
+				}
+			}
+		}
+	}
+}
+
+// send hands a message to a connection, dropping the connection if it does not keep up;
+// the caller holds the lock
+func (s *realtimeService) send(connection *realtimeConnection, message dto.RealtimeMessage) {
+	if connection.dropped {
+		return
+	}
+	select {
+	case connection.messages <- message:
+	default:
+		// The client reconnects and receives a fresh presence snapshot
+		connection.dropped = true
+		close(connection.messages)
+	}
+}
+
+// online reports whether the user is connected to any instance; the caller holds the lock
+func (s *realtimeService) online(userID uint) bool {
+	if s.local[userID].Connections > 0 {
+		return true
+	}
+	for _, presence := range s.remote {
+		if presence.users[userID].Connections > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// presenceOrganizations returns the organizations of a connected user; the caller holds the lock
+func (s *realtimeService) presenceOrganizations(userID uint) []uint {
+	if user, ok := s.local[userID]; ok {
+		return user.OrganizationIDs
+	}
+	for _, presence := range s.remote {
+		if user, ok := presence.users[userID]; ok {
+			return user.OrganizationIDs
+		}
+	}
+	return nil
+}
+
+// presenceOf describes a user's presence; the caller holds the lock
+func (s *realtimeService) presenceOf(userID uint) dto.PresenceResponse {
+	response := dto.PresenceResponse{UserID: userID, Online: s.online(userID)}
+	if lastSeen, ok := s.lastSeen[userID]; ok && !response.Online {
+		response.LastSeenAt = &lastSeen
+	}
+	return response
+}
+
+// visiblePresence lists the online users the actor can see; the caller holds the lock
+func (s *realtimeService) visiblePresence(actor dto.Actor) []dto.PresenceResponse {
+	userIDs := userIDsOf(s.local)
+	for _, presence := range s.remote {
+		userIDs = append(userIDs, userIDsOf(presence.users)...)
+	}
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+
+	presence := make([]dto.PresenceResponse, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if s.online(userID) && canSeeUser(actor, userID, s.presenceOrganizations(userID)) {
+			presence = append(presence, s.presenceOf(userID))
+		}
+	}
+	return presence
+}
+
+func (s *realtimeService) organizationIDs(userID uint) ([]uint, error) {
+	memberships, err := s.membershipRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve memberships: %w", err)
+	}
+	organizationIDs := make([]uint, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationID)
+	}
+	return organizationIDs, nil
+}
+
+func realtimeMessage(messageType string, data any) (dto.RealtimeMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return dto.RealtimeMessage{}, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return dto.RealtimeMessage{Type: messageType, Data: encoded}, nil
+}
+
+func userIDsOf[T any](users map[uint]T) []uint {
+	userIDs := make([]uint, 0, len(users))
+	for userID := range users {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/mailer"
+)
+
+type registrationService struct {
+	userRepo          interfaces.UserRepository
+	unitOfWork        interfaces.UnitOfWork
+	allowedDomains    util.DomainList
+	disposableDomains util.DomainList
+	mailer            mailer.Mailer
+	auditService      serviceInterfaces.AuditService
+	cfg               *config.Config
+}
+
+func NewRegistrationService(
+	userRepo interfaces.UserRepository,
+	unitOfWork interfaces.UnitOfWork,
+	disposableDomains util.DomainList,
+	mailer mailer.Mailer,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.RegistrationService {
+	return &registrationService{
+		userRepo:          userRepo,
+		unitOfWork:        unitOfWork,
+		allowedDomains:    util.NewDomainList(cfg.RegistrationAllowedDomains),
+		disposableDomains: disposableDomains,
+		mailer:            mailer,
+		auditService:      auditService,
+		cfg:               cfg,
+	}
+}
+
+func (s *registrationService) CheckAllowed(email string) (error, int) {
+	// Public registration can be turned off in favour of invitations
+	if s.cfg.RegistrationMode == config.RegistrationModeInviteOnly {
+		return errors.New("registration is by invitation only"), fiber.StatusForbidden
+	}
+
+	domain := util.EmailDomain(email)
+	if domain == "" {
+		return errors.New("invalid email address"), fiber.StatusBadRequest
+	}
+
+	if s.cfg.RegistrationMode == config.RegistrationModeDomainAllowlist && !s.allowedDomains.Contains(domain) {
+		return errors.New("registration is not open to this email domain"), fiber.StatusForbidden
+	}
+
+	// Throwaway addresses are refused in every mode
+	if s.disposableDomains.Contains(domain) {
+		return errors.New("disposable email addresses are not accepted"), fiber.StatusBadRequest
+	}
+
+	return nil, fiber.StatusOK
+}
+
+func (s *registrationService) InitialStatus() string {
+	if s.cfg.RegistrationMode == config.RegistrationModeApproval {
+		return entity.UserStatusPendingApproval
+	}
+	return entity.UserStatusActive
+}
+
+func (s *registrationService) GetPendingRegistrations() ([]dto.RegistrationResponse, error, int) {
+	users, err := s.userRepo.FindByStatus(entity.UserStatusPendingApproval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve registrations: %w", err), fiber.StatusInternalServerError
+	}
+
+	responses := make([]dto.RegistrationResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, toRegistrationResponse(user))
+	}
+
+	return responses, nil, fiber.StatusOK
+}
+
+func (s *registrationService) ApproveRegistration(id uint, actor dto.Actor) (dto.RegistrationResponse, error, int) {
+	user, err, status := s.decide(id, entity.UserStatusActive)
+	if err != nil {
+		return dto.RegistrationResponse{}, err, status
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nYour account has been approved. You can now sign in at %s.\n",
+		user.Name, s.cfg.AppBaseURL,
+	)
+	s.notify(user, "Your account has been approved", body)
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionRegistrationApproved,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+	})
+	return toRegistrationResponse(user), nil, fiber.StatusOK
+}
+
+func (s *registrationService) RejectRegistration(id uint, req dto.RejectRegistrationRequest, actor dto.Actor) (dto.RegistrationResponse, error, int) {
+	user, err, status := s.decide(id, entity.UserStatusRejected)
+	if err != nil {
+		return dto.RegistrationResponse{}, err, status
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nYour registration has not been approved.\n", user.Name)
+	if req.Reason != "" {
+		body += fmt.Sprintf("\nReason: %s\n", req.Reason)
+	}
+	s.notify(user, "Your registration has been declined", body)
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionRegistrationRejected,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"reason": req.Reason},
+	})
+	return toRegistrationResponse(user), nil, fiber.StatusOK
+}
+
+// decide moves a pending registration to its final status
+func (s *registrationService) decide(id uint, status string) (entity.User, error, int) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.User{}, errors.New("registration not found"), fiber.StatusNotFound
+		}
+		return entity.User{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	if user.Status != entity.UserStatusPendingApproval {
+		return entity.User{}, errors.New("registration is not pending approval"), fiber.StatusConflict
+	}
+
+	user.Status = status
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Users().Update(&user); err != nil {
+			return err
+		}
+		return stageUserEvent(tx.Outbox(), entity.EventUserUpdated, dto.UserEventData{User: eventUser(user)})
+	})
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to update user: %w", err), fiber.StatusInternalServerError
+	}
+
+	return user, nil, fiber.StatusOK
+}
+
+// notify informs the applicant of the decision; a failed email does not undo it
+func (s *registrationService) notify(user entity.User, subject, body string) {
+	if err := s.mailer.Send(user.Email, subject, body); err != nil {
+		log.Printf("failed to send registration decision to user:%d: %v", user.ID, err)
+	}
+}
+
+func toRegistrationResponse(user entity.User) dto.RegistrationResponse {
+	return dto.RegistrationResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt,
+	}
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+)
+
+type roleGrantService struct {
+	roleGrantRepo interfaces.RoleGrantRepository
+	userRepo      interfaces.UserRepository
+	roleRepo      interfaces.RoleRepository
+	sessionRepo   interfaces.SessionRepository
+	auditService  serviceInterfaces.AuditService
+	cfg           *config.Config
+}
+
+func NewRoleGrantService(
+	roleGrantRepo interfaces.RoleGrantRepository,
+	userRepo interfaces.UserRepository,
+	roleRepo interfaces.RoleRepository,
+	sessionRepo interfaces.SessionRepository,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.RoleGrantService {
+	return &roleGrantService{
+		roleGrantRepo: roleGrantRepo,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		sessionRepo:   sessionRepo,
+		auditService:  auditService,
+		cfg:           cfg,
+	}
+}
+
+func (s *roleGrantService) RequestGrant(req dto.RoleGrantRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return dto.RoleGrantResponse{}, errors.New("reason is required"), fiber.StatusBadRequest
+	}
+	if len(reason) > 1024 {
+		return dto.RoleGrantResponse{}, errors.New("reason must be at most 1024 characters"), fiber.StatusBadRequest
+	}
+
+	// Granting a role to someone else skips the approval step and is reserved to admins
+	userID := actor.UserID
+	direct := req.UserID != 0 && req.UserID != actor.UserID
+	if direct {
+		isAdmin, err := s.isPermanentAdmin(actor)
+		if err != nil {
+			return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+		}
+		if !isAdmin {
+			return dto.RoleGrantResponse{}, errors.New("only admins can grant roles to other users"), fiber.StatusForbidden
+		}
+		userID = req.UserID
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.RoleGrantResponse{}, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	role, err := s.roleRepo.FindByName(req.RoleName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.RoleGrantResponse{}, errors.New("role not found"), fiber.StatusBadRequest
+		}
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve role: %w", err), fiber.StatusInternalServerError
+	}
+	if role.ID == user.RoleID {
+		return dto.RoleGrantResponse{}, errors.New("user already has this role"), fiber.StatusBadRequest
+	}
+
+	now := time.Now()
+	startsAt, endsAt, err := s.grantWindow(req, now)
+	if err != nil {
+		return dto.RoleGrantResponse{}, err, fiber.StatusBadRequest
+	}
+
+	if _, err := s.roleGrantRepo.FindOpenByUserAndRole(user.ID, role.ID, now); err == nil {
+		return dto.RoleGrantResponse{}, errors.New("user already has an open grant for this role"), fiber.StatusConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve role grants: %w", err), fiber.StatusInternalServerError
+	}
+
+	grant := entity.RoleGrant{
+		UserID:        user.ID,
+		RoleID:        role.ID,
+		Reason:        reason,
+		Status:        entity.RoleGrantStatusRequested,
+		RequestedByID: actor.UserID,
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+	}
+	if direct {
+		grant.Status = entity.RoleGrantStatusApproved
+		grant.DecidedByID = &actor.UserID
+		grant.DecidedAt = &now
+	}
+
+	if err := s.roleGrantRepo.Create(&grant); err != nil {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to create role grant: %w", err), fiber.StatusInternalServerError
+	}
+	grant.User = user
+	grant.Role = role
+
+	action := entity.AuditActionRoleGrantRequested
+	if direct {
+		action = entity.AuditActionRoleGrantGranted
+	}
+	s.recordGrant(actor, action, grant)
+
+	return toRoleGrantResponse(grant, now), nil, fiber.StatusCreated
+}
+
+func (s *roleGrantService) GetGrants(status string, userID uint) ([]dto.RoleGrantResponse, error, int) {
+	grants, err := s.roleGrantRepo.FindAll(status, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve role grants: %w", err), fiber.StatusInternalServerError
+	}
+
+	return toRoleGrantResponses(grants), nil, fiber.StatusOK
+}
+
+func (s *roleGrantService) GetUserGrants(userID uint) ([]dto.RoleGrantResponse, error, int) {
+	return s.GetGrants("", userID)
+}
+
+func (s *roleGrantService) ApproveGrant(id uint, req dto.RoleGrantDecisionRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int) {
+	grant, err, status := s.findPendingGrant(id, actor)
+	if err != nil {
+		return dto.RoleGrantResponse{}, err, status
+	}
+
+	// The requested length of time starts counting at approval if the window has already begun
+	now := time.Now()
+	if grant.StartsAt.Before(now) {
+		grant.EndsAt = now.Add(grant.EndsAt.Sub(grant.StartsAt))
+		grant.StartsAt = now
+	}
+
+	grant.Status = entity.RoleGrantStatusApproved
+	grant.DecidedByID = &actor.UserID
+	grant.DecidedAt = &now
+	grant.DecisionNote = strings.TrimSpace(req.Note)
+	if err := s.roleGrantRepo.Update(&grant); err != nil {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to update role grant: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.recordGrant(actor, entity.AuditActionRoleGrantApproved, grant)
+
+	return toRoleGrantResponse(grant, now), nil, fiber.StatusOK
+}
+
+func (s *roleGrantService) RejectGrant(id uint, req dto.RoleGrantDecisionRequest, actor dto.Actor) (dto.RoleGrantResponse, error, int) {
+	grant, err, status := s.findPendingGrant(id, actor)
+	if err != nil {
+		return dto.RoleGrantResponse{}, err, status
+	}
+
+	now := time.Now()
+	grant.Status = entity.RoleGrantStatusRejected
+	grant.DecidedByID = &actor.UserID
+	grant.DecidedAt = &now
+	grant.DecisionNote = strings.TrimSpace(req.Note)
+	if err := s.roleGrantRepo.Update(&grant); err != nil {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to update role grant: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.recordGrant(actor, entity.AuditActionRoleGrantRejected, grant)
+
+	return toRoleGrantResponse(grant, now), nil, fiber.StatusOK
+}
+
+func (s *roleGrantService) RevokeGrant(id uint, actor dto.Actor) (dto.RoleGrantResponse, error, int) {
+	grant, err := s.roleGrantRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.RoleGrantResponse{}, errors.New("role grant not found"), fiber.StatusNotFound
+		}
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve role grant: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Do not reveal grants of other users to anyone but permanent admins; an admin role
+	// from a grant does not reach the grants of others
+	if grant.UserID != actor.UserID {
+		isAdmin, err := s.isPermanentAdmin(actor)
+		if err != nil {
+			return dto.RoleGrantResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+		}
+		if !isAdmin {
+			return dto.RoleGrantResponse{}, errors.New("role grant not found"), fiber.StatusNotFound
+		}
+	}
+
+	if grant.Status != entity.RoleGrantStatusRequested && grant.Status != entity.RoleGrantStatusApproved {
+		return dto.RoleGrantResponse{}, fmt.Errorf("role grant is already %s", grant.Status), fiber.StatusConflict
+	}
+
+	now := time.Now()
+	grant.Status = entity.RoleGrantStatusRevoked
+	grant.RevokedByID = &actor.UserID
+	grant.RevokedAt = &now
+	if err := s.roleGrantRepo.Update(&grant); err != nil {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to update role grant: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Access tokens carrying the grant stop working; refreshing issues tokens with the user's own role
+	if err := s.sessionRepo.ClearRoleGrant(grant.ID); err != nil {
+		return dto.RoleGrantResponse{}, fmt.Errorf("failed to update sessions: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.recordGrant(actor, entity.AuditActionRoleGrantRevoked, grant)
+
+	return toRoleGrantResponse(grant, now), nil, fiber.StatusOK
+}
+
+func (s *roleGrantService) ExpireGrants() (int, error) {
+	now := time.Now()
+	grants, err := s.roleGrantRepo.FindEnded(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve ended role grants: %w", err)
+	}
+
+	// Tokens issued under an expired grant are downgraded by the middleware, so sessions are left alone.
+	// A grant revoked since it was read is left as it is.
+	expired := 0
+	for _, grant := range grants {
+		ok, err := s.roleGrantRepo.Expire(grant.ID, grant.Status, now)
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire role grant %d: %w", grant.ID, err)
+		}
+		if !ok {
+			continue
+		}
+
+		grant.Status = entity.RoleGrantStatusExpired
+		grant.ExpiredAt = &now
+		s.recordGrant(dto.Actor{}, entity.AuditActionRoleGrantExpired, grant)
+		expired++
+	}
+
+	return expired, nil
+}
+
+// recordGrant adds a change of the grant to the audit log; the details show the grant as it is now
+func (s *roleGrantService) recordGrant(actor dto.Actor, action string, grant entity.RoleGrant) {
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     action,
+		TargetType: entity.AuditTargetRoleGrant,
+		TargetID:   grant.ID,
+		Details: map[string]any{
+			"user_id":   grant.UserID,
+			"role":      grant.Role.Name,
+			"status":    grant.Status,
+			"starts_at": grant.StartsAt.UTC().Format(time.RFC3339),
+			"ends_at":   grant.EndsAt.UTC().Format(time.RFC3339),
+			"reason":    grant.Reason,
+			"note":      grant.DecisionNote,
+		},
+	})
+}
+
+func (s *roleGrantService) RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ExpireGrants(); err != nil {
+			log.Printf("role grant expiry sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// findPendingGrant loads a grant awaiting a decision. Only admins holding the role permanently
+// decide on requests, and never on their own.
+func (s *roleGrantService) findPendingGrant(id uint, actor dto.Actor) (entity.RoleGrant, error, int) {
+	isAdmin, err := s.isPermanentAdmin(actor)
+	if err != nil {
+		return entity.RoleGrant{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+	if !isAdmin {
+		return entity.RoleGrant{}, errors.New("only permanent admins can decide on role grants"), fiber.StatusForbidden
+	}
+
+	grant, err := s.roleGrantRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.RoleGrant{}, errors.New("role grant not found"), fiber.StatusNotFound
+		}
+		return entity.RoleGrant{}, fmt.Errorf("failed to retrieve role grant: %w", err), fiber.StatusInternalServerError
+	}
+
+	if grant.UserID == actor.UserID {
+		return entity.RoleGrant{}, errors.New("cannot decide on your own role grant"), fiber.StatusForbidden
+	}
+	if grant.Status != entity.RoleGrantStatusRequested {
+		return entity.RoleGrant{}, fmt.Errorf("role grant is already %s", grant.Status), fiber.StatusConflict
+	}
+	if !time.Now().Before(grant.EndsAt) {
+		return entity.RoleGrant{}, errors.New("role grant request has expired"), fiber.StatusConflict
+	}
+
+	return grant, nil, fiber.StatusOK
+}
+
+// isPermanentAdmin reports whether the actor is a user whose own role, not one from a grant,
+// is admin. Service accounts and other principals without a user never are.
+func (s *roleGrantService) isPermanentAdmin(actor dto.Actor) (bool, error) {
+	if actor.ServiceAccountID != 0 || actor.UserID == 0 {
+		return false, nil
+	}
+
+	user, err := s.userRepo.FindByID(actor.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.Role.Name == "admin", nil
+}
+
+// grantWindow validates the requested time window against the configured maximum
+func (s *roleGrantService) grantWindow(req dto.RoleGrantRequest, now time.Time) (time.Time, time.Time, error) {
+	startsAt := now
+	if req.StartsAt != nil && req.StartsAt.After(now) {
+		startsAt = *req.StartsAt
+	}
+
+	var endsAt time.Time
+	switch {
+	case req.EndsAt != nil && req.DurationMinutes != 0:
+		return time.Time{}, time.Time{}, errors.New("specify either ends_at or duration_minutes")
+	case req.EndsAt != nil:
+		endsAt = *req.EndsAt
+	case req.DurationMinutes > 0:
+		endsAt = startsAt.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		return time.Time{}, time.Time{}, errors.New("ends_at or duration_minutes is required")
+	}
+
+	if !endsAt.After(startsAt) {
+		return time.Time{}, time.Time{}, errors.New("role grant must end after it starts")
+	}
+	if endsAt.Sub(startsAt) > s.cfg.RoleGrantMaxDuration {
+		return time.Time{}, time.Time{}, fmt.Errorf("role grants can last at most %s", s.cfg.RoleGrantMaxDuration)
+	}
+
+	return startsAt, endsAt, nil
+}
+
+func toRoleGrantResponses(grants []entity.RoleGrant) []dto.RoleGrantResponse {
+	now := time.Now()
+	response := make([]dto.RoleGrantResponse, 0, len(grants))
+	for _, grant := range grants {
+		response = append(response, toRoleGrantResponse(grant, now))
+	}
+	return response
+}
+
+func toRoleGrantResponse(grant entity.RoleGrant, now time.Time) dto.RoleGrantResponse {
+	return dto.RoleGrantResponse{
+		ID:            grant.ID,
+		UserID:        grant.UserID,
+		UserEmail:     grant.User.Email,
+		Role:          grant.Role.Name,
+		Reason:        grant.Reason,
+		Status:        grant.Status,
+		Active:        grant.IsActive(now),
+		RequestedByID: grant.RequestedByID,
+		DecidedByID:   grant.DecidedByID,
+		DecisionNote:  grant.DecisionNote,
+		StartsAt:      grant.StartsAt,
+		EndsAt:        grant.EndsAt,
+		DecidedAt:     grant.DecidedAt,
+		RevokedByID:   grant.RevokedByID,
+		RevokedAt:     grant.RevokedAt,
+		ExpiredAt:     grant.ExpiredAt,
+		CreatedAt:     grant.CreatedAt,
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository"
+	"user_crud/internal/dto"
+)
+
+func TestRevokeGrantOfOthersTakesPermanentAdmin(t *testing.T) {
+	db := newTestDB(t)
+	service := NewRoleGrantService(
+		repository.NewRoleGrantRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewRoleRepository(db),
+		repository.NewSessionRepository(db),
+		NewAuditService(repository.NewAuditEventRepository(db)),
+		&config.Config{},
+	)
+
+	admin := createTestUser(t, db, "admin@example.com", "admin")
+	elevated := createTestUser(t, db, "elevated@example.com", "user")
+	other := createTestUser(t, db, "other@example.com", "user")
+
+	adminRole, err := repository.NewRoleRepository(db).FindByName("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	grantTo := func(user entity.User) entity.RoleGrant {
+		grant := entity.RoleGrant{
+			UserID:        user.ID,
+			RoleID:        adminRole.ID,
+			Reason:        "incident",
+			Status:        entity.RoleGrantStatusApproved,
+			RequestedByID: user.ID,
+			DecidedByID:   &admin.ID,
+			StartsAt:      now.Add(-time.Minute),
+			EndsAt:        now.Add(time.Hour),
+		}
+		if err := repository.NewRoleGrantRepository(db).Create(&grant); err != nil {
+			t.Fatal(err)
+		}
+		return grant
+	}
+	grantTo(elevated)
+	otherGrant := grantTo(other)
+
+	// The elevated user acts with the admin role of their grant
+	actor := dto.Actor{UserID: elevated.ID, Role: "admin"}
+	if _, err, status := service.RevokeGrant(otherGrant.ID, actor); status != fiber.StatusNotFound {
+		t.Fatalf("temporary admin revoking another grant: status = %d (%v), want %d", status, err, fiber.StatusNotFound)
+	}
+
+	actor = dto.Actor{UserID: admin.ID, Role: "admin"}
+	response, err, status := service.RevokeGrant(otherGrant.ID, actor)
+	if status != fiber.StatusOK {
+		t.Fatalf("permanent admin revoking another grant: status = %d (%v), want %d", status, err, fiber.StatusOK)
+	}
+	if response.Status != entity.RoleGrantStatusRevoked {
+		t.Errorf("status = %q, want %q", response.Status, entity.RoleGrantStatusRevoked)
+	}
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+)
+
+// OAuth 2.0 grant types accepted by the token endpoint
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeJWTBearer         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+type serviceAccountService struct {
+	accountRepo  interfaces.ServiceAccountRepository
+	userRepo     interfaces.UserRepository
+	auditService serviceInterfaces.AuditService
+}
+
+func NewServiceAccountService(
+	accountRepo interfaces.ServiceAccountRepository,
+	userRepo interfaces.UserRepository,
+	auditService serviceInterfaces.AuditService,
+) serviceInterfaces.ServiceAccountService {
+	return &serviceAccountService{
+		accountRepo:  accountRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *serviceAccountService) CreateServiceAccount(req dto.CreateServiceAccountRequest, actor dto.Actor) (dto.ServiceAccountCredentialsResponse, error, int) {
+	if strings.TrimSpace(req.Name) == "" {
+		return dto.ServiceAccountCredentialsResponse{}, errors.New("name is required"), fiber.StatusBadRequest
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, err, fiber.StatusBadRequest
+	}
+
+	if req.PublicKey != "" {
+		if err := util.ValidatePublicKeyPEM(req.PublicKey); err != nil {
+			return dto.ServiceAccountCredentialsResponse{}, errors.New("invalid public key"), fiber.StatusBadRequest
+		}
+	}
+
+	// Owner defaults to the calling admin and must be an admin
+	ownerID := req.OwnerID
+	if ownerID == 0 {
+		ownerID = actor.UserID
+	}
+	if err, status := s.checkOwner(ownerID); err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, err, status
+	}
+
+	clientID, err := util.GenerateRandomToken(12)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, errors.New("failed to generate client ID"), fiber.StatusInternalServerError
+	}
+
+	clientSecret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, errors.New("failed to generate client secret"), fiber.StatusInternalServerError
+	}
+
+	account := entity.ServiceAccount{
+		Name:             req.Name,
+		Description:      req.Description,
+		ClientID:         "sa_" + clientID,
+		ClientSecretHash: util.HashToken(clientSecret),
+		PublicKey:        req.PublicKey,
+		Scopes:           strings.Join(scopes, " "),
+		OwnerID:          ownerID,
+	}
+
+	if err := s.accountRepo.Create(&account); err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, fmt.Errorf("failed to create service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Reload to populate the owner
+	account, err = s.accountRepo.FindByID(account.ID)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, fmt.Errorf("failed to retrieve service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionServiceAccountCreated,
+		TargetType: entity.AuditTargetServiceAccount,
+		TargetID:   account.ID,
+		After:      serviceAccountSnapshot(account),
+	})
+
+	return dto.ServiceAccountCredentialsResponse{
+		ServiceAccountResponse: toServiceAccountResponse(account),
+		ClientSecret:           clientSecret,
+	}, nil, fiber.StatusCreated
+}
+
+func (s *serviceAccountService) GetAllServiceAccounts() ([]dto.ServiceAccountResponse, error, int) {
+	accounts, err := s.accountRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve service accounts: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.ServiceAccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		response = append(response, toServiceAccountResponse(account))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *serviceAccountService) GetServiceAccount(id uint) (dto.ServiceAccountResponse, error, int) {
+	account, err, status := s.findAccount(id)
+	if err != nil {
+		return dto.ServiceAccountResponse{}, err, status
+	}
+
+	return toServiceAccountResponse(account), nil, fiber.StatusOK
+}
+
+func (s *serviceAccountService) UpdateServiceAccount(id uint, req dto.UpdateServiceAccountRequest, actor dto.Actor) (dto.ServiceAccountResponse, error, int) {
+	account, err, status := s.findAccount(id)
+	if err != nil {
+		return dto.ServiceAccountResponse{}, err, status
+	}
+	before := serviceAccountSnapshot(account)
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return dto.ServiceAccountResponse{}, errors.New("name cannot be empty"), fiber.StatusBadRequest
+		}
+		account.Name = *req.Name
+	}
+
+	if req.Description != nil {
+		account.Description = *req.Description
+	}
+
+	if req.Scopes != nil {
+		scopes, err := normalizeScopes(*req.Scopes)
+		if err != nil {
+			return dto.ServiceAccountResponse{}, err, fiber.StatusBadRequest
+		}
+		account.Scopes = strings.Join(scopes, " ")
+	}
+
+	if req.PublicKey != nil {
+		if *req.PublicKey != "" {
+			if err := util.ValidatePublicKeyPEM(*req.PublicKey); err != nil {
+				return dto.ServiceAccountResponse{}, errors.New("invalid public key"), fiber.StatusBadRequest
+			}
+		}
+		account.PublicKey = *req.PublicKey
+	}
+
+	if req.OwnerID != nil {
+		if err, status := s.checkOwner(*req.OwnerID); err != nil {
+			return dto.ServiceAccountResponse{}, err, status
+		}
+		account.OwnerID = *req.OwnerID
+		account.Owner = entity.User{}
+	}
+
+	if req.Disabled != nil {
+		account.Disabled = *req.Disabled
+	}
+
+	if err := s.accountRepo.Update(&account); err != nil {
+		return dto.ServiceAccountResponse{}, fmt.Errorf("failed to update service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	account, err = s.accountRepo.FindByID(account.ID)
+	if err != nil {
+		return dto.ServiceAccountResponse{}, fmt.Errorf("failed to retrieve service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionServiceAccountUpdated,
+		TargetType: entity.AuditTargetServiceAccount,
+		TargetID:   account.ID,
+		Before:     before,
+		After:      serviceAccountSnapshot(account),
+	})
+
+	return toServiceAccountResponse(account), nil, fiber.StatusOK
+}
+
+func (s *serviceAccountService) RotateSecret(id uint, actor dto.Actor) (dto.ServiceAccountCredentialsResponse, error, int) {
+	account, err, status := s.findAccount(id)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, err, status
+	}
+
+	clientSecret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, errors.New("failed to generate client secret"), fiber.StatusInternalServerError
+	}
+
+	account.ClientSecretHash = util.HashToken(clientSecret)
+	if err := s.accountRepo.Update(&account); err != nil {
+		return dto.ServiceAccountCredentialsResponse{}, fmt.Errorf("failed to update service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionServiceAccountRotated,
+		TargetType: entity.AuditTargetServiceAccount,
+		TargetID:   account.ID,
+	})
+
+	return dto.ServiceAccountCredentialsResponse{
+		ServiceAccountResponse: toServiceAccountResponse(account),
+		ClientSecret:           clientSecret,
+	}, nil, fiber.StatusOK
+}
+
+func (s *serviceAccountService) DeleteServiceAccount(id uint, actor dto.Actor) (error, int) {
+	account, err, status := s.findAccount(id)
+	if err != nil {
+		return err, status
+	}
+
+	if err := s.accountRepo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete service account: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionServiceAccountDeleted,
+		TargetType: entity.AuditTargetServiceAccount,
+		TargetID:   account.ID,
+		Before:     serviceAccountSnapshot(account),
+	})
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *serviceAccountService) IssueToken(req dto.ServiceTokenRequest, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	var account entity.ServiceAccount
+	var err error
+
+	switch req.GrantType {
+	case GrantTypeClientCredentials:
+		account, err = s.accountRepo.FindByClientID(req.ClientID)
+		if err != nil || req.ClientSecret == "" || account.ClientSecretHash == "" ||
+			!util.CompareTokenHash(req.ClientSecret, account.ClientSecretHash) {
+			return dto.TokenResponse{}, errors.New("invalid client credentials"), fiber.StatusUnauthorized
+		}
+
+	case GrantTypeJWTBearer:
+		clientID, err := util.ParseClientAssertionSubject(req.Assertion)
+		if err != nil {
+			return dto.TokenResponse{}, errors.New("invalid assertion"), fiber.StatusUnauthorized
+		}
+		account, err = s.accountRepo.FindByClientID(clientID)
+		if err != nil || account.PublicKey == "" {
+			return dto.TokenResponse{}, errors.New("invalid assertion"), fiber.StatusUnauthorized
+		}
+		claims, err := util.VerifyClientAssertion(req.Assertion, account.ClientID, account.PublicKey)
+		if err != nil {
+			return dto.TokenResponse{}, errors.New("invalid assertion"), fiber.StatusUnauthorized
+		}
+
+		// Each assertion may be exchanged once; its jti is kept until it expires
+		fresh, err := s.accountRepo.UseAssertion(entity.UsedAssertion{
+			ClientID:  account.ClientID,
+			TokenID:   claims.ID,
+			ExpiresAt: claims.ExpiresAt.Time,
+		}, time.Now())
+		if err != nil {
+			return dto.TokenResponse{}, fmt.Errorf("failed to record assertion: %w", err), fiber.StatusInternalServerError
+		}
+		if !fresh {
+			return dto.TokenResponse{}, errors.New("assertion already used"), fiber.StatusUnauthorized
+		}
+
+	default:
+		return dto.TokenResponse{}, errors.New("unsupported grant type"), fiber.StatusBadRequest
+	}
+
+	if account.Disabled {
+		return dto.TokenResponse{}, errors.New("service account is disabled"), fiber.StatusUnauthorized
+	}
+
+	// Grant the requested scopes, or every scope of the account when none are requested
+	granted := strings.Fields(account.Scopes)
+	if req.Scope != "" {
+		requested := strings.Fields(req.Scope)
+		for _, scope := range requested {
+			if !slices.Contains(granted, scope) {
+				return dto.TokenResponse{}, fmt.Errorf("scope %q not granted to this client", scope), fiber.StatusBadRequest
+			}
+		}
+		granted = requested
+	}
+
+	accessToken, err := util.GenerateServiceAccessToken(account.ID, account.ClientID, granted)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+	}
+
+	now := time.Now()
+	account.LastUsedAt = &now
+	if err := s.accountRepo.Update(&account); err != nil {
+		log.Printf("failed to record last use of service_account:%d: %v", account.ID, err)
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      dto.Actor{ServiceAccountID: account.ID, Client: client},
+		Action:     entity.AuditActionServiceAccountTokenIssued,
+		TargetType: entity.AuditTargetServiceAccount,
+		TargetID:   account.ID,
+		Details:    map[string]any{"grant_type": req.GrantType, "scopes": granted},
+	})
+
+	return dto.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(util.AccessTokenExpiry / time.Second),
+		Scope:       strings.Join(granted, " "),
+	}, nil, fiber.StatusOK
+}
+
+// ValidateServiceAccount checks that the account behind a service access token still
+// exists and is enabled, and returns the token's scopes the account still holds
+func (s *serviceAccountService) ValidateServiceAccount(id uint, scopes []string) ([]string, error) {
+	account, err := s.accountRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("service account not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve service account: %w", err)
+	}
+	if account.Disabled {
+		return nil, errors.New("service account is disabled")
+	}
+
+	granted := strings.Fields(account.Scopes)
+	current := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if slices.Contains(granted, scope) {
+			current = append(current, scope)
+		}
+	}
+	return current, nil
+}
+
+func (s *serviceAccountService) findAccount(id uint) (entity.ServiceAccount, error, int) {
+	account, err := s.accountRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ServiceAccount{}, errors.New("service account not found"), fiber.StatusNotFound
+		}
+		return entity.ServiceAccount{}, fmt.Errorf("failed to retrieve service account: %w", err), fiber.StatusInternalServerError
+	}
+	return account, nil, fiber.StatusOK
+}
+
+// checkOwner makes sure a service account is always owned by a human admin
+func (s *serviceAccountService) checkOwner(ownerID uint) (error, int) {
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("owner not found"), fiber.StatusBadRequest
+		}
+		return fmt.Errorf("failed to retrieve owner: %w", err), fiber.StatusInternalServerError
+	}
+	if owner.Role.Name != "admin" {
+		return errors.New("owner must be an admin"), fiber.StatusBadRequest
+	}
+	return nil, fiber.StatusOK
+}
+
+// normalizeScopes validates scopes against entity.AvailableScopes and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	var result []string
+	for _, scope := range scopes {
+		if !slices.Contains(entity.AvailableScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func toServiceAccountResponse(account entity.ServiceAccount) dto.ServiceAccountResponse {
+	return dto.ServiceAccountResponse{
+		ID:           account.ID,
+		Name:         account.Name,
+		Description:  account.Description,
+		ClientID:     account.ClientID,
+		Scopes:       strings.Fields(account.Scopes),
+		HasPublicKey: account.PublicKey != "",
+		OwnerID:      account.OwnerID,
+		OwnerEmail:   account.Owner.Email,
+		Disabled:     account.Disabled,
+		LastUsedAt:   account.LastUsedAt,
+		CreatedAt:    account.CreatedAt,
+	}
+}
+
+// serviceAccountSnapshot is the part of a service account recorded in audit log diffs;
+// credentials are left out
+func serviceAccountSnapshot(account entity.ServiceAccount) map[string]any {
+	return map[string]any{
+		"name":        account.Name,
+		"description": account.Description,
+		"client_id":   account.ClientID,
+		"scopes":      account.Scopes,
+		"owner_id":    account.OwnerID,
+		"has_key":     account.PublicKey != "",
+		"disabled":    account.Disabled,
+	}
+}
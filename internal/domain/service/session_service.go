@@ -0,0 +1,420 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+)
+
+// lastSeenResolution limits how often ValidateSession writes the last-seen timestamp
+const lastSeenResolution = time.Minute
+
+// errSessionChanged means the session was revoked, expired or had its refresh token rotated
+// by someone else between reading it and issuing new tokens
+var errSessionChanged = errors.New("session revoked or expired")
+
+type sessionService struct {
+	sessionRepo    interfaces.SessionRepository
+	userRepo       interfaces.UserRepository
+	membershipRepo interfaces.MembershipRepository
+	roleGrantRepo  interfaces.RoleGrantRepository
+	groupService   serviceInterfaces.GroupService
+	auditService   serviceInterfaces.AuditService
+	cfg            *config.Config
+}
+
+func NewSessionService(
+	sessionRepo interfaces.SessionRepository,
+	userRepo interfaces.UserRepository,
+	membershipRepo interfaces.MembershipRepository,
+	roleGrantRepo interfaces.RoleGrantRepository,
+	groupService serviceInterfaces.GroupService,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.SessionService {
+	return &sessionService{
+		sessionRepo:    sessionRepo,
+		userRepo:       userRepo,
+		membershipRepo: membershipRepo,
+		roleGrantRepo:  roleGrantRepo,
+		groupService:   groupService,
+		auditService:   auditService,
+		cfg:            cfg,
+	}
+}
+
+func (s *sessionService) StartSession(user entity.User, roleName string, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	now := time.Now()
+	session := entity.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(client.UserAgent, 512),
+		IPAddress:  client.IPAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(util.RefreshTokenExpiry),
+	}
+
+	// The refresh token embeds the session ID, so the row is created first
+	// and the token hash filled in afterwards
+	if err := s.sessionRepo.Create(&session); err != nil {
+		return dto.TokenResponse{}, errors.New("failed to create session"), fiber.StatusInternalServerError
+	}
+
+	return s.issueTokens(&session, user, roleName, "")
+}
+
+func (s *sessionService) RefreshSession(refreshToken string, client dto.ClientInfo) (dto.TokenResponse, error, int) {
+	// Verify refresh token
+	userID, sessionID, err := util.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("invalid refresh token"), fiber.StatusUnauthorized
+	}
+
+	// Impersonation sessions never had a refresh token
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() || session.ImpersonatorID != nil {
+		return dto.TokenResponse{}, errors.New("invalid refresh token"), fiber.StatusUnauthorized
+	}
+
+	// Refresh tokens are single use. Presenting an already rotated token means
+	// it has leaked, so the whole session is revoked.
+	if !util.CompareTokenHash(refreshToken, session.RefreshTokenHash) {
+		s.revokeReusedSession(session.ID, userID, client)
+		return dto.TokenResponse{}, errors.New("invalid refresh token"), fiber.StatusUnauthorized
+	}
+
+	// Get user
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("user not found"), fiber.StatusUnauthorized
+	}
+
+	previousHash := session.RefreshTokenHash
+	session.LastSeenAt = time.Now()
+	session.ExpiresAt = session.LastSeenAt.Add(util.RefreshTokenExpiry)
+	if client.IPAddress != "" {
+		session.IPAddress = client.IPAddress
+	}
+
+	// The token is only rotated while the session still holds its hash, so of two requests
+	// presenting it at the same time one loses and is treated as reuse, as is a session
+	// revoked since it was read
+	response, err, status := s.issueTokens(&session, user, user.Role.Name, previousHash)
+	if errors.Is(err, errSessionChanged) {
+		s.revokeReusedSession(session.ID, userID, client)
+		return dto.TokenResponse{}, errors.New("invalid refresh token"), fiber.StatusUnauthorized
+	}
+	return response, err, status
+}
+
+// revokeReusedSession revokes a session whose refresh token was presented after it had been
+// rotated. Sessions revoked already, e.g. by a logout, are not recorded as reuse.
+func (s *sessionService) revokeReusedSession(sessionID, userID uint, client dto.ClientInfo) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.RevokedAt != nil {
+		return
+	}
+
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		log.Printf("failed to revoke session %d after refresh token reuse: %v", sessionID, err)
+	}
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      dto.Actor{UserID: userID, Client: client},
+		Action:     entity.AuditActionRefreshTokenReused,
+		TargetType: entity.AuditTargetSession,
+		TargetID:   sessionID,
+	})
+}
+
+func (s *sessionService) ValidateSession(sessionID, userID, organizationID uint, organizationRole string, roleGrantID uint) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return errors.New("session not found")
+	}
+
+	if session.UserID != userID || !session.IsActive() {
+		return errors.New("session revoked or expired")
+	}
+
+	// Tokens issued before the user was moved out of an organization are no longer valid
+	if session.OrganizationID != organizationID {
+		return errors.New("session organization changed")
+	}
+
+	// Tokens issued before the user's organization role changed are no longer valid
+	if organizationID != 0 {
+		membership, err := s.membershipRepo.Find(organizationID, userID)
+		if err != nil {
+			return errors.New("membership not found")
+		}
+		if membership.Role != organizationRole {
+			return errors.New("organization role changed")
+		}
+	}
+
+	// Tokens carrying a role grant that has since been revoked are no longer valid
+	if session.RoleGrantID != roleGrantID {
+		return errors.New("session role grant changed")
+	}
+
+	if time.Since(session.LastSeenAt) > lastSeenResolution {
+		if err := s.sessionRepo.Touch(session.ID, time.Now()); err != nil {
+			log.Printf("failed to update last seen of session %d: %v", session.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sessionService) SwitchOrganization(sessionID, userID, organizationID uint) (dto.TokenResponse, error, int) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return dto.TokenResponse{}, errors.New("session revoked or expired"), fiber.StatusUnauthorized
+	}
+
+	if _, err := s.membershipRepo.Find(organizationID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.TokenResponse{}, errors.New("not a member of this organization"), fiber.StatusForbidden
+		}
+		return dto.TokenResponse{}, fmt.Errorf("failed to retrieve membership: %w", err), fiber.StatusInternalServerError
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("user not found"), fiber.StatusUnauthorized
+	}
+
+	// Access tokens of the previous organization stop working once the session moves
+	session.OrganizationID = organizationID
+	response, err, status := s.issueTokens(&session, user, user.Role.Name, session.RefreshTokenHash)
+	if errors.Is(err, errSessionChanged) {
+		return dto.TokenResponse{}, err, fiber.StatusUnauthorized
+	}
+	return response, err, status
+}
+
+func (s *sessionService) GetUserSessions(userID, currentSessionID uint) ([]dto.SessionResponse, error, int) {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:             session.ID,
+			UserAgent:      session.UserAgent,
+			IPAddress:      session.IPAddress,
+			CreatedAt:      session.CreatedAt,
+			LastSeenAt:     session.LastSeenAt,
+			ExpiresAt:      session.ExpiresAt,
+			Current:        session.ID == currentSessionID,
+			ImpersonatorID: session.ImpersonatorID,
+		})
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *sessionService) RevokeSession(userID, sessionID uint) (error, int) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found"), fiber.StatusNotFound
+		}
+		return fmt.Errorf("failed to retrieve session: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Do not reveal sessions of other users
+	if session.UserID != userID {
+		return errors.New("session not found"), fiber.StatusNotFound
+	}
+
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err), fiber.StatusInternalServerError
+	}
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *sessionService) RevokeAllSessions(userID uint) (error, int) {
+	if err := s.sessionRepo.RevokeAllByUserID(userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err), fiber.StatusInternalServerError
+	}
+
+	return nil, fiber.StatusNoContent
+}
+
+// StartImpersonation opens a session of the user on behalf of an admin. Only a short-lived
+// access token carrying the admin in its "act" claim is issued; it cannot be refreshed.
+func (s *sessionService) StartImpersonation(user, impersonator entity.User, client dto.ClientInfo) (dto.ImpersonationResponse, error, int) {
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.ImpersonationTokenExpiry)
+	session := entity.Session{
+		UserID:         user.ID,
+		ImpersonatorID: &impersonator.ID,
+		UserAgent:      truncate(client.UserAgent, 512),
+		IPAddress:      client.IPAddress,
+		LastSeenAt:     now,
+		ExpiresAt:      expiresAt,
+	}
+
+	subject, err := s.accessTokenSubject(&session, user, user.Role.Name)
+	if err != nil {
+		return dto.ImpersonationResponse{}, err, fiber.StatusInternalServerError
+	}
+
+	if err := s.sessionRepo.Create(&session); err != nil {
+		return dto.ImpersonationResponse{}, errors.New("failed to create session"), fiber.StatusInternalServerError
+	}
+
+	subject.SessionID = session.ID
+	subject.ImpersonatorID = impersonator.ID
+	subject.ImpersonatorEmail = impersonator.Email
+	subject.ExpiresAt = expiresAt
+
+	accessToken, err := util.GenerateAccessToken(subject)
+	if err != nil {
+		return dto.ImpersonationResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+	}
+
+	return dto.ImpersonationResponse{
+		AccessToken:    accessToken,
+		TokenType:      "Bearer",
+		ExpiresIn:      int64(s.cfg.ImpersonationTokenExpiry / time.Second),
+		ExpiresAt:      expiresAt,
+		UserID:         user.ID,
+		ImpersonatorID: impersonator.ID,
+		SessionID:      session.ID,
+	}, nil, fiber.StatusCreated
+}
+
+// issueTokens generates a token pair for the session and stores the new refresh token hash in
+// place of previousHash. It returns errSessionChanged when the session no longer holds previousHash
+// or was revoked in the meantime.
+func (s *sessionService) issueTokens(session *entity.Session, user entity.User, roleName, previousHash string) (dto.TokenResponse, error, int) {
+	subject, err := s.accessTokenSubject(session, user, roleName)
+	if err != nil {
+		return dto.TokenResponse{}, err, fiber.StatusInternalServerError
+	}
+
+	accessToken, err := util.GenerateAccessToken(subject)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to generate access token"), fiber.StatusInternalServerError
+	}
+
+	refreshToken, err := util.GenerateRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to generate refresh token"), fiber.StatusInternalServerError
+	}
+
+	session.RefreshTokenHash = util.HashToken(refreshToken)
+	rotated, err := s.sessionRepo.Rotate(session, previousHash, time.Now())
+	if err != nil {
+		return dto.TokenResponse{}, errors.New("failed to update session"), fiber.StatusInternalServerError
+	}
+	if !rotated {
+		return dto.TokenResponse{}, errSessionChanged, fiber.StatusUnauthorized
+	}
+
+	return dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(util.AccessTokenExpiry / time.Second),
+	}, nil, fiber.StatusOK
+}
+
+// accessTokenSubject describes the session's user for an access token. The organization role
+// and role grants are looked up on every issue so role changes apply on refresh.
+func (s *sessionService) accessTokenSubject(session *entity.Session, user entity.User, roleName string) (util.AccessTokenSubject, error) {
+	organizationRole, err := s.resolveOrganization(session)
+	if err != nil {
+		return util.AccessTokenSubject{}, errors.New("failed to retrieve membership")
+	}
+
+	grant, err := s.roleGrantRepo.FindActiveByUserID(user.ID, time.Now())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.AccessTokenSubject{}, errors.New("failed to retrieve role grants")
+	}
+	session.RoleGrantID = grant.ID
+
+	subject := util.AccessTokenSubject{
+		UserID:           user.ID,
+		SessionID:        session.ID,
+		Email:            user.Email,
+		Role:             roleName,
+		OrganizationID:   session.OrganizationID,
+		OrganizationRole: organizationRole,
+	}
+
+	if grant.ID != 0 {
+		subject.Role = grant.Role.Name
+		subject.RoleGrantID = grant.ID
+		subject.BaseRole = roleName
+		subject.RoleExpiresAt = grant.EndsAt
+	}
+
+	if s.cfg.TokenIncludeGroups && session.OrganizationID != 0 {
+		groups, err := s.groupService.GetEffectiveGroups(user.ID, session.OrganizationID)
+		if err != nil {
+			return util.AccessTokenSubject{}, errors.New("failed to retrieve groups")
+		}
+		for _, group := range groups {
+			subject.Groups = append(subject.Groups, group.Name)
+		}
+		subject.Permissions = groupPermissions(groups)
+	}
+
+	return subject, nil
+}
+
+// resolveOrganization keeps the session in its organization while the user is a member there
+// and otherwise moves it to the organization the user joined first, if any
+func (s *sessionService) resolveOrganization(session *entity.Session) (string, error) {
+	if session.OrganizationID != 0 {
+		membership, err := s.membershipRepo.Find(session.OrganizationID, session.UserID)
+		if err == nil {
+			return membership.Role, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+	}
+
+	memberships, err := s.membershipRepo.FindByUserID(session.UserID)
+	if err != nil {
+		return "", err
+	}
+	if len(memberships) == 0 {
+		session.OrganizationID = 0
+		return "", nil
+	}
+
+	session.OrganizationID = memberships[0].OrganizationID
+	return memberships[0].Role, nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository"
+	"user_crud/internal/domain/repository/interfaces"
+	"user_crud/internal/dto"
+)
+
+// hookedSessionRepository runs afterFind once a session has been read, so a test can
+// change the session between loading and rotating it
+type hookedSessionRepository struct {
+	interfaces.SessionRepository
+	afterFind func(entity.Session)
+}
+
+func (r *hookedSessionRepository) FindByID(id uint) (entity.Session, error) {
+	session, err := r.SessionRepository.FindByID(id)
+	if err == nil && r.afterFind != nil {
+		r.afterFind(session)
+	}
+	return session, err
+}
+
+// newTestSessionService starts a session for a new user and returns the service, its
+// hookable session repository and the tokens of the session
+func newTestSessionService(t *testing.T) (*sessionService, *hookedSessionRepository, *gorm.DB, dto.TokenResponse) {
+	t.Helper()
+
+	db := newTestDB(t)
+	sessions := &hookedSessionRepository{SessionRepository: repository.NewSessionRepository(db)}
+	service := NewSessionService(
+		sessions,
+		repository.NewUserRepository(db),
+		repository.NewMembershipRepository(db),
+		repository.NewRoleGrantRepository(db),
+		nil,
+		NewAuditService(repository.NewAuditEventRepository(db)),
+		&config.Config{},
+	).(*sessionService)
+
+	user := createTestUser(t, db, "bob@example.com", "user")
+	tokens, err, _ := service.StartSession(user, "user", dto.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, sessions, db, tokens
+}
+
+func TestRefreshSessionRotatesOnce(t *testing.T) {
+	service, _, _, tokens := newTestSessionService(t)
+
+	rotated, err, _ := service.RefreshSession(tokens.RefreshToken, dto.ClientInfo{})
+	if err != nil {
+		t.Fatalf("expected the refresh to succeed, got %v", err)
+	}
+
+	// The old token is reuse now and takes the session down with it
+	if _, err, status := service.RefreshSession(tokens.RefreshToken, dto.ClientInfo{}); err == nil || status != fiber.StatusUnauthorized {
+		t.Fatalf("expected the rotated token to be refused, got %d %v", status, err)
+	}
+	if _, err, status := service.RefreshSession(rotated.RefreshToken, dto.ClientInfo{}); err == nil || status != fiber.StatusUnauthorized {
+		t.Errorf("expected the session to be revoked after reuse, got %d %v", status, err)
+	}
+}
+
+func TestRefreshSessionRevokedBeforeRotation(t *testing.T) {
+	service, sessions, db, tokens := newTestSessionService(t)
+
+	// A logout lands after the refresh has read the session but before it rotates the token
+	sessions.afterFind = func(session entity.Session) {
+		sessions.afterFind = nil
+		if err := sessions.Revoke(session.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err, status := service.RefreshSession(tokens.RefreshToken, dto.ClientInfo{}); err == nil || status != fiber.StatusUnauthorized {
+		t.Fatalf("expected the refresh of a revoked session to fail, got %d %v", status, err)
+	}
+
+	var session entity.Session
+	if err := db.First(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.RevokedAt == nil {
+		t.Error("expected the session to stay revoked")
+	}
+	var reused int64
+	db.Model(&entity.AuditEvent{}).Where("action = ?", entity.AuditActionRefreshTokenReused).Count(&reused)
+	if reused != 0 {
+		t.Errorf("expected a logout not to be recorded as token reuse, got %d events", reused)
+	}
+}
+
+func TestConcurrentRefreshesWithOneToken(t *testing.T) {
+	service, sessions, db, tokens := newTestSessionService(t)
+
+	// Both refreshes read the session before either rotates its token
+	var loaded sync.WaitGroup
+	var finds atomic.Int32
+	loaded.Add(2)
+	sessions.afterFind = func(entity.Session) {
+		if finds.Add(1) <= 2 {
+			loaded.Done()
+			loaded.Wait()
+		}
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]int, 2)
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, statuses[i] = service.RefreshSession(tokens.RefreshToken, dto.ClientInfo{})
+		}()
+	}
+	wg.Wait()
+
+	if !(statuses[0] == fiber.StatusOK && statuses[1] == fiber.StatusUnauthorized) && !(statuses[0] == fiber.StatusUnauthorized && statuses[1] == fiber.StatusOK) {
+		t.Fatalf("expected exactly one refresh to succeed, got %v", statuses)
+	}
+
+	// The losing request presented a token that had just been rotated, which is reuse
+	var session entity.Session
+	if err := db.First(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.RevokedAt == nil {
+		t.Error("expected the session to be revoked after the token was used twice")
+	}
+}
@@ -3,161 +3,304 @@ package service
 import (
 	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 
+	"user_crud/internal/config"
 	"user_crud/internal/domain/entity"
 	"user_crud/internal/domain/repository/interfaces"
 	serviceInterfaces "user_crud/internal/domain/service/interfaces"
 	"user_crud/internal/dto"
 	"user_crud/internal/util"
+	"user_crud/pkg/mailer"
 )
 
+const (
+	// emailChangeTokenExpiry is how long an email change can be confirmed
+	emailChangeTokenExpiry = time.Hour * 24
+	// passwordSetupTokenExpiry is how long an admin created user can choose a password
+	passwordSetupTokenExpiry = time.Hour * 72
+	// defaultRole is the role of self-registered users, the only one organization admins assign
+	defaultRole = "user"
+)
+
+// errInvalidToken is returned for verification tokens that are unknown, used or expired
+var errInvalidToken = errors.New("invalid or expired token")
+
 type userService struct {
-	userRepo interfaces.UserRepository
-	fileRepo interfaces.FileRepository
+	userRepo       interfaces.UserRepository
+	roleRepo       interfaces.RoleRepository
+	fileRepo       interfaces.FileRepository
+	sessionRepo    interfaces.SessionRepository
+	tokenRepo      interfaces.VerificationTokenRepository
+	membershipRepo interfaces.MembershipRepository
+	unitOfWork     interfaces.UnitOfWork
+	passwordPolicy serviceInterfaces.PasswordPolicyService
+	policies       serviceInterfaces.PolicyService
+	organizations  serviceInterfaces.OrganizationService
+	notifications  serviceInterfaces.NotificationService
+	auditService   serviceInterfaces.AuditService
+	mailer         mailer.Mailer
+	files          serviceInterfaces.FileService
+	cfg            *config.Config
 }
 
 func NewUserService(
 	userRepo interfaces.UserRepository,
+	roleRepo interfaces.RoleRepository,
 	fileRepo interfaces.FileRepository,
+	sessionRepo interfaces.SessionRepository,
+	tokenRepo interfaces.VerificationTokenRepository,
+	membershipRepo interfaces.MembershipRepository,
+	unitOfWork interfaces.UnitOfWork,
+	passwordPolicy serviceInterfaces.PasswordPolicyService,
+	policies serviceInterfaces.PolicyService,
+	organizations serviceInterfaces.OrganizationService,
+	notifications serviceInterfaces.NotificationService,
+	auditService serviceInterfaces.AuditService,
+	mailer mailer.Mailer,
+	files serviceInterfaces.FileService,
+	cfg *config.Config,
 ) serviceInterfaces.UserService {
 	return &userService{
-		userRepo: userRepo,
-		fileRepo: fileRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		fileRepo:       fileRepo,
+		sessionRepo:    sessionRepo,
+		tokenRepo:      tokenRepo,
+		membershipRepo: membershipRepo,
+		unitOfWork:     unitOfWork,
+		passwordPolicy: passwordPolicy,
+		policies:       policies,
+		organizations:  organizations,
+		notifications:  notifications,
+		auditService:   auditService,
+		mailer:         mailer,
+		files:          files,
+		cfg:            cfg,
 	}
 }
 
-func (s *userService) CreateUser(c *fiber.Ctx, currentUserRole string) (dto.UserResponse, error, int) {
-	// Only admins can create users
-	if currentUserRole != "admin" {
+func (s *userService) CreateUser(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int) {
+	// Only global admins and users allowed to manage their organization can create users;
+	// the permission lets them create users with the default role only
+	if !hasAdminRights(actor) && !hasPermission(actor, entity.ScopeUsersWrite) {
 		return dto.UserResponse{}, errors.New("permission denied"), fiber.StatusForbidden
 	}
 
+	var req dto.CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return dto.UserResponse{}, errors.New("invalid request body"), fiber.StatusBadRequest
+	}
+
 	// Check required fields
-	name := c.FormValue("name")
-	if name == "" {
+	if req.Name == "" {
 		return dto.UserResponse{}, errors.New("name is required"), fiber.StatusBadRequest
 	}
 
-	birthdate := c.FormValue("birthdate")
-	if birthdate == "" {
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return dto.UserResponse{}, errors.New("email is required"), fiber.StatusBadRequest
+	}
+	if !util.IsValidEmail(req.Email) {
+		return dto.UserResponse{}, errors.New("invalid email address"), fiber.StatusBadRequest
+	}
+
+	if req.Birthdate == "" {
 		return dto.UserResponse{}, errors.New("birthdate is required"), fiber.StatusBadRequest
 	}
 
+	if req.RoleName == "" {
+		return dto.UserResponse{}, errors.New("role_name is required"), fiber.StatusBadRequest
+	}
+
+	// Roles apply across organizations, so only global admins hand out others than the default
+	if !canAssignRole(actor, req.RoleName) {
+		return dto.UserResponse{}, errors.New("only global admins can assign roles other than " + defaultRole), fiber.StatusForbidden
+	}
+
+	// Other users add users to their own organization; global admins may pick one
+	scoped := !hasAdminRights(actor)
+	organizationID := actor.OrganizationID
+	joinDefault := false
+	if !scoped {
+		if req.OrganizationID != 0 {
+			if _, err, status := s.organizations.GetOrganization(req.OrganizationID, actor); err != nil {
+				return dto.UserResponse{}, err, status
+			}
+			scoped, organizationID = true, req.OrganizationID
+		} else {
+			joinDefault = true
+		}
+	}
+
+	if req.SendInvite && req.Password != "" {
+		return dto.UserResponse{}, errors.New("password cannot be set when send_invite is true"), fiber.StatusBadRequest
+	}
+	if !req.SendInvite && req.Password == "" {
+		return dto.UserResponse{}, errors.New("password is required unless send_invite is true"), fiber.StatusBadRequest
+	}
+
 	image, err := c.FormFile("image")
 	if err != nil {
 		return dto.UserResponse{}, errors.New("image is required"), fiber.StatusBadRequest
 	}
 
 	// Parse birthdate
-	birthTime, err := util.ParseBirthdate(birthdate)
+	birthTime, err := util.ParseBirthdate(req.Birthdate)
 	if err != nil {
 		return dto.UserResponse{}, errors.New("invalid birthdate format. Please use DD.MM.YYYY"), fiber.StatusBadRequest
 	}
 
-	// Calculate age
-	age := util.CalculateAge(birthTime)
-
-	// Save image file
-	imageName, err := util.SaveUploadedFile(c, image)
+	// Resolve role
+	role, err := s.roleRepo.FindByName(req.RoleName)
 	if err != nil {
-		return dto.UserResponse{}, fmt.Errorf("failed to save image: %w", err), fiber.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.UserResponse{}, errors.New("role not found"), fiber.StatusBadRequest
+		}
+		return dto.UserResponse{}, fmt.Errorf("failed to retrieve role: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Check if email already exists
+	if err, status := s.checkEmailAvailable(req.Email); err != nil {
+		return dto.UserResponse{}, err, status
 	}
 
 	// Create user entity
 	user := entity.User{
-		Name:      name,
-		Age:       age,
-		ImageName: imageName,
+		Name:   req.Name,
+		Email:  req.Email,
+		Age:    util.CalculateAge(birthTime),
+		RoleID: role.ID,
 	}
 
-	// Create user in database
-	if err := s.userRepo.Create(&user); err != nil {
-		// Clean up the image file if user creation fails
-		_ = util.DeleteFile(imageName)
+	// Enforce password policy and hash password; invited users choose theirs later
+	if !req.SendInvite {
+		if err, status := s.passwordPolicy.ValidatePassword(req.Password, user); err != nil {
+			return dto.UserResponse{}, err, status
+		}
+
+		user.Password, err = util.HashPassword(req.Password)
+		if err != nil {
+			return dto.UserResponse{}, errors.New("failed to hash password"), fiber.StatusInternalServerError
+		}
+	}
+
+	// Keep the image for the media workers; the user shows it once it is processed
+	avatar, err, status := s.files.UploadImage(c, actor, image)
+	if err != nil {
+		return dto.UserResponse{}, err, status
+	}
+
+	// Create user in database together with the queued image and registration event
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		users := tx.Users()
+		if scoped {
+			users = users.InOrganization(organizationID)
+		}
+		if err := users.Create(&user); err != nil {
+			return err
+		}
+
+		avatar.UserID = user.ID
+		if err := tx.Files().Create(&avatar); err != nil {
+			return fmt.Errorf("failed to create file record: %w", err)
+		}
+
+		data := dto.UserEventData{User: eventUser(user)}
+		data.User.Role = role.Name
+		return stageUserEvent(tx.Outbox(), entity.EventUserRegistered, data)
+	})
+	if err != nil {
+		// Clean up the upload if user creation fails
+		s.files.DiscardUpload(avatar.SourceName)
+		// Another request may have taken the address since it was checked
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return dto.UserResponse{}, errors.New("email already exists"), fiber.StatusConflict
+		}
 		return dto.UserResponse{}, fmt.Errorf("failed to create user: %w", err), fiber.StatusInternalServerError
 	}
+	s.files.Wake()
 
-	// Create file record
-	file := entity.File{
-		FileName: imageName,
-		UserID:   user.ID,
+	if joinDefault {
+		if err := s.organizations.JoinDefaultOrganization(user.ID); err != nil {
+			log.Printf("failed to add user:%d to the default organization: %v", user.ID, err)
+		}
 	}
 
-	if err := s.fileRepo.Create(&file); err != nil {
-		// We should implement a proper rollback here in production code
-		return dto.UserResponse{}, fmt.Errorf("failed to create file record: %w", err), fiber.StatusInternalServerError
+	if req.SendInvite {
+		if err, status := s.sendPasswordSetup(user); err != nil {
+			return dto.UserResponse{}, err, status
+		}
+	} else if err := s.passwordPolicy.RecordPassword(user.ID, user.Password); err != nil {
+		log.Printf("failed to record password history of user:%d: %v", user.ID, err)
 	}
 
+	// Reload to populate the role
+	createdUser, err, status := s.findUser(user.ID)
+	if err != nil {
+		return dto.UserResponse{}, err, status
+	}
+
+	s.recordUser(actor, entity.AuditActionUserCreated, createdUser.ID, nil, userSnapshot(createdUser))
+
 	// Build response
-	return dto.UserResponse{
-		ID:       user.ID,
-		Name:     user.Name,
-		Age:      user.Age,
-		Email:    user.Email,
-		Role:     user.Role.Name,
-		ImageUrl: util.BuildImageURL(c, user.ImageName),
-	}, nil, fiber.StatusCreated
+	return s.toUserResponse(c, createdUser), nil, fiber.StatusCreated
 }
 
-func (s *userService) GetAllUsers(c *fiber.Ctx) ([]dto.UserResponse, error, int) {
-	users, err := s.userRepo.FindAll()
+func (s *userService) GetAllUsers(c *fiber.Ctx, actor dto.Actor) ([]dto.UserResponse, error, int) {
+	repo := s.usersFor(actor)
+
+	// Global admins may narrow the list down to one organization
+	if hasAdminRights(actor) && c.Query("organization_id") != "" {
+		organizationID, err := strconv.ParseUint(c.Query("organization_id"), 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid organization_id"), fiber.StatusBadRequest
+		}
+		repo = s.userRepo.InOrganization(uint(organizationID))
+	}
+
+	users, err := repo.FindAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve users: %w", err), fiber.StatusInternalServerError
 	}
 
 	var response []dto.UserResponse
 	for _, user := range users {
-		response = append(response, dto.UserResponse{
-			ID:       user.ID,
-			Name:     user.Name,
-			Age:      user.Age,
-			Email:    user.Email,
-			Role:     user.Role.Name,
-			ImageUrl: util.BuildImageURL(c, user.ImageName),
-		})
+		response = append(response, s.toUserResponse(c, user))
 	}
 
 	return response, nil, fiber.StatusOK
 }
 
-func (s *userService) GetUser(id uint, c *fiber.Ctx) (dto.UserResponse, error, int) {
-	user, err := s.userRepo.FindByID(id)
+func (s *userService) GetUser(id uint, c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int) {
+	user, err, status := s.findUserFor(actor, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return dto.UserResponse{}, errors.New("user not found"), fiber.StatusNotFound
-		}
-		return dto.UserResponse{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+		return dto.UserResponse{}, err, status
 	}
 
-	return dto.UserResponse{
-		ID:       user.ID,
-		Name:     user.Name,
-		Age:      user.Age,
-		Email:    user.Email,
-		Role:     user.Role.Name,
-		ImageUrl: util.BuildImageURL(c, user.ImageName),
-	}, nil, fiber.StatusOK
+	return s.toUserResponse(c, user), nil, fiber.StatusOK
 }
 
-func (s *userService) UpdateUser(c *fiber.Ctx, id uint, currentUserID uint, currentUserRole string) (dto.UserResponse, error, int) {
-	// Check if user has permission to update this record
-	// Admin can update any record, users can only update their own
-	if currentUserRole != "admin" && currentUserID != id {
-		return dto.UserResponse{}, errors.New("permission denied"), fiber.StatusForbidden
-	}
-
+func (s *userService) UpdateUser(c *fiber.Ctx, id uint, actor dto.Actor) (dto.UserResponse, error, int) {
 	// Find existing user
-	existingUser, err := s.userRepo.FindByID(id)
+	existingUser, err, status := s.findUserFor(actor, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return dto.UserResponse{}, errors.New("user not found"), fiber.StatusNotFound
-		}
-		return dto.UserResponse{}, fmt.Errorf("failed to find user: %w", err), fiber.StatusInternalServerError
+		return dto.UserResponse{}, err, status
 	}
 
+	// Check the access policy allows the actor to update this user
+	if err, status := s.authorizeUser(actor, entity.ActionUsersUpdate, existingUser); err != nil {
+		return dto.UserResponse{}, err, status
+	}
+	before := userSnapshot(existingUser)
+
 	// Check required fields
 	name := c.FormValue("name")
 	if name == "" {
@@ -179,92 +322,646 @@ func (s *userService) UpdateUser(c *fiber.Ctx, id uint, currentUserID uint, curr
 	existingUser.Name = name
 	existingUser.Age = util.CalculateAge(birthTime)
 
-	// Check if there's a new image
-	oldImageName := existingUser.ImageName
-	image, err := c.FormFile("image")
+	// Only global admins change roles, and not their own so they cannot lock themselves out.
+	// Taking a role away is guarded like handing it out.
+	previousRole := existingUser.Role.Name
+	if roleName := c.FormValue("role_name"); roleName != "" && roleName != previousRole {
+		if !canAssignRole(actor, roleName) || !canAssignRole(actor, previousRole) {
+			return dto.UserResponse{}, errors.New("only global admins can change roles"), fiber.StatusForbidden
+		}
+		if existingUser.ID == actor.UserID {
+			return dto.UserResponse{}, errors.New("cannot change your own role"), fiber.StatusForbidden
+		}
 
-	if err == nil {
-		// New image was uploaded
-		imageName, err := util.SaveUploadedFile(c, image)
+		role, err := s.roleRepo.FindByName(roleName)
 		if err != nil {
-			return dto.UserResponse{}, fmt.Errorf("failed to save new image: %w", err), fiber.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return dto.UserResponse{}, errors.New("role not found"), fiber.StatusBadRequest
+			}
+			return dto.UserResponse{}, fmt.Errorf("failed to retrieve role: %w", err), fiber.StatusInternalServerError
 		}
+		existingUser.RoleID = role.ID
+		existingUser.Role = role
+	}
 
-		// Update user with new image
-		existingUser.ImageName = imageName
-
-		// Update or create file record
-		file, err := s.fileRepo.FindByUserID(existingUser.ID)
+	// Check if there's a new image; it replaces the current one once it is processed
+	var avatar *entity.File
+	if image, err := c.FormFile("image"); err == nil {
+		upload, err, status := s.files.UploadImage(c, actor, image)
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Create new file record
-				newFile := entity.File{
-					FileName: imageName,
-					UserID:   existingUser.ID,
-				}
-				if err := s.fileRepo.Create(&newFile); err != nil {
-					return dto.UserResponse{}, fmt.Errorf("failed to create file record: %w", err), fiber.StatusInternalServerError
-				}
-			} else {
-				return dto.UserResponse{}, fmt.Errorf("failed to retrieve file record: %w", err), fiber.StatusInternalServerError
+			return dto.UserResponse{}, err, status
+		}
+		avatar = &upload
+	}
+
+	// Save updated user together with its events and the queued image
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Users().Update(&existingUser); err != nil {
+			return err
+		}
+
+		if avatar != nil {
+			avatar.UserID = existingUser.ID
+			if err := tx.Files().Create(avatar); err != nil {
+				return fmt.Errorf("failed to create file record: %w", err)
 			}
+			existingUser.Files = append(existingUser.Files, *avatar)
+		}
+
+		data := dto.UserEventData{User: eventUser(existingUser)}
+		if err := stageUserEvent(tx.Outbox(), entity.EventUserUpdated, data); err != nil {
+			return err
+		}
+		if existingUser.Role.Name != previousRole {
+			return stageUserEvent(tx.Outbox(), entity.EventRoleChanged, dto.UserEventData{User: data.User, PreviousRole: previousRole})
+		}
+		return nil
+	})
+	if avatar != nil {
+		if err != nil {
+			s.files.DiscardUpload(avatar.SourceName)
 		} else {
-			// Update existing file
-			file.FileName = imageName
-			if err := s.fileRepo.Update(&file); err != nil {
-				return dto.UserResponse{}, fmt.Errorf("failed to update file record: %w", err), fiber.StatusInternalServerError
-			}
+			s.files.Wake()
+		}
+	}
+	if err != nil {
+		return dto.UserResponse{}, fmt.Errorf("failed to update user: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.recordUser(actor, entity.AuditActionUserUpdated, existingUser.ID, before, userSnapshot(existingUser))
+
+	// Access tokens carry the role, so the user signs in again to act with the new one
+	if existingUser.Role.Name != previousRole {
+		if err := s.sessionRepo.RevokeAllByUserID(existingUser.ID); err != nil {
+			log.Printf("failed to revoke sessions of user:%d after role change: %v", existingUser.ID, err)
+		}
+		s.notifications.Notify(existingUser.ID, roleChangedNotification(previousRole, existingUser.Role.Name))
+	}
+
+	return s.toUserResponse(c, existingUser), nil, fiber.StatusOK
+}
+
+func (s *userService) DeleteUser(id uint, actor dto.Actor) (error, int) {
+	// Find the user to get image filename
+	user, err, status := s.findUserFor(actor, id)
+	if err != nil {
+		return err, status
+	}
+
+	// Check the access policy allows the actor to delete this user
+	if err, status := s.authorizeUser(actor, entity.ActionUsersDelete, user); err != nil {
+		return err, status
+	}
+
+	// An organization admin must not delete an account other tenants still rely on
+	if !hasAdminRights(actor) {
+		memberships, err := s.membershipRepo.FindByUserID(id)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve memberships: %w", err), fiber.StatusInternalServerError
+		}
+		if len(memberships) > 1 {
+			return errors.New("user belongs to other organizations, remove them from this organization instead"), fiber.StatusConflict
 		}
+	}
+
+	return s.deleteUser(user, actor, entity.AuditActionUserDeleted)
+}
+
+func (s *userService) ChangePassword(actor dto.Actor, currentSessionID uint, req dto.ChangePasswordRequest) (error, int) {
+	user, err, status := s.findUser(actor.UserID)
+	if err != nil {
+		return err, status
+	}
+
+	// Confirm the current password
+	if !util.CheckPassword(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect"), fiber.StatusUnauthorized
+	}
+
+	// Enforce password policy
+	if err, status := s.passwordPolicy.ValidatePassword(req.NewPassword, user); err != nil {
+		return err, status
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("failed to hash password"), fiber.StatusInternalServerError
+	}
+
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(&user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err), fiber.StatusInternalServerError
+	}
+
+	if err := s.passwordPolicy.RecordPassword(user.ID, hashedPassword); err != nil {
+		log.Printf("failed to record password history of user:%d: %v", user.ID, err)
+	}
+
+	// Sign out every other device
+	if err := s.sessionRepo.RevokeOthersByUserID(user.ID, currentSessionID); err != nil {
+		log.Printf("failed to revoke sessions of user:%d after password change: %v", user.ID, err)
+	}
+
+	s.recordUser(actor, entity.AuditActionPasswordChanged, user.ID, nil, nil)
+	s.notifications.Notify(user.ID, passwordChangedNotification(actor.Client, time.Now()))
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *userService) RequestEmailChange(actor dto.Actor, req dto.ChangeEmailRequest) (error, int) {
+	user, err, status := s.findUser(actor.UserID)
+	if err != nil {
+		return err, status
+	}
+
+	if !util.CheckPassword(req.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect"), fiber.StatusUnauthorized
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if !util.IsValidEmail(newEmail) {
+		return errors.New("invalid email address"), fiber.StatusBadRequest
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.New("new email is the same as the current one"), fiber.StatusBadRequest
+	}
+	if err, status := s.checkEmailAvailable(newEmail); err != nil {
+		return err, status
+	}
+
+	token, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return errors.New("failed to generate verification token"), fiber.StatusInternalServerError
+	}
+
+	// Only the latest request can be confirmed
+	if err := s.tokenRepo.DeleteByUserIDAndPurpose(user.ID, entity.TokenPurposeEmailChange); err != nil {
+		return fmt.Errorf("failed to replace pending email change: %w", err), fiber.StatusInternalServerError
+	}
+
+	verification := entity.VerificationToken{
+		UserID:    user.ID,
+		Purpose:   entity.TokenPurposeEmailChange,
+		TokenHash: util.HashToken(token),
+		Payload:   newEmail,
+		ExpiresAt: time.Now().Add(emailChangeTokenExpiry),
+	}
+	if err := s.tokenRepo.Create(&verification); err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err), fiber.StatusInternalServerError
+	}
+
+	verifyBody := fmt.Sprintf(
+		"Hello %s,\n\nConfirm your new email address by opening the link below within 24 hours:\n\n%s/verify-email?token=%s\n",
+		user.Name, s.cfg.AppBaseURL, token,
+	)
+	if err := s.mailer.Send(newEmail, "Confirm your new email address", verifyBody); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err), fiber.StatusInternalServerError
+	}
+
+	noticeBody := fmt.Sprintf(
+		"Hello %s,\n\nA change of your account email to %s was requested. If this wasn't you, change your password now.\n",
+		user.Name, newEmail,
+	)
+	if err := s.mailer.Send(user.Email, "Email change requested", noticeBody); err != nil {
+		log.Printf("failed to notify user:%d about email change: %v", user.ID, err)
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionEmailChangeRequested,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"new_email": newEmail},
+	})
+
+	return nil, fiber.StatusAccepted
+}
 
-		// Delete old image
-		_ = util.DeleteFile(oldImageName)
+func (s *userService) ConfirmEmailChange(token string, client dto.ClientInfo) (error, int) {
+	verification, err := s.tokenRepo.FindByTokenHash(util.HashToken(token))
+	if err != nil || verification.Purpose != entity.TokenPurposeEmailChange || !verification.IsUsable() {
+		return errInvalidToken, fiber.StatusBadRequest
 	}
 
-	// Save updated user
-	if err := s.userRepo.Update(&existingUser); err != nil {
+	// The address may have been taken since the change was requested
+	if err, status := s.checkEmailAvailable(verification.Payload); err != nil {
+		return err, status
+	}
+
+	// Reload to populate the role for the event
+	user, err, status := s.findUser(verification.UserID)
+	if err != nil {
+		return err, status
+	}
+
+	// The token is used up in the transaction changing the address, so it applies once
+	previousEmail := user.Email
+	user.Email = verification.Payload
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		used, err := tx.VerificationTokens().Use(verification.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidToken
+		}
+		if err := tx.Users().Update(&user); err != nil {
+			return err
+		}
+		return stageUserEvent(tx.Outbox(), entity.EventUserUpdated, dto.UserEventData{User: eventUser(user)})
+	})
+	if errors.Is(err, errInvalidToken) {
+		return err, fiber.StatusBadRequest
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errors.New("email already exists"), fiber.StatusConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err), fiber.StatusInternalServerError
+	}
+
+	// Whoever holds the token confirms the change, so it is recorded for the user
+	s.recordUser(dto.Actor{UserID: user.ID, Client: client}, entity.AuditActionEmailChanged, user.ID,
+		map[string]any{"email": previousEmail}, map[string]any{"email": user.Email})
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *userService) CompletePasswordSetup(req dto.SetPasswordRequest, client dto.ClientInfo) (error, int) {
+	verification, err := s.tokenRepo.FindByTokenHash(util.HashToken(req.Token))
+	if err != nil || verification.Purpose != entity.TokenPurposePasswordSetup || !verification.IsUsable() {
+		return errInvalidToken, fiber.StatusBadRequest
+	}
+
+	user := verification.User
+
+	// Enforce password policy
+	if err, status := s.passwordPolicy.ValidatePassword(req.Password, user); err != nil {
+		return err, status
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		return errors.New("failed to hash password"), fiber.StatusInternalServerError
+	}
+
+	// The token is used up in the transaction setting the password, so a replayed link
+	// cannot overwrite a password chosen later
+	user.Password = hashedPassword
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		used, err := tx.VerificationTokens().Use(verification.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidToken
+		}
+		return tx.Users().Update(&user)
+	})
+	if errors.Is(err, errInvalidToken) {
+		return err, fiber.StatusBadRequest
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err), fiber.StatusInternalServerError
+	}
+
+	if err := s.passwordPolicy.RecordPassword(user.ID, hashedPassword); err != nil {
+		log.Printf("failed to record password history of user:%d: %v", user.ID, err)
+	}
+
+	s.recordUser(dto.Actor{UserID: user.ID, Client: client}, entity.AuditActionPasswordSet, user.ID, nil, nil)
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *userService) UpdateAvatar(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int) {
+	user, err, status := s.findUser(actor.UserID)
+	if err != nil {
+		return dto.UserResponse{}, err, status
+	}
+
+	image, err := c.FormFile("image")
+	if err != nil {
+		return dto.UserResponse{}, errors.New("image is required"), fiber.StatusBadRequest
+	}
+
+	// The avatar switches to the image once the media workers have processed it
+	file, err, status := s.files.UploadImage(c, actor, image)
+	if err != nil {
+		return dto.UserResponse{}, err, status
+	}
+
+	file.UserID = user.ID
+	if err := s.fileRepo.Create(&file); err != nil {
+		s.files.DiscardUpload(file.SourceName)
+		return dto.UserResponse{}, fmt.Errorf("failed to create file record: %w", err), fiber.StatusInternalServerError
+	}
+	user.Files = append(user.Files, file)
+	s.files.Wake()
+
+	s.recordUser(actor, entity.AuditActionAvatarUpdated, user.ID, nil, map[string]any{"pending_image_id": file.ID})
+
+	return s.toUserResponse(c, user), nil, fiber.StatusAccepted
+}
+
+func (s *userService) DeleteAvatar(c *fiber.Ctx, actor dto.Actor) (dto.UserResponse, error, int) {
+	user, err, status := s.findUser(actor.UserID)
+	if err != nil {
+		return dto.UserResponse{}, err, status
+	}
+
+	// Images still being processed are cancelled, too; attachments stay
+	var oldFiles, otherFiles []entity.File
+	var oldIDs []uint
+	for _, file := range user.Files {
+		if file.Purpose == entity.FilePurposeAvatar {
+			oldFiles = append(oldFiles, file)
+			oldIDs = append(oldIDs, file.ID)
+		} else {
+			otherFiles = append(otherFiles, file)
+		}
+	}
+	if user.ImageName == "" && len(oldFiles) == 0 {
+		return s.toUserResponse(c, user), nil, fiber.StatusOK
+	}
+
+	oldImageName := user.ImageName
+	user.ImageName = ""
+	user.Files = otherFiles
+
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		if err := tx.Files().DeleteByIDs(oldIDs); err != nil {
+			return fmt.Errorf("failed to delete file records: %w", err)
+		}
+		if oldImageName == "" {
+			return nil
+		}
+		return saveImageChange(tx, &user, oldImageName)
+	})
+	if err != nil {
 		return dto.UserResponse{}, fmt.Errorf("failed to update user: %w", err), fiber.StatusInternalServerError
 	}
 
-	return dto.UserResponse{
-		ID:       existingUser.ID,
-		Name:     existingUser.Name,
-		Age:      existingUser.Age,
-		Email:    existingUser.Email,
-		Role:     existingUser.Role.Name,
-		ImageUrl: util.BuildImageURL(c, existingUser.ImageName),
-	}, nil, fiber.StatusOK
+	s.files.DeleteBlobs(oldFiles)
+
+	s.recordUser(actor, entity.AuditActionAvatarDeleted, user.ID,
+		map[string]any{"image_name": oldImageName}, map[string]any{"image_name": ""})
+
+	return s.toUserResponse(c, user), nil, fiber.StatusOK
 }
 
-func (s *userService) DeleteUser(id uint, currentUserID uint, currentUserRole string) (error, int) {
-	// Only admins can delete users
-	if currentUserRole != "admin" {
-		return errors.New("permission denied"), fiber.StatusForbidden
+func (s *userService) DeleteOwnAccount(actor dto.Actor, req dto.DeleteAccountRequest) (error, int) {
+	user, err, status := s.findUser(actor.UserID)
+	if err != nil {
+		return err, status
 	}
 
-	// Find the user to get image filename
+	// Confirm with the password so a hijacked session cannot delete the account
+	if !util.CheckPassword(req.Password, user.Password) {
+		return errors.New("password is incorrect"), fiber.StatusUnauthorized
+	}
+
+	return s.deleteUser(user, actor, entity.AuditActionAccountDeleted)
+}
+
+func (s *userService) findUser(id uint) (entity.User, error, int) {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found"), fiber.StatusNotFound
+			return entity.User{}, errors.New("user not found"), fiber.StatusNotFound
 		}
-		return fmt.Errorf("failed to find user: %w", err), fiber.StatusInternalServerError
+		return entity.User{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+	return user, nil, fiber.StatusOK
+}
+
+// usersFor returns the user repository scoped to what the actor may see.
+// Global admins and service accounts with the users:admin scope see every user, everyone
+// else their current organization.
+func (s *userService) usersFor(actor dto.Actor) interfaces.UserRepository {
+	if hasAdminRights(actor) {
+		return s.userRepo
+	}
+	return s.userRepo.InOrganization(actor.OrganizationID)
+}
+
+// findUserFor loads a user visible to the actor; everyone can see their own record
+func (s *userService) findUserFor(actor dto.Actor, id uint) (entity.User, error, int) {
+	if actor.UserID == id {
+		return s.findUser(id)
+	}
+
+	user, err := s.usersFor(actor).FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.User{}, errors.New("user not found"), fiber.StatusNotFound
+		}
+		return entity.User{}, fmt.Errorf("failed to retrieve user: %w", err), fiber.StatusInternalServerError
+	}
+	return user, nil, fiber.StatusOK
+}
+
+// authorizeUser checks the actor may perform the action on a user
+func (s *userService) authorizeUser(actor dto.Actor, action string, user entity.User) (error, int) {
+	resource, err := s.policies.UserResource(user)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve memberships: %w", err), fiber.StatusInternalServerError
 	}
 
-	// Store image name for later deletion
-	imageName := user.ImageName
+	if decision := s.policies.Authorize(actor, action, resource, nil); !decision.Allowed {
+		return errors.New("permission denied"), fiber.StatusForbidden
+	}
+	return nil, fiber.StatusOK
+}
 
-	// Delete file records first (respect foreign key constraints)
-	if err := s.fileRepo.DeleteByUserID(id); err != nil {
-		return fmt.Errorf("failed to delete file records: %w", err), fiber.StatusInternalServerError
+// sendPasswordSetup emails a newly created user a link to choose their password
+func (s *userService) sendPasswordSetup(user entity.User) (error, int) {
+	token, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return errors.New("failed to generate invitation token"), fiber.StatusInternalServerError
 	}
 
-	// Delete the user
-	if err := s.userRepo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err), fiber.StatusInternalServerError
+	verification := entity.VerificationToken{
+		UserID:    user.ID,
+		Purpose:   entity.TokenPurposePasswordSetup,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordSetupTokenExpiry),
+	}
+	if err := s.tokenRepo.Create(&verification); err != nil {
+		return fmt.Errorf("failed to store invitation token: %w", err), fiber.StatusInternalServerError
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nAn account has been created for you. Choose your password within 72 hours:\n\n%s/set-password?token=%s\n",
+		user.Name, s.cfg.AppBaseURL, token,
+	)
+	if err := s.mailer.Send(user.Email, "Your account is ready", body); err != nil {
+		return fmt.Errorf("failed to send invitation email: %w", err), fiber.StatusInternalServerError
+	}
+
+	return nil, fiber.StatusOK
+}
+
+func (s *userService) checkEmailAvailable(email string) (error, int) {
+	_, err := s.userRepo.FindByEmail(email)
+	if err == nil {
+		return errors.New("email already exists"), fiber.StatusConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("failed to check email existence"), fiber.StatusInternalServerError
+	}
+	return nil, fiber.StatusOK
+}
+
+// deleteUser removes a user together with everything that references them
+// and records the deletion as the given audit action
+func (s *userService) deleteUser(user entity.User, actor dto.Actor, action string) (error, int) {
+	id := user.ID
+
+	// The deletion event names the organizations, so their members learn of it
+	memberships, err := s.membershipRepo.FindByUserID(id)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve memberships: %w", err), fiber.StatusInternalServerError
+	}
+	deleted := dto.UserEventData{User: eventUser(user)}
+	for _, membership := range memberships {
+		deleted.OrganizationIDs = append(deleted.OrganizationIDs, membership.OrganizationID)
+	}
+
+	// Store the files for later deletion
+	files := user.Files
+
+	// Delete the user, everything referencing them and the deletion event together,
+	// so a failure leaves the user as they were
+	err = s.unitOfWork.Transaction(func(tx interfaces.Repositories) error {
+		// Delete file records first (respect foreign key constraints)
+		if err := tx.Files().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete file records: %w", err)
+		}
+
+		// Delete login sessions
+		if err := tx.Sessions().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete sessions: %w", err)
+		}
+
+		// Delete password history
+		if err := tx.PasswordHistory().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete password history: %w", err)
+		}
+
+		// Delete verification tokens
+		if err := tx.VerificationTokens().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete verification tokens: %w", err)
+		}
+
+		// Delete temporary role grants
+		if err := tx.RoleGrants().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete role grants: %w", err)
+		}
+
+		// Delete group and organization memberships
+		if err := tx.Groups().DeleteMembershipsByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete group memberships: %w", err)
+		}
+		if err := tx.Memberships().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete memberships: %w", err)
+		}
+
+		// Delete notifications and notification preferences
+		if err := tx.Notifications().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete notifications: %w", err)
+		}
+		if err := tx.NotificationPreferences().DeleteByUserID(id); err != nil {
+			return fmt.Errorf("failed to delete notification preferences: %w", err)
+		}
+
+		// Service accounts the user owns and invitations they sent stop working with them
+		if err := tx.ServiceAccounts().DeleteByOwnerID(id); err != nil {
+			return fmt.Errorf("failed to delete service accounts: %w", err)
+		}
+		if err := tx.Invitations().DeleteByInviterID(id); err != nil {
+			return fmt.Errorf("failed to delete invitations: %w", err)
+		}
+
+		if err := tx.Users().Delete(id); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return stageUserEvent(tx.Outbox(), entity.EventUserDeleted, deleted)
+	})
+	if err != nil {
+		return err, fiber.StatusInternalServerError
 	}
 
-	// Delete the image file
-	_ = util.DeleteFile(imageName)
+	// Delete the image files
+	s.files.DeleteBlobs(files)
+
+	s.recordUser(actor, action, id, userSnapshot(user), nil)
 
 	return nil, fiber.StatusNoContent
 }
+
+// recordUser adds a change of the user to the audit log
+func (s *userService) recordUser(actor dto.Actor, action string, userID uint, before, after any) {
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     action,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   userID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func (s *userService) toUserResponse(c *fiber.Ctx, user entity.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:     user.ID,
+		Name:   user.Name,
+		Age:    user.Age,
+		Email:  user.Email,
+		Role:   user.Role.Name,
+		Status: user.Status,
+		Images: s.files.ImageURLs(c, user),
+		Groups: toGroupRefs(user.GroupMemberships),
+		// Set while a newer image is being processed
+		PendingImageID: pendingImageID(user),
+	}
+}
+
+func toGroupRefs(memberships []entity.GroupMember) []dto.GroupRef {
+	groups := make([]dto.GroupRef, 0, len(memberships))
+	for _, membership := range memberships {
+		groups = append(groups, dto.GroupRef{ID: membership.GroupID, Name: membership.Group.Name})
+	}
+	return groups
+}
+
+// hasAdminRights reports whether the caller may reach user records of every organization.
+// Service accounts need the users:admin scope for that; what they may change is left to
+// the access policy, and they never hand out admin rights.
+func hasAdminRights(actor dto.Actor) bool {
+	if actor.ServiceAccountID != 0 {
+		return slices.Contains(actor.Scopes, entity.ScopeUsersAdmin)
+	}
+	return actor.Role == "admin"
+}
+
+// canAssignRole reports whether the actor may give a user the role. Organization admins,
+// holders of the users:write permission and service accounts only assign the default role.
+func canAssignRole(actor dto.Actor, roleName string) bool {
+	return roleName == defaultRole || isGlobalAdmin(actor)
+}
+
+// isGlobalAdmin reports whether the actor is a human global admin
+func isGlobalAdmin(actor dto.Actor) bool {
+	return actor.Role == "admin" && actor.ServiceAccountID == 0
+}
+
+// hasPermission reports whether the actor holds a permission in their current organization.
+// Organization admins hold every permission.
+func hasPermission(actor dto.Actor, permission string) bool {
+	return isOrganizationAdmin(actor) || slices.Contains(actor.Permissions, permission)
+}
+
+// isOrganizationAdmin reports whether the actor administers their current organization
+func isOrganizationAdmin(actor dto.Actor) bool {
+	return actor.OrganizationID != 0 && actor.OrganizationRole == entity.OrganizationRoleAdmin
+}
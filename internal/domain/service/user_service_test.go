@@ -0,0 +1,112 @@
+package service
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/dto"
+)
+
+func TestHasAdminRights(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor dto.Actor
+		want  bool
+	}{
+		{"admin", dto.Actor{UserID: 1, Role: "admin"}, true},
+		{"user", dto.Actor{UserID: 1, Role: "user"}, false},
+		{"service account", dto.Actor{ServiceAccountID: 1, Role: entity.ServiceAccountRole, Scopes: []string{entity.ScopeUsersRead, entity.ScopeUsersWrite}}, false},
+		{"service account with users:admin", dto.Actor{ServiceAccountID: 1, Role: entity.ServiceAccountRole, Scopes: []string{entity.ScopeUsersRead, entity.ScopeUsersAdmin}}, true},
+	}
+	for _, test := range tests {
+		if got := hasAdminRights(test.actor); got != test.want {
+			t.Errorf("%s: hasAdminRights = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// createUserAs posts a user with the role to CreateUser on behalf of the actor and returns
+// the status. The role is checked before the service touches its dependencies.
+func createUserAs(t *testing.T, actor dto.Actor, roleName string) int {
+	t.Helper()
+
+	service := &userService{}
+	app := fiber.New()
+	app.Post("/users", func(c *fiber.Ctx) error {
+		_, err, status := service.CreateUser(c, actor)
+		if err != nil {
+			return c.Status(status).SendString(err.Error())
+		}
+		return c.SendStatus(status)
+	})
+
+	form := url.Values{
+		"name":      {"Carol"},
+		"email":     {"carol@example.com"},
+		"password":  {"correct horse battery"},
+		"birthdate": {"01.01.1990"},
+		"role_name": {roleName},
+	}
+	req := httptest.NewRequest(fiber.MethodPost, "/users", strings.NewReader(form.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestCreateUserRoleByOrganizationAdmin(t *testing.T) {
+	actor := dto.Actor{UserID: 1, Role: "user", OrganizationID: 1, OrganizationRole: entity.OrganizationRoleAdmin}
+	for _, roleName := range []string{"moderator", "admin"} {
+		if status := createUserAs(t, actor, roleName); status != fiber.StatusForbidden {
+			t.Errorf("organization admin creating a %s: status = %d, want %d", roleName, status, fiber.StatusForbidden)
+		}
+	}
+
+	// The default role passes the check and fails later on the missing image
+	if status := createUserAs(t, actor, defaultRole); status != fiber.StatusBadRequest {
+		t.Errorf("organization admin creating a user: status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+}
+
+func TestCreateUserRoleByGroupPermission(t *testing.T) {
+	actor := dto.Actor{UserID: 1, Role: "user", OrganizationID: 1, OrganizationRole: entity.OrganizationRoleMember, Permissions: []string{entity.ScopeUsersWrite}}
+	if status := createUserAs(t, actor, "moderator"); status != fiber.StatusForbidden {
+		t.Errorf("users:write holder creating a moderator: status = %d, want %d", status, fiber.StatusForbidden)
+	}
+	if status := createUserAs(t, actor, defaultRole); status != fiber.StatusBadRequest {
+		t.Errorf("users:write holder creating a user: status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	admin := dto.Actor{UserID: 1, Role: "admin"}
+	organizationAdmin := dto.Actor{UserID: 2, Role: "user", OrganizationID: 1, OrganizationRole: entity.OrganizationRoleAdmin}
+	writer := dto.Actor{UserID: 3, Role: "user", OrganizationID: 1, OrganizationRole: entity.OrganizationRoleMember, Permissions: []string{entity.ScopeUsersWrite}}
+	account := dto.Actor{ServiceAccountID: 1, Role: entity.ServiceAccountRole, Scopes: []string{entity.ScopeUsersWrite, entity.ScopeUsersAdmin}}
+
+	tests := []struct {
+		name     string
+		actor    dto.Actor
+		roleName string
+		want     bool
+	}{
+		{"admin assigning moderator", admin, "moderator", true},
+		{"admin assigning admin", admin, "admin", true},
+		{"organization admin assigning the default role", organizationAdmin, defaultRole, true},
+		{"organization admin assigning moderator", organizationAdmin, "moderator", false},
+		{"users:write holder assigning moderator", writer, "moderator", false},
+		{"service account assigning moderator", account, "moderator", false},
+	}
+	for _, test := range tests {
+		if got := canAssignRole(test.actor, test.roleName); got != test.want {
+			t.Errorf("%s: canAssignRole = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/pkg/events"
+)
+
+// userStreamSubscriberBuffer is how many events a client may lag behind before it is dropped
+const userStreamSubscriberBuffer = 64
+
+// userStreamEventTypes maps the domain events to the changes sent on the stream
+var userStreamEventTypes = map[string]string{
+	entity.EventUserRegistered: entity.WebhookEventUserCreated,
+	entity.EventUserUpdated:    entity.WebhookEventUserUpdated,
+	entity.EventUserDeleted:    entity.WebhookEventUserDeleted,
+	entity.EventRoleChanged:    entity.WebhookEventUserRoleChanged,
+}
+
+// userStreamEntry is a buffered event with what is needed to decide who may see it
+type userStreamEntry struct {
+	sequence        uint64
+	event           dto.UserStreamEvent
+	userID          uint
+	organizationIDs []uint
+}
+
+type userStreamSubscriber struct {
+	actor  dto.Actor
+	events chan dto.UserStreamEvent
+}
+
+type userStreamService struct {
+	membershipRepo interfaces.MembershipRepository
+	cfg            *config.Config
+	// Prefixes every event ID, so IDs handed out before a restart are recognized as stale
+	instance string
+
+	mu          sync.Mutex
+	sequence    uint64
+	buffer      []userStreamEntry // oldest first, at most cfg.UserStreamBufferSize
+	subscribers map[*userStreamSubscriber]struct{}
+}
+
+func NewUserStreamService(
+	membershipRepo interfaces.MembershipRepository,
+	cfg *config.Config,
+) serviceInterfaces.UserStreamService {
+	return &userStreamService{
+		membershipRepo: membershipRepo,
+		cfg:            cfg,
+		instance:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers:    make(map[*userStreamSubscriber]struct{}),
+	}
+}
+
+func (s *userStreamService) Handle(_ context.Context, event events.Event) error {
+	streamType, ok := userStreamEventTypes[event.Type]
+	if !ok {
+		return nil
+	}
+
+	var data dto.UserEventData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("invalid data of event %s: %w", event.ID, err)
+	}
+
+	// Deleted users have no memberships left, their event names the organizations instead
+	organizationIDs := data.OrganizationIDs
+	if event.Type != entity.EventUserDeleted {
+		memberships, err := s.membershipRepo.FindByUserID(data.User.ID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve memberships: %w", err)
+		}
+		organizationIDs = nil
+		for _, membership := range memberships {
+			organizationIDs = append(organizationIDs, membership.OrganizationID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A retried publish hands over the same event again
+	for _, entry := range s.buffer {
+		if entry.event.EventID == event.ID {
+			return nil
+		}
+	}
+
+	s.sequence++
+	entry := userStreamEntry{
+		sequence: s.sequence,
+		event: dto.UserStreamEvent{
+			ID:         s.instance + "-" + strconv.FormatUint(s.sequence, 10),
+			EventID:    event.ID,
+			Type:       streamType,
+			OccurredAt: event.OccurredAt,
+			Data:       event.Data,
+		},
+		userID:          data.User.ID,
+		organizationIDs: organizationIDs,
+	}
+	s.buffer = append(s.buffer, entry)
+	if overflow := len(s.buffer) - s.cfg.UserStreamBufferSize; overflow > 0 {
+		s.buffer = slices.Delete(s.buffer, 0, overflow)
+	}
+
+	for subscriber := range s.subscribers {
+		if !canSeeUserChange(subscriber.actor, entry) {
+			continue
+		}
+		select {
+		case subscriber.events <- entry.event:
+		default:
+			// The client does not keep up; it reconnects and resumes from the buffer
+			s.drop(subscriber)
+		}
+	}
+	return nil
+}
+
+func (s *userStreamService) Subscribe(actor dto.Actor, lastEventID string) (dto.UserStreamSubscription, error, int) {
+	subscriber := &userStreamSubscriber{
+		actor:  actor,
+		events: make(chan dto.UserStreamEvent, userStreamSubscriberBuffer),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription := dto.UserStreamSubscription{
+		Events: subscriber.events,
+		Cancel: func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.drop(subscriber)
+		},
+	}
+
+	if lastEventID != "" {
+		missed, ok := s.missedSince(lastEventID)
+		if !ok {
+			subscription.Reset = true
+		}
+		for _, entry := range missed {
+			if canSeeUserChange(actor, entry) {
+				subscription.Missed = append(subscription.Missed, entry.event)
+			}
+		}
+	}
+
+	s.subscribers[subscriber] = struct{}{}
+	return subscription, nil, fiber.StatusOK
+}
+
+// missedSince returns the buffered events after the event ID. It reports false when
+// the ID is unknown or events after it have already left the buffer.
+func (s *userStreamService) missedSince(lastEventID string) ([]userStreamEntry, bool) {
+	instance, sequence, ok := strings.Cut(lastEventID, "-")
+	if !ok || instance != s.instance {
+		return nil, false
+	}
+	last, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil || last > s.sequence {
+		return nil, false
+	}
+
+	if len(s.buffer) > 0 && s.buffer[0].sequence > last+1 {
+		return nil, false
+	}
+	for i, entry := range s.buffer {
+		if entry.sequence > last {
+			return s.buffer[i:], true
+		}
+	}
+	return nil, true
+}
+
+// drop removes a subscriber and closes its channel; the caller holds the lock
+func (s *userStreamService) drop(subscriber *userStreamSubscriber) {
+	if _, ok := s.subscribers[subscriber]; !ok {
+		return
+	}
+	delete(s.subscribers, subscriber)
+	close(subscriber.events)
+}
+
+func canSeeUserChange(actor dto.Actor, entry userStreamEntry) bool {
+	return canSeeUser(actor, entry.userID, entry.organizationIDs)
+}
+
+// canSeeUser applies the visibility of the user list: global admins see every user,
+// everyone else the members of their current organization and themselves
+func canSeeUser(actor dto.Actor, userID uint, organizationIDs []uint) bool {
+	if hasAdminRights(actor) || userID == actor.UserID {
+		return true
+	}
+	return actor.OrganizationID != 0 && slices.Contains(organizationIDs, actor.OrganizationID)
+}
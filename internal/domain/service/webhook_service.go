@@ -0,0 +1,609 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository/interfaces"
+	serviceInterfaces "user_crud/internal/domain/service/interfaces"
+	"user_crud/internal/dto"
+	"user_crud/internal/util"
+	"user_crud/pkg/events"
+)
+
+const (
+	defaultWebhookPageSize = 50
+	maxWebhookPageSize     = 500
+	// webhookDispatchBatch is the number of due deliveries picked up per dispatch run
+	webhookDispatchBatch = 100
+	// webhookWorkers is the number of deliveries sent at the same time
+	webhookWorkers = 8
+	// maxWebhookResponseBody is how much of an endpoint's answer is kept in the delivery log
+	maxWebhookResponseBody = 1024
+)
+
+// webhookEventTypes maps the domain events to the webhook events subscribers see
+var webhookEventTypes = map[string]string{
+	entity.EventUserRegistered: entity.WebhookEventUserCreated,
+	entity.EventUserUpdated:    entity.WebhookEventUserUpdated,
+	entity.EventUserDeleted:    entity.WebhookEventUserDeleted,
+	entity.EventRoleChanged:    entity.WebhookEventUserRoleChanged,
+}
+
+type webhookService struct {
+	subscriptionRepo interfaces.WebhookSubscriptionRepository
+	deliveryRepo     interfaces.WebhookDeliveryRepository
+	auditService     serviceInterfaces.AuditService
+	client           *http.Client
+	cfg              *config.Config
+}
+
+func NewWebhookService(
+	subscriptionRepo interfaces.WebhookSubscriptionRepository,
+	deliveryRepo interfaces.WebhookDeliveryRepository,
+	auditService serviceInterfaces.AuditService,
+	cfg *config.Config,
+) serviceInterfaces.WebhookService {
+	return &webhookService{
+		subscriptionRepo: subscriptionRepo,
+		deliveryRepo:     deliveryRepo,
+		auditService:     auditService,
+		client: &http.Client{
+			Timeout: cfg.WebhookTimeout,
+			// A redirect is reported as the endpoint's answer instead of being followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}
+}
+
+func (s *webhookService) Name() string {
+	return "webhooks"
+}
+
+func (s *webhookService) Publish(_ context.Context, event events.Event) error {
+	eventType, ok := webhookEventTypes[event.Type]
+	if !ok {
+		return nil
+	}
+
+	subscriptions, err := s.subscriptionRepo.FindActive()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve webhooks: %w", err)
+	}
+
+	payload, err := json.Marshal(dto.WebhookPayload{
+		ID:        event.ID,
+		Type:      eventType,
+		CreatedAt: event.OccurredAt.UTC(),
+		Data:      event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event %s: %w", event.ID, err)
+	}
+
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		if !subscribesTo(subscription, eventType) {
+			continue
+		}
+
+		// Events published again after a failed dispatch run are not queued twice
+		delivery := entity.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         entity.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+		}
+		if _, err := s.deliveryRepo.Enqueue(&delivery); err != nil {
+			return fmt.Errorf("failed to queue %s for webhook:%d: %w", event.ID, subscription.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *webhookService) CreateSubscription(req dto.CreateWebhookRequest, actor dto.Actor) (dto.WebhookSecretResponse, error, int) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return dto.WebhookSecretResponse{}, errors.New("name is required"), fiber.StatusBadRequest
+	}
+
+	endpoint, err := validateWebhookURL(req.URL)
+	if err != nil {
+		return dto.WebhookSecretResponse{}, err, fiber.StatusBadRequest
+	}
+
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return dto.WebhookSecretResponse{}, err, fiber.StatusBadRequest
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return dto.WebhookSecretResponse{}, errors.New("failed to generate secret"), fiber.StatusInternalServerError
+	}
+
+	subscription := entity.WebhookSubscription{
+		Name:        name,
+		URL:         endpoint,
+		Secret:      secret,
+		Events:      strings.Join(events, " "),
+		Active:      true,
+		CreatedByID: actor.UserID,
+	}
+	if err := s.subscriptionRepo.Create(&subscription); err != nil {
+		return dto.WebhookSecretResponse{}, fmt.Errorf("failed to create webhook: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionWebhookCreated,
+		TargetType: entity.AuditTargetWebhook,
+		TargetID:   subscription.ID,
+		After:      webhookSnapshot(subscription),
+	})
+
+	return dto.WebhookSecretResponse{
+		WebhookResponse: toWebhookResponse(subscription),
+		Secret:          secret,
+	}, nil, fiber.StatusCreated
+}
+
+func (s *webhookService) GetSubscriptions() ([]dto.WebhookResponse, error, int) {
+	subscriptions, err := s.subscriptionRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhooks: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.WebhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		response = append(response, toWebhookResponse(subscription))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *webhookService) GetSubscription(id uint) (dto.WebhookResponse, error, int) {
+	subscription, err, status := s.findSubscription(id)
+	if err != nil {
+		return dto.WebhookResponse{}, err, status
+	}
+
+	return toWebhookResponse(subscription), nil, fiber.StatusOK
+}
+
+func (s *webhookService) UpdateSubscription(id uint, req dto.UpdateWebhookRequest, actor dto.Actor) (dto.WebhookResponse, error, int) {
+	subscription, err, status := s.findSubscription(id)
+	if err != nil {
+		return dto.WebhookResponse{}, err, status
+	}
+	before := webhookSnapshot(subscription)
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return dto.WebhookResponse{}, errors.New("name cannot be empty"), fiber.StatusBadRequest
+		}
+		subscription.Name = name
+	}
+
+	if req.URL != nil {
+		endpoint, err := validateWebhookURL(*req.URL)
+		if err != nil {
+			return dto.WebhookResponse{}, err, fiber.StatusBadRequest
+		}
+		subscription.URL = endpoint
+	}
+
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(*req.Events)
+		if err != nil {
+			return dto.WebhookResponse{}, err, fiber.StatusBadRequest
+		}
+		subscription.Events = strings.Join(events, " ")
+	}
+
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+
+	if err := s.subscriptionRepo.Update(&subscription); err != nil {
+		return dto.WebhookResponse{}, fmt.Errorf("failed to update webhook: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionWebhookUpdated,
+		TargetType: entity.AuditTargetWebhook,
+		TargetID:   subscription.ID,
+		Before:     before,
+		After:      webhookSnapshot(subscription),
+	})
+
+	return toWebhookResponse(subscription), nil, fiber.StatusOK
+}
+
+func (s *webhookService) RotateSecret(id uint, actor dto.Actor) (dto.WebhookSecretResponse, error, int) {
+	subscription, err, status := s.findSubscription(id)
+	if err != nil {
+		return dto.WebhookSecretResponse{}, err, status
+	}
+
+	// Deliveries still queued are signed with the new secret when they are sent
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return dto.WebhookSecretResponse{}, errors.New("failed to generate secret"), fiber.StatusInternalServerError
+	}
+
+	subscription.Secret = secret
+	if err := s.subscriptionRepo.Update(&subscription); err != nil {
+		return dto.WebhookSecretResponse{}, fmt.Errorf("failed to update webhook: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionWebhookRotated,
+		TargetType: entity.AuditTargetWebhook,
+		TargetID:   subscription.ID,
+	})
+
+	return dto.WebhookSecretResponse{
+		WebhookResponse: toWebhookResponse(subscription),
+		Secret:          secret,
+	}, nil, fiber.StatusOK
+}
+
+func (s *webhookService) DeleteSubscription(id uint, actor dto.Actor) (error, int) {
+	subscription, err, status := s.findSubscription(id)
+	if err != nil {
+		return err, status
+	}
+
+	if err := s.deliveryRepo.DeleteBySubscriptionID(subscription.ID); err != nil {
+		return fmt.Errorf("failed to delete deliveries: %w", err), fiber.StatusInternalServerError
+	}
+
+	if err := s.subscriptionRepo.Delete(subscription.ID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionWebhookDeleted,
+		TargetType: entity.AuditTargetWebhook,
+		TargetID:   subscription.ID,
+		Before:     webhookSnapshot(subscription),
+	})
+
+	return nil, fiber.StatusNoContent
+}
+
+func (s *webhookService) GetDeliveries(id uint, status string, limit, offset int) ([]dto.WebhookDeliveryResponse, error, int) {
+	if _, err, code := s.findSubscription(id); err != nil {
+		return nil, err, code
+	}
+
+	switch status {
+	case "", entity.WebhookDeliveryPending, entity.WebhookDeliverySucceeded, entity.WebhookDeliveryDead:
+	default:
+		return nil, fmt.Errorf("unknown status %q", status), fiber.StatusBadRequest
+	}
+
+	if limit <= 0 {
+		limit = defaultWebhookPageSize
+	}
+	if limit > maxWebhookPageSize {
+		return nil, fmt.Errorf("limit must be at most %d", maxWebhookPageSize), fiber.StatusBadRequest
+	}
+	if offset < 0 {
+		return nil, errors.New("offset must not be negative"), fiber.StatusBadRequest
+	}
+
+	deliveries, err := s.deliveryRepo.FindBySubscriptionID(id, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve deliveries: %w", err), fiber.StatusInternalServerError
+	}
+
+	response := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, toWebhookDeliveryResponse(delivery))
+	}
+
+	return response, nil, fiber.StatusOK
+}
+
+func (s *webhookService) Redeliver(id, deliveryID uint, actor dto.Actor) (dto.WebhookDeliveryResponse, error, int) {
+	subscription, err, status := s.findSubscription(id)
+	if err != nil {
+		return dto.WebhookDeliveryResponse{}, err, status
+	}
+
+	original, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil || original.SubscriptionID != subscription.ID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.WebhookDeliveryResponse{}, errors.New("delivery not found"), fiber.StatusNotFound
+		}
+		return dto.WebhookDeliveryResponse{}, fmt.Errorf("failed to retrieve delivery: %w", err), fiber.StatusInternalServerError
+	}
+
+	// The event keeps its ID so receivers can recognize a repeated event
+	delivery := entity.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         entity.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOfID: &original.ID,
+	}
+	if err := s.deliveryRepo.Create(&delivery); err != nil {
+		return dto.WebhookDeliveryResponse{}, fmt.Errorf("failed to queue delivery: %w", err), fiber.StatusInternalServerError
+	}
+
+	s.auditService.Record(dto.AuditRecord{
+		Actor:      actor,
+		Action:     entity.AuditActionWebhookRedelivered,
+		TargetType: entity.AuditTargetWebhook,
+		TargetID:   subscription.ID,
+		Details:    map[string]any{"delivery_id": original.ID, "event_id": original.EventID},
+	})
+
+	return toWebhookDeliveryResponse(delivery), nil, fiber.StatusAccepted
+}
+
+func (s *webhookService) DeliverDue() (int, error) {
+	deliveries, err := s.deliveryRepo.FindDue(time.Now(), webhookDispatchBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve due deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, webhookWorkers)
+	attempted := 0
+	for i := range deliveries {
+		delivery := deliveries[i]
+
+		// A delivery is claimed only once a worker is free to send it. The lease outlasts
+		// the request timeout, so other dispatchers skip the delivery unless this attempt
+		// was cut short before recording its outcome.
+		workers <- struct{}{}
+		now := time.Now()
+		claimed, err := s.deliveryRepo.Claim(&delivery, now, now.Add(s.cfg.WebhookTimeout+time.Minute))
+		if err != nil || !claimed {
+			<-workers
+			if err != nil {
+				wg.Wait()
+				return attempted, fmt.Errorf("failed to claim delivery %d: %w", delivery.ID, err)
+			}
+			continue
+		}
+
+		attempted++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			s.attempt(&delivery)
+		}()
+	}
+	wg.Wait()
+
+	return attempted, nil
+}
+
+func (s *webhookService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.DeliverDue(); err != nil {
+			log.Printf("webhook dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attempt posts the delivery once and schedules a retry or dead-letters it on failure
+func (s *webhookService) attempt(delivery *entity.WebhookDelivery) {
+	subscription := delivery.Subscription
+	leaseUntil := delivery.NextAttemptAt
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	if subscription.Active {
+		delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = s.post(subscription, *delivery, now)
+	} else {
+		delivery.Error = "webhook is disabled"
+	}
+
+	switch {
+	case delivery.Error == "":
+		delivery.Status = entity.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case !subscription.Active || delivery.Attempts >= s.cfg.WebhookMaxAttempts:
+		delivery.Status = entity.WebhookDeliveryDead
+		log.Printf("webhook delivery %d of %s to webhook:%d dead-lettered after %d attempts: %s",
+			delivery.ID, delivery.EventID, subscription.ID, delivery.Attempts, delivery.Error)
+	default:
+		delivery.NextAttemptAt = now.Add(s.retryDelay(delivery.Attempts))
+	}
+
+	finished, err := s.deliveryRepo.FinishAttempt(delivery, leaseUntil)
+	if err != nil {
+		log.Printf("failed to record attempt of webhook delivery %d: %v", delivery.ID, err)
+	} else if !finished {
+		log.Printf("attempt of webhook delivery %d outlasted its lease and was superseded", delivery.ID)
+	}
+}
+
+// post sends the payload and returns the response status, the start of the response body
+// and, unless the endpoint accepted the delivery, what went wrong
+func (s *webhookService) post(subscription entity.WebhookSubscription, delivery entity.WebhookDelivery, now time.Time) (int, string, string) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err.Error()
+	}
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderUserAgent, "user_crud-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.EventID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(util.WebhookSignatureHeader, util.SignWebhookPayload(subscription.Secret, now, payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", truncate(err.Error(), 1024)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Sprintf("endpoint answered with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), ""
+}
+
+// retryDelay doubles the wait with every failed attempt, starting at WebhookRetryBaseDelay
+func (s *webhookService) retryDelay(attempts int) time.Duration {
+	delay := s.cfg.WebhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= s.cfg.WebhookRetryMaxDelay {
+			return s.cfg.WebhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+func (s *webhookService) findSubscription(id uint) (entity.WebhookSubscription, error, int) {
+	subscription, err := s.subscriptionRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.WebhookSubscription{}, errors.New("webhook not found"), fiber.StatusNotFound
+		}
+		return entity.WebhookSubscription{}, fmt.Errorf("failed to retrieve webhook: %w", err), fiber.StatusInternalServerError
+	}
+	return subscription, nil, fiber.StatusOK
+}
+
+// subscribesTo reports whether the subscription receives events of the type
+func subscribesTo(subscription entity.WebhookSubscription, eventType string) bool {
+	events := strings.Fields(subscription.Events)
+	return len(events) == 0 || slices.Contains(events, eventType)
+}
+
+// validateWebhookURL accepts absolute http and https URLs
+func validateWebhookURL(raw string) (string, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+	if endpoint.User != nil {
+		return "", errors.New("url must not contain credentials")
+	}
+	return endpoint.String(), nil
+}
+
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	var result []string
+	for _, event := range events {
+		if !slices.Contains(entity.WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !slices.Contains(result, event) {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + secret, nil
+}
+
+func toWebhookResponse(subscription entity.WebhookSubscription) dto.WebhookResponse {
+	events := strings.Fields(subscription.Events)
+	if len(events) == 0 {
+		events = entity.WebhookEvents
+	}
+
+	return dto.WebhookResponse{
+		ID:          subscription.ID,
+		Name:        subscription.Name,
+		URL:         subscription.URL,
+		Events:      events,
+		Active:      subscription.Active,
+		CreatedByID: subscription.CreatedByID,
+		CreatedAt:   subscription.CreatedAt,
+		UpdatedAt:   subscription.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery entity.WebhookDelivery) dto.WebhookDeliveryResponse {
+	response := dto.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		Error:          delivery.Error,
+		DeliveredAt:    delivery.DeliveredAt,
+		RedeliveryOfID: delivery.RedeliveryOfID,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == entity.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+	return response
+}
+
+// webhookSnapshot is the part of a subscription recorded in audit log diffs; the secret is left out
+func webhookSnapshot(subscription entity.WebhookSubscription) map[string]any {
+	return map[string]any{
+		"name":   subscription.Name,
+		"url":    subscription.URL,
+		"events": subscription.Events,
+		"active": subscription.Active,
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"user_crud/internal/config"
+	"user_crud/internal/domain/entity"
+	"user_crud/internal/domain/repository"
+)
+
+func TestAttemptOutlastingItsLeaseIsSuperseded(t *testing.T) {
+	db := newTestDB(t)
+	deliveries := repository.NewWebhookDeliveryRepository(db)
+	service := NewWebhookService(
+		repository.NewWebhookSubscriptionRepository(db),
+		deliveries,
+		NewAuditService(repository.NewAuditEventRepository(db)),
+		&config.Config{WebhookTimeout: time.Second, WebhookMaxAttempts: 3},
+	).(*webhookService)
+
+	// A disabled webhook dead-letters the delivery on its next attempt without sending it
+	subscription := entity.WebhookSubscription{Name: "crm", URL: "http://127.0.0.1:1/hook", Secret: "secret", CreatedByID: 1}
+	if err := db.Create(&subscription).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&subscription).Update("active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	queued := entity.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        "evt-1",
+		EventType:      "user.created",
+		Payload:        "{}",
+		Status:         entity.WebhookDeliveryPending,
+		NextAttemptAt:  now.Add(-time.Second),
+	}
+	if err := deliveries.Create(&queued); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := deliveries.FindDue(now, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("FindDue = %d deliveries, %v; want 1", len(due), err)
+	}
+	first, second := due[0], due[0]
+
+	// The first lease runs out before its attempt is recorded, and another worker claims the delivery
+	if claimed, err := deliveries.Claim(&first, now, now.Add(time.Minute)); err != nil || !claimed {
+		t.Fatalf("first claim = %v, %v", claimed, err)
+	}
+	later := now.Add(2 * time.Minute)
+	if claimed, err := deliveries.Claim(&second, later, later.Add(time.Minute)); err != nil || !claimed {
+		t.Fatalf("second claim = %v, %v", claimed, err)
+	}
+
+	service.attempt(&first)
+	stored, err := deliveries.FindByID(queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != entity.WebhookDeliveryPending || stored.Attempts != 0 {
+		t.Fatalf("after the superseded attempt: status %q, %d attempts; want pending, 0", stored.Status, stored.Attempts)
+	}
+
+	service.attempt(&second)
+	if stored, err = deliveries.FindByID(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != entity.WebhookDeliveryDead || stored.Attempts != 1 {
+		t.Errorf("after the current attempt: status %q, %d attempts; want dead, 1", stored.Status, stored.Attempts)
+	}
+}
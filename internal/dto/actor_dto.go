@@ -0,0 +1,19 @@
+package dto
+
+// Actor identifies the authenticated principal a service acts for
+type Actor struct {
+	UserID           uint   // 0 for service accounts
+	ServiceAccountID uint   // 0 for users
+	Role             string // global role
+	OrganizationID   uint   // current organization, 0 when the user has none
+	OrganizationRole string // role within the current organization
+	// Permissions granted through groups in the current organization, only
+	// populated on routes using middleware.LoadPermissions
+	Permissions []string
+	// Scopes granted to a service account, nil for users
+	Scopes []string
+	// Admin impersonating the user, 0 otherwise
+	ImpersonatorID uint
+	// Device and request the actor acts from
+	Client ClientInfo
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditRecord describes an event to append to the audit log
+type AuditRecord struct {
+	// Principal performing the action and the request it came in; without a user or
+	// service account the event is recorded as anonymous, or as system when there is no request
+	Actor      Actor
+	Action     string
+	TargetType string
+	TargetID   uint
+	// Snapshots of the target before and after the action; only fields that differ are stored
+	Before any
+	After  any
+	// Further facts about the event, such as a reason
+	Details map[string]any
+}
+
+// AuditEventQuery filters audit events; zero values match everything
+type AuditEventQuery struct {
+	ActorType  string
+	ActorID    uint
+	Action     string // exact action or a prefix ending in ".", e.g. "user."
+	TargetType string
+	TargetID   uint
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditChange holds the old and new value of a changed field
+type AuditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type AuditEventResponse struct {
+	ID             uint            `json:"id"`
+	ActorType      string          `json:"actor_type"`
+	ActorID        uint            `json:"actor_id"`
+	ImpersonatorID *uint           `json:"impersonator_id,omitempty"`
+	Action         string          `json:"action"`
+	TargetType     string          `json:"target_type,omitempty"`
+	TargetID       uint            `json:"target_id,omitempty"`
+	Changes        json.RawMessage `json:"changes,omitempty"`
+	Details        json.RawMessage `json:"details,omitempty"`
+	IPAddress      string          `json:"ip_address,omitempty"`
+	UserAgent      string          `json:"user_agent,omitempty"`
+	RequestID      string          `json:"request_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+}
+
+type AuditEventListResponse struct {
+	Events []AuditEventResponse `json:"events"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// AuditVerifyResponse reports whether the hash chain of the audit log is intact.
+// Comparing LastHash with a previously noted value also reveals removed trailing events.
+type AuditVerifyResponse struct {
+	Valid      bool   `json:"valid"`
+	Checked    int    `json:"checked"`
+	BrokenAtID uint   `json:"broken_at_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	LastHash   string `json:"last_hash"`
+}
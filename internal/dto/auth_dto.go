@@ -14,7 +14,12 @@ type RegisterRequest struct {
 
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	TokenType    string `json:"token_type"`
-	ExpiresIn    int64  `json:"expires_in"` // seconds
+	ExpiresIn    int64  `json:"expires_in"`      // seconds
+	Scope        string `json:"scope,omitempty"` // service account tokens only
+}
+
+type UnlockRequest struct {
+	IPAddress string `json:"ip_address"` // optional, also clears the lockout of this address
 }
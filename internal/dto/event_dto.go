@@ -0,0 +1,23 @@
+package dto
+
+// EventUser describes the user a domain event is about
+type EventUser struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Age    int    `json:"age"`
+	Role   string `json:"role"`
+	Status string `json:"status"`
+	Image  string `json:"image,omitempty"` // stored image name
+}
+
+// UserEventData is the data of the user domain events
+type UserEventData struct {
+	User EventUser `json:"user"`
+	// Role before a user.role_changed event
+	PreviousRole string `json:"previous_role,omitempty"`
+	// Image before a user.image_replaced event; User.Image is empty when the image was removed
+	PreviousImage string `json:"previous_image,omitempty"`
+	// Organizations the user belonged to before a user.deleted event
+	OrganizationIDs []uint `json:"organization_ids,omitempty"`
+}
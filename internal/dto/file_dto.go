@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"io"
+	"time"
+)
+
+// FileResponse describes a file of a user and reports its processing status
+type FileResponse struct {
+	ID         uint   `json:"id"`
+	Purpose    string `json:"purpose"` // avatar or document
+	Name       string `json:"name"`    // as uploaded
+	MimeType   string `json:"mime_type,omitempty"`
+	Size       int64  `json:"size"`
+	Checksum   string `json:"checksum,omitempty"` // hex encoded SHA-256 of the stored content
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	UploaderID *uint  `json:"uploader_id"`
+	// UploaderServiceAccountID is set instead of UploaderID for uploads of service accounts
+	UploaderServiceAccountID *uint  `json:"uploader_service_account_id,omitempty"`
+	Status                   string `json:"status"` // pending, processing, ready or failed
+	Attempts                 int    `json:"attempts"`
+	Error                    string `json:"error,omitempty"` // of the last failed attempt
+	// Images maps the thumbnails and ImageOriginal to their URLs like UserResponse.Images,
+	// once an avatar image is ready
+	Images    map[string]string `json:"images,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// FileDownload is the content of a file with what to send along with it. The caller
+// closes Body.
+type FileDownload struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Body        io.ReadCloser
+}
@@ -0,0 +1,39 @@
+package dto
+
+import "time"
+
+// GroupRequest creates or replaces a group
+type GroupRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	ParentID    *uint    `json:"parent_id"` // nil for a top-level group
+	Permissions []string `json:"permissions"`
+	// OrganizationID lets global admins create groups outside their current organization
+	OrganizationID uint `json:"organization_id"`
+}
+
+type AddGroupMemberRequest struct {
+	UserID uint `json:"user_id" validate:"required"`
+}
+
+type GroupResponse struct {
+	ID             uint      `json:"id"`
+	OrganizationID uint      `json:"organization_id"`
+	ParentID       *uint     `json:"parent_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Permissions    []string  `json:"permissions"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type GroupMemberResponse struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// GroupRef identifies a group a user is a direct member of
+type GroupRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
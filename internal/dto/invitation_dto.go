@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+type CreateInvitationRequest struct {
+	Email          string `json:"email" validate:"required,email"`
+	RoleName       string `json:"role_name" validate:"required,oneof=admin moderator user"`
+	ExpiresInHours int    `json:"expires_in_hours"` // defaults to 7 days
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type InvitationResponse struct {
+	ID           uint       `json:"id"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	InviterID    uint       `json:"inviter_id"`
+	InviterEmail string     `json:"inviter_email"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification describes a notification to deliver to a user
+type Notification struct {
+	Type  string
+	Title string
+	Body  string
+	// Details shown with the notification, such as the device of a login
+	Data map[string]any
+}
+
+// NotificationQuery selects a page of the caller's notifications
+type NotificationQuery struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+type NotificationResponse struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Read      bool            `json:"read"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	Total         int64                  `json:"total"` // matching the query
+	Limit         int                    `json:"limit"`
+	Offset        int                    `json:"offset"`
+}
+
+// NotificationPreferenceResponse tells where notifications of a type are delivered
+type NotificationPreferenceResponse struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences"`
+}
+
+// NotificationPreferenceRequest changes the channels of a type; omitted channels keep their setting
+type NotificationPreferenceRequest struct {
+	Type  string `json:"type"`
+	InApp *bool  `json:"in_app"`
+	Email *bool  `json:"email"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+import "time"
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+	Slug string `json:"slug"` // derived from the name when empty
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type OrganizationResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddMemberRequest struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Role   string `json:"role" validate:"omitempty,oneof=admin member"` // defaults to member
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// MoveMemberRequest moves a user from one organization to another
+type MoveMemberRequest struct {
+	OrganizationID uint   `json:"organization_id" validate:"required"`
+	Role           string `json:"role" validate:"omitempty,oneof=admin member"` // defaults to member
+}
+
+type SwitchOrganizationRequest struct {
+	OrganizationID uint `json:"organization_id" validate:"required"`
+}
+
+type MembershipResponse struct {
+	OrganizationID   uint      `json:"organization_id"`
+	OrganizationName string    `json:"organization_name,omitempty"`
+	UserID           uint      `json:"user_id"`
+	UserName         string    `json:"user_name,omitempty"`
+	UserEmail        string    `json:"user_email,omitempty"`
+	Role             string    `json:"role"`
+	Current          bool      `json:"current,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
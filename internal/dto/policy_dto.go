@@ -0,0 +1,21 @@
+package dto
+
+import "user_crud/pkg/policy"
+
+// PolicyExplainRequest describes a request to evaluate against the access policy.
+// The subject defaults to the caller; given a user_id it is built from that user in
+// organization_id, or their first organization. A resource with type "user" and an id
+// gets that user's attributes. Attributes given explicitly override the loaded ones.
+type PolicyExplainRequest struct {
+	Action         string            `json:"action" validate:"required"`
+	UserID         uint              `json:"user_id"`
+	OrganizationID uint              `json:"organization_id"`
+	Subject        policy.Attributes `json:"subject"`
+	Resource       policy.Attributes `json:"resource"`
+	Environment    policy.Attributes `json:"environment"`
+}
+
+type PolicyExplainResponse struct {
+	Request  policy.Request  `json:"request"`
+	Decision policy.Decision `json:"decision"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Message types pushed on the WebSocket channel
+const (
+	RealtimeTypePresenceSnapshot = "presence.snapshot" // the visible users online when the client connects
+	RealtimeTypePresence         = "presence"          // a visible user came online or went offline
+	RealtimeTypeNotification     = "notification"
+)
+
+// RealtimeMessage is a message pushed to WebSocket clients
+type RealtimeMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type PresenceResponse struct {
+	UserID uint `json:"user_id"`
+	Online bool `json:"online"`
+	// When the user was last connected; unknown for users not seen since this instance started
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// RealtimeConnection is one WebSocket client's view of the channel
+type RealtimeConnection struct {
+	// Messages for the client; closed when it falls too far behind and should reconnect
+	Messages <-chan RealtimeMessage
+	// Close ends the connection and updates the user's presence
+	Close func()
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// RegistrationPendingResponse is returned instead of tokens when new accounts need approval
+type RegistrationPendingResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type RejectRegistrationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type RegistrationResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// RoleGrantRequest asks for a temporary role. The time window is given either by ends_at or by
+// duration_minutes; windows that have already started when approved begin at approval instead.
+type RoleGrantRequest struct {
+	UserID          uint       `json:"user_id"` // admins may grant a role to another user directly; defaults to the caller
+	RoleName        string     `json:"role_name" validate:"required"`
+	Reason          string     `json:"reason" validate:"required"`
+	StartsAt        *time.Time `json:"starts_at"` // defaults to now
+	EndsAt          *time.Time `json:"ends_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+type RoleGrantDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+type RoleGrantResponse struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"user_id"`
+	UserEmail     string     `json:"user_email"`
+	Role          string     `json:"role"`
+	Reason        string     `json:"reason"`
+	Status        string     `json:"status"`
+	Active        bool       `json:"active"`
+	RequestedByID uint       `json:"requested_by_id"`
+	DecidedByID   *uint      `json:"decided_by_id"`
+	DecisionNote  string     `json:"decision_note,omitempty"`
+	StartsAt      time.Time  `json:"starts_at"`
+	EndsAt        time.Time  `json:"ends_at"`
+	DecidedAt     *time.Time `json:"decided_at"`
+	RevokedByID   *uint      `json:"revoked_by_id"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	ExpiredAt     *time.Time `json:"expired_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
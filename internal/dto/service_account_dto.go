@@ -0,0 +1,51 @@
+package dto
+
+import "time"
+
+type CreateServiceAccountRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes" validate:"required"`
+	PublicKey   string   `json:"public_key"` // optional PEM key for JWT assertions
+	OwnerID     uint     `json:"owner_id"`   // defaults to the calling admin
+}
+
+type UpdateServiceAccountRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Scopes      *[]string `json:"scopes"`
+	PublicKey   *string   `json:"public_key"`
+	OwnerID     *uint     `json:"owner_id"`
+	Disabled    *bool     `json:"disabled"`
+}
+
+type ServiceAccountResponse struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	ClientID     string     `json:"client_id"`
+	Scopes       []string   `json:"scopes"`
+	HasPublicKey bool       `json:"has_public_key"`
+	OwnerID      uint       `json:"owner_id"`
+	OwnerEmail   string     `json:"owner_email"`
+	Disabled     bool       `json:"disabled"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ServiceAccountCredentialsResponse is returned only when a secret is issued;
+// the plain client secret cannot be retrieved again afterwards
+type ServiceAccountCredentialsResponse struct {
+	ServiceAccountResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+// ServiceTokenRequest follows the OAuth 2.0 token endpoint parameters for the
+// client_credentials and jwt-bearer grants
+type ServiceTokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type" validate:"required"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Assertion    string `json:"assertion" form:"assertion"`
+	Scope        string `json:"scope" form:"scope"` // space separated subset of the account's scopes
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// ClientInfo describes the device a request originates from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+	RequestID string
+}
+
+type SessionResponse struct {
+	ID             uint      `json:"id"`
+	UserAgent      string    `json:"user_agent"`
+	IPAddress      string    `json:"ip_address"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Current        bool      `json:"current"`
+	ImpersonatorID *uint     `json:"impersonator_id,omitempty"` // admin who impersonated the user in this session
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// ImpersonationResponse carries a short-lived access token acting as the user; there is no refresh token
+type ImpersonationResponse struct {
+	AccessToken    string    `json:"access_token"`
+	TokenType      string    `json:"token_type"`
+	ExpiresIn      int64     `json:"expires_in"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UserID         uint      `json:"user_id"`
+	ImpersonatorID uint      `json:"impersonator_id"`
+	SessionID      uint      `json:"session_id"`
+}
@@ -3,17 +3,55 @@ package dto
 type CreateUserRequest struct {
 	Name      string `form:"name" validate:"required"`
 	Email     string `form:"email" validate:"required,email"`
-	Password  string `form:"password" validate:"required,min=6"`
+	Password  string `form:"password" validate:"required_without=SendInvite"`
 	Birthdate string `form:"birthdate" validate:"required"`
 	RoleName  string `form:"role_name" validate:"required,oneof=admin moderator user"`
+	// SendInvite emails the user a link to choose their own password instead
+	SendInvite bool `form:"send_invite"`
+	// OrganizationID lets global admins pick the organization, the default one otherwise.
+	// Users created by organization admins always join the admin's organization.
+	OrganizationID uint `form:"organization_id"`
 	// Image file is handled separately in the controller
 }
 
+// ImageOriginal is the key of the uploaded image in UserResponse.Images
+const ImageOriginal = "original"
+
 type UserResponse struct {
-	ID       uint   `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Age      int    `json:"age"`
-	ImageUrl string `json:"image_url"`
-	Role     string `json:"role"`
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+	// Images maps the edge length of square thumbnails, e.g. "64", and ImageOriginal to
+	// their URLs; empty without an image
+	Images map[string]string `json:"images"`
+	// PendingImageID is the latest uploaded image still being processed; poll it under
+	// /api/users/:id/files/:fileId. Images switch to it once it is ready.
+	PendingImageID uint       `json:"pending_image_id,omitempty"`
+	Role           string     `json:"role"`
+	Status         string     `json:"status"`
+	Groups         []GroupRef `json:"groups"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" validate:"required,email"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type SetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserStreamEvent is a user change sent on the Server-Sent Events stream
+type UserStreamEvent struct {
+	// Stream position, sent as the SSE id and returned by clients in Last-Event-ID
+	ID         string          `json:"-"`
+	EventID    string          `json:"event_id"` // domain event ID, the same on every delivery
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// UserStreamSubscription is one client's view of the user change stream
+type UserStreamSubscription struct {
+	// Buffered events after the client's Last-Event-ID
+	Missed []UserStreamEvent
+	// The client missed events that are no longer buffered and should reload the users
+	Reset bool
+	// Live events; closed when the client falls too far behind and should reconnect
+	Events <-chan UserStreamEvent
+	// Cancel ends the subscription
+	Cancel func()
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswordChecker tells whether a password appears in a known breach corpus
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+type rangeDirectoryChecker struct {
+	dir string
+}
+
+// NewRangeDirectoryChecker checks passwords against a local copy of the
+// "Pwned Passwords" k-anonymity range dataset. The directory holds one file
+// per 5 character SHA-1 prefix (e.g. "21BD1" or "21BD1.txt") whose lines are
+// "<35 character suffix>:<count>", so only the matching range is ever read.
+func NewRangeDirectoryChecker(dir string) BreachedPasswordChecker {
+	return &rangeDirectoryChecker{dir: dir}
+}
+
+func (c *rangeDirectoryChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	file, err := c.openRange(prefix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+func (c *rangeDirectoryChecker) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(c.dir, prefix))
+	}
+	return file, err
+}
+
+type noopBreachedPasswordChecker struct{}
+
+// NewNoopBreachedPasswordChecker is used when no breach dataset is configured
+func NewNoopBreachedPasswordChecker() BreachedPasswordChecker {
+	return noopBreachedPasswordChecker{}
+}
+
+func (noopBreachedPasswordChecker) IsBreached(string) (bool, error) {
+	return false, nil
+}
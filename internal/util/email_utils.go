@@ -0,0 +1,90 @@
+package util
+
+import (
+	"bufio"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// IsValidEmail reports whether email is a bare address such as "name@example.com"
+func IsValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email, ".")
+}
+
+// NormalizeEmail lowercases an address and drops any plus-addressing tag, so
+// "Jane.Doe+news@Example.com" and "jane.doe@example.com" compare equal
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+
+	if tag := strings.IndexByte(local, '+'); tag > 0 {
+		local = local[:tag]
+	}
+	return local + "@" + domain
+}
+
+// EmailDomain returns the lowercased domain part of an address
+func EmailDomain(email string) string {
+	_, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	return domain
+}
+
+// DomainList is a set of lowercased domain names
+type DomainList map[string]struct{}
+
+// NewDomainList builds a list from domain names, ignoring blanks
+func NewDomainList(domains []string) DomainList {
+	list := make(DomainList)
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			list[domain] = struct{}{}
+		}
+	}
+	return list
+}
+
+// LoadDomainList reads one domain per line; blank lines and lines starting with "#" are skipped
+func LoadDomainList(path string) (DomainList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewDomainList(domains), nil
+}
+
+// Contains reports whether domain or one of its parent domains is in the list
+func (l DomainList) Contains(domain string) bool {
+	domain = strings.ToLower(domain)
+	for domain != "" {
+		if _, ok := l[domain]; ok {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
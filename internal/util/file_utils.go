@@ -1,45 +1,229 @@
 package util
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif" // dimensions of GIF attachments
+	"io"
+	"mime"
 	"mime/multipart"
-	"os"
-	"path/filepath"
-	"time"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
-)
 
-const (
-	ImageDir = "public/images"
+	"user_crud/pkg/blobstore"
 )
 
-// SaveUploadedFile saves an uploaded file to the server's file system
-// Changed the parameter type to multipart.FileHeader instead of fiber.FormFile
-func SaveUploadedFile(c *fiber.Ctx, file *multipart.FileHeader) (string, error) {
-	// Create unique filename
-	imageName := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
+// ErrInvalidFile is wrapped by every error about the content of an uploaded attachment
+var ErrInvalidFile = errors.New("invalid file")
+
+// StoredFile is a file written to a blob store
+type StoredFile struct {
+	Name        string
+	Variant     int // edge length of a square thumbnail, 0 for the uploaded image itself
+	ContentType string
+	Size        int64
+	Checksum    string // hex encoded SHA-256
+	Width       int    // of images, 0 for other files
+	Height      int
+}
+
+// AttachmentLimits restricts the attachments users can upload
+type AttachmentLimits struct {
+	MaxBytes     int64
+	AllowedTypes []string // media types as sniffed from the content, e.g. "application/pdf"
+}
+
+// StoreAttachment stores an uploaded file as is, after checking its size and the type
+// sniffed from its content, and returns it with its metadata. The name is random; the
+// content type is the sniffed one, never the one the client claimed. Errors wrapping
+// ErrInvalidFile are the client's fault.
+func StoreAttachment(c *fiber.Ctx, attachments blobstore.BlobStore, file *multipart.FileHeader, limits AttachmentLimits) (StoredFile, error) {
+	if limits.MaxBytes > 0 && file.Size > limits.MaxBytes {
+		return StoredFile{}, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidFile, limits.MaxBytes)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return StoredFile{}, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return StoredFile{}, err
+	}
+	if len(data) == 0 {
+		return StoredFile{}, fmt.Errorf("%w: file is empty", ErrInvalidFile)
+	}
+
+	contentType := http.DetectContentType(data)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !slices.Contains(limits.AllowedTypes, mediaType) {
+		return StoredFile{}, fmt.Errorf("%w: files of type %s are not accepted", ErrInvalidFile, mediaType)
+	}
+
+	token, err := GenerateRandomToken(16)
+	if err != nil {
+		return StoredFile{}, err
+	}
+	stored := describeFile("documents/"+token, data, contentType)
+	if strings.HasPrefix(mediaType, "image/") {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			stored.Width, stored.Height = config.Width, config.Height
+		}
+	}
+
+	if err := attachments.Put(c.UserContext(), stored.Name, bytes.NewReader(data), stored.Size, contentType); err != nil {
+		return StoredFile{}, err
+	}
+	return stored, nil
+}
+
+// SanitizeFileName reduces a client supplied file name to a safe base name for
+// Content-Disposition headers and listings
+func SanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+func describeFile(name string, data []byte, contentType string) StoredFile {
+	sum := sha256.Sum256(data)
+	return StoredFile{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(ImageDir, os.ModePerm); err != nil {
+// StoreUpload runs the cheap checks on an uploaded image and keeps it as is in the upload
+// store for ProcessImage, returning the name it was stored under. Errors wrapping
+// ErrInvalidImage are the client's fault.
+func StoreUpload(c *fiber.Ctx, uploads blobstore.BlobStore, file *multipart.FileHeader, limits ImageLimits) (string, error) {
+	if limits.MaxBytes > 0 && file.Size > limits.MaxBytes {
+		return "", fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidImage, limits.MaxBytes)
+	}
+
+	src, err := file.Open()
+	if err != nil {
 		return "", err
 	}
+	defer src.Close()
 
-	// Save the file
-	savePath := filepath.Join(ImageDir, imageName)
-	if err := c.SaveFile(file, savePath); err != nil {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckImageSignature(data); err != nil {
 		return "", err
 	}
 
-	return imageName, nil
+	token, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	name := "incoming/" + token
+	if err := uploads.Put(c.UserContext(), name, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return "", err
+	}
+	return name, nil
 }
 
-// DeleteFile removes a file from the file system
-func DeleteFile(filename string) error {
+// ProcessImage sanitizes the upload stored under sourceName and writes it to the image
+// store, followed by square thumbnails of variantSizes. Names are random, so clients
+// cannot choose paths or overwrite each other's files; thumbnails share the image's name
+// with their size appended. Errors wrapping ErrInvalidImage will not go away on a retry.
+func ProcessImage(ctx context.Context, uploads, images blobstore.BlobStore, sourceName string, limits ImageLimits, variantSizes []int) ([]StoredFile, error) {
+	src, err := uploads.Get(ctx, sourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	image, err := SanitizeImage(data, limits)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := SquareVariants(image, variantSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make([]StoredFile, 0, len(variants)+1)
+	put := func(file StoredFile, data []byte) error {
+		if err := images.Put(ctx, file.Name, bytes.NewReader(data), file.Size, file.ContentType); err != nil {
+			// Leave nothing behind of a partly stored image
+			DeleteFiles(images, stored)
+			return err
+		}
+		stored = append(stored, file)
+		return nil
+	}
+
+	original := describeFile(name+image.Extension, image.Data, image.ContentType)
+	original.Width, original.Height = image.Width, image.Height
+	if err := put(original, image.Data); err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		file := describeFile(fmt.Sprintf("%s_%d%s", name, variant.Size, variant.Extension), variant.Data, variant.ContentType)
+		file.Variant = variant.Size
+		file.Width, file.Height = variant.Width, variant.Width
+		if err := put(file, variant.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return stored, nil
+}
+
+// DeleteFile removes a file from a blob store
+func DeleteFile(store blobstore.BlobStore, filename string) error {
 	if filename == "" {
 		return nil
 	}
 
-	filePath := filepath.Join(ImageDir, filename)
-	return os.Remove(filePath)
+	return store.Delete(context.Background(), filename)
+}
+
+// DeleteFiles removes stored files from a blob store, ignoring failures
+func DeleteFiles(store blobstore.BlobStore, files []StoredFile) {
+	for _, file := range files {
+		_ = DeleteFile(store, file.Name)
+	}
 }
@@ -0,0 +1,452 @@
+package util
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ErrInvalidImage is wrapped by every error about the content of an uploaded image
+var ErrInvalidImage = errors.New("invalid image")
+
+const (
+	// jpegQuality is used when re-encoding JPEG uploads
+	jpegQuality = 90
+	// maxPNGTextSize bounds how far a compressed PNG text chunk is inflated to look for markup
+	maxPNGTextSize = 1 << 20
+)
+
+var (
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	jpegSignature = []byte{0xFF, 0xD8, 0xFF}
+)
+
+// markupSignatures betray metadata that is meant to be read as a page or script
+var markupSignatures = [][]byte{
+	[]byte("<?php"),
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<!doctype"),
+	[]byte("<svg"),
+	[]byte("<iframe"),
+}
+
+// ImageLimits bounds the uploads accepted as images
+type ImageLimits struct {
+	MaxBytes  int64
+	MaxWidth  int
+	MaxHeight int
+}
+
+// SanitizedImage is an upload re-encoded without any of its metadata
+type SanitizedImage struct {
+	Data        []byte
+	ContentType string
+	Extension   string // including the dot
+	Width       int
+	Height      int
+	decoded     image.Image
+}
+
+// ImageVariant is a square thumbnail of a sanitized image, encoded in the image's format
+type ImageVariant struct {
+	Size        int // requested edge length
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int // edge length, smaller than Size when the image is
+}
+
+var (
+	errUnsupportedFormat = fmt.Errorf("%w: only JPEG and PNG images are supported", ErrInvalidImage)
+	errEmbeddedMarkup    = errors.New("file contains embedded markup")
+)
+
+// CheckImageSignature tells by its first bytes whether data can be an image SanitizeImage
+// accepts, without looking further
+func CheckImageSignature(data []byte) error {
+	if !bytes.HasPrefix(data, pngSignature) && !bytes.HasPrefix(data, jpegSignature) {
+		return errUnsupportedFormat
+	}
+	return nil
+}
+
+// SanitizeImage checks that data is a single JPEG or PNG image within limits and
+// re-encodes it. Re-encoding drops EXIF, GPS and any other embedded metadata; the EXIF
+// orientation of JPEGs is applied to the pixels first so photos keep displaying upright.
+// Files that carry other content, such as an archive appended after the image or
+// markup in a comment or text chunk, are rejected. Only metadata is searched for
+// markup, as compressed pixel data may contain any byte sequence.
+func SanitizeImage(data []byte, limits ImageLimits) (SanitizedImage, error) {
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return SanitizedImage{}, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidImage, limits.MaxBytes)
+	}
+
+	var format string
+	var end, orientation int
+	var err error
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		format = "png"
+		end, err = scanPNG(data)
+	case bytes.HasPrefix(data, jpegSignature):
+		format = "jpeg"
+		end, orientation, err = scanJPEG(data)
+	default:
+		return SanitizedImage{}, errUnsupportedFormat
+	}
+	if err != nil {
+		return SanitizedImage{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if len(bytes.Trim(data[end:], "\x00")) > 0 {
+		return SanitizedImage{}, fmt.Errorf("%w: unexpected data after the end of the image", ErrInvalidImage)
+	}
+
+	// Check the dimensions before decoding, so a small file cannot claim a huge bitmap
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decodedFormat != format {
+		return SanitizedImage{}, fmt.Errorf("%w: file cannot be decoded", ErrInvalidImage)
+	}
+	if config.Width <= 0 || config.Height <= 0 ||
+		(limits.MaxWidth > 0 && config.Width > limits.MaxWidth) ||
+		(limits.MaxHeight > 0 && config.Height > limits.MaxHeight) {
+		return SanitizedImage{}, fmt.Errorf("%w: image is %dx%d pixels, at most %dx%d are allowed",
+			ErrInvalidImage, config.Width, config.Height, limits.MaxWidth, limits.MaxHeight)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return SanitizedImage{}, fmt.Errorf("%w: file cannot be decoded", ErrInvalidImage)
+	}
+
+	var encoded bytes.Buffer
+	sanitized := SanitizedImage{}
+	if format == "png" {
+		err = png.Encode(&encoded, img)
+		sanitized.ContentType, sanitized.Extension = "image/png", ".png"
+	} else {
+		img = applyOrientation(img, orientation)
+		err = jpeg.Encode(&encoded, img, &jpeg.Options{Quality: jpegQuality})
+		sanitized.ContentType, sanitized.Extension = "image/jpeg", ".jpg"
+	}
+	if err != nil {
+		return SanitizedImage{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	sanitized.Data = encoded.Bytes()
+	sanitized.Width = img.Bounds().Dx()
+	sanitized.Height = img.Bounds().Dy()
+	sanitized.decoded = img
+	return sanitized, nil
+}
+
+// SquareVariants crops the centre square of a sanitized image and scales it down to
+// each of the sizes. Images are never scaled up, so the variants of a small image are
+// as large as its short side.
+func SquareVariants(sanitized SanitizedImage, sizes []int) ([]ImageVariant, error) {
+	bounds := sanitized.decoded.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	draw.Draw(square, square.Bounds(), sanitized.decoded, origin, draw.Src)
+
+	variants := make([]ImageVariant, 0, len(sizes))
+	for _, size := range sizes {
+		thumbnail := scaleDown(square, min(size, side))
+
+		var encoded bytes.Buffer
+		var err error
+		if sanitized.ContentType == "image/png" {
+			err = png.Encode(&encoded, thumbnail)
+		} else {
+			err = jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %dpx variant: %w", size, err)
+		}
+
+		variants = append(variants, ImageVariant{
+			Size:        size,
+			Data:        encoded.Bytes(),
+			ContentType: sanitized.ContentType,
+			Extension:   sanitized.Extension,
+			Width:       thumbnail.Bounds().Dx(),
+		})
+	}
+	return variants, nil
+}
+
+// scaleDown resizes a square image to side pixels, averaging the source pixels each
+// target pixel covers
+func scaleDown(src *image.RGBA, side int) *image.RGBA {
+	srcSide := src.Bounds().Dx()
+	if side == srcSide {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		y0, y1 := y*srcSide/side, max((y+1)*srcSide/side, y*srcSide/side+1)
+		for x := 0; x < side; x++ {
+			x0, x1 := x*srcSide/side, max((x+1)*srcSide/side, x*srcSide/side+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			offset := dst.PixOffset(x, y)
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+	return dst
+}
+
+// containsMarkup reports whether metadata holds one of markupSignatures, in any case
+func containsMarkup(metadata []byte) bool {
+	lower := bytes.ToLower(metadata)
+	for _, signature := range markupSignatures {
+		if bytes.Contains(lower, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanPNG walks the chunks of a PNG, checks its text chunks for markup and returns
+// the offset just past IEND
+func scanPNG(data []byte) (int, error) {
+	offset := len(pngSignature)
+	for offset+8 <= len(data) {
+		length := int64(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		next := int64(offset) + 12 + length
+		if next > int64(len(data)) {
+			return 0, errors.New("truncated PNG chunk")
+		}
+		if chunkType == "IEND" {
+			return int(next), nil
+		}
+
+		switch chunkType {
+		case "tEXt", "zTXt", "iTXt":
+			text, err := pngText(chunkType, data[offset+8:next-4])
+			if err != nil {
+				return 0, err
+			}
+			if containsMarkup(text) {
+				return 0, errEmbeddedMarkup
+			}
+		}
+		offset = int(next)
+	}
+	return 0, errors.New("PNG has no end chunk")
+}
+
+// pngText returns the keyword and text of a PNG text chunk, inflating compressed text
+func pngText(chunkType string, chunk []byte) ([]byte, error) {
+	keyword, rest, found := bytes.Cut(chunk, []byte{0})
+	if !found {
+		return nil, errors.New("malformed PNG text chunk")
+	}
+
+	compressed := false
+	switch chunkType {
+	case "zTXt":
+		// Compression method, then the compressed text
+		if len(rest) < 1 {
+			return nil, errors.New("malformed PNG text chunk")
+		}
+		rest, compressed = rest[1:], true
+	case "iTXt":
+		// Compression flag and method, then the language tag and translated keyword
+		if len(rest) < 2 {
+			return nil, errors.New("malformed PNG text chunk")
+		}
+		compressed = rest[0] == 1
+		language, afterLanguage, ok := bytes.Cut(rest[2:], []byte{0})
+		translated, text, ok2 := bytes.Cut(afterLanguage, []byte{0})
+		if !ok || !ok2 {
+			return nil, errors.New("malformed PNG text chunk")
+		}
+		keyword = bytes.Join([][]byte{keyword, language, translated}, []byte{0})
+		rest = text
+	}
+
+	if compressed {
+		reader, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			return nil, errors.New("malformed PNG text chunk")
+		}
+		defer reader.Close()
+		if rest, err = io.ReadAll(io.LimitReader(reader, maxPNGTextSize+1)); err != nil {
+			return nil, errors.New("malformed PNG text chunk")
+		}
+		if len(rest) > maxPNGTextSize {
+			return nil, errors.New("PNG text chunk is too large")
+		}
+	}
+
+	return append(append(keyword, 0), rest...), nil
+}
+
+// scanJPEG walks the segments of a JPEG, checks its application and comment segments
+// for markup and returns the offset just past the end of image marker, together with
+// the EXIF orientation (1 when absent)
+func scanJPEG(data []byte) (int, int, error) {
+	orientation := 1
+	offset := 2
+	for offset < len(data) {
+		if data[offset] != 0xFF {
+			return 0, 0, errors.New("malformed JPEG segment")
+		}
+		// Markers may be preceded by any number of fill bytes
+		for offset < len(data) && data[offset] == 0xFF {
+			offset++
+		}
+		if offset >= len(data) {
+			break
+		}
+		marker := data[offset]
+		offset++
+
+		switch {
+		case marker == 0xD9: // end of image
+			return offset, orientation, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // no payload
+			continue
+		}
+
+		if offset+2 > len(data) {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset:]))
+		if length < 2 || offset+length > len(data) {
+			return 0, 0, errors.New("truncated JPEG segment")
+		}
+		segment := data[offset+2 : offset+length]
+		offset += length
+
+		if (marker >= 0xE0 && marker <= 0xEF) || marker == 0xFE { // APPn and COM
+			if containsMarkup(segment) {
+				return 0, 0, errEmbeddedMarkup
+			}
+		}
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			if value, ok := exifOrientation(segment[6:]); ok {
+				orientation = value
+			}
+		}
+
+		// Entropy-coded data follows a start of scan, up to the next marker other than a restart
+		if marker == 0xDA {
+			for offset+1 < len(data) {
+				next := data[offset+1]
+				if data[offset] == 0xFF && next != 0x00 && (next < 0xD0 || next > 0xD7) {
+					break
+				}
+				offset++
+			}
+			if offset+1 >= len(data) {
+				break
+			}
+		}
+	}
+	return 0, 0, errors.New("JPEG has no end of image marker")
+}
+
+// exifOrientation reads the orientation tag from the first IFD of TIFF encoded EXIF data
+func exifOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		// Tag 0x0112 of type SHORT
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 0, false
+			}
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// applyOrientation rotates and flips img as EXIF orientation values 2 to 8 ask for
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = width-1-x, y
+			case 3: // upside down
+				dx, dy = width-1-x, height-1-y
+			case 4: // upside down and mirrored
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
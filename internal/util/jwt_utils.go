@@ -17,31 +17,110 @@ const (
 	RefreshTokenSecret = "your_refresh_token_secret" // Use env var in production
 	AccessTokenExpiry  = time.Hour * 1               // 1 hour
 	RefreshTokenExpiry = time.Hour * 24 * 7          // 1 week
+	TokenIssuer        = "user_crud_api"
+
+	// MaxClientAssertionLifetime bounds how far in the future a service
+	// account's signed JWT assertion may expire
+	MaxClientAssertionLifetime = time.Minute * 5
 )
 
 // JWTClaims defines the claims in the JWT token
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID           uint     `json:"user_id"`
+	Email            string   `json:"email"`
+	Role             string   `json:"role"`
+	PrincipalType    string   `json:"principal_type,omitempty"`
+	ServiceAccountID uint     `json:"service_account_id,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+	SessionID        uint     `json:"sid,omitempty"`
+	// Tenant the token acts in and the user's role there
+	OrganizationID   uint   `json:"org,omitempty"`
+	OrganizationRole string `json:"org_role,omitempty"`
+	// Effective groups and their permissions, only included when enabled in the config
+	Groups      []string `json:"groups,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Set when Role comes from a temporary role grant; BaseRole applies once RoleExpiresAt (Unix time) has passed
+	RoleGrantID   uint   `json:"role_grant,omitempty"`
+	BaseRole      string `json:"base_role,omitempty"`
+	RoleExpiresAt int64  `json:"role_exp,omitempty"`
+	// Admin acting as the user, only present on impersonation tokens (RFC 8693 "act" claim)
+	Act *ActorClaim `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaim identifies who is acting on behalf of the token subject
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+}
+
+// AccessTokenSubject describes the user and login session an access token is issued for
+type AccessTokenSubject struct {
+	UserID           uint
+	SessionID        uint
+	Email            string
+	Role             string
+	OrganizationID   uint
+	OrganizationRole string
+	Groups           []string
+	Permissions      []string
+	// Temporary role grant Role comes from, if any
+	RoleGrantID   uint
+	BaseRole      string
+	RoleExpiresAt time.Time
+	// Admin impersonating the user, if any
+	ImpersonatorID    uint
+	ImpersonatorEmail string
+	// Overrides AccessTokenExpiry when set
+	ExpiresAt time.Time
+}
+
+// RefreshClaims defines the claims in the JWT refresh token
+type RefreshClaims struct {
+	SessionID uint `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateAccessToken creates a new JWT access token
-func GenerateAccessToken(userID uint, email, role string) (string, error) {
+// GenerateAccessToken creates a new JWT access token bound to a login session and organization
+func GenerateAccessToken(subject AccessTokenSubject) (string, error) {
+	expiresAt := subject.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(AccessTokenExpiry)
+	}
+
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:           subject.UserID,
+		Email:            subject.Email,
+		Role:             subject.Role,
+		PrincipalType:    "user",
+		SessionID:        subject.SessionID,
+		OrganizationID:   subject.OrganizationID,
+		OrganizationRole: subject.OrganizationRole,
+		Groups:           subject.Groups,
+		Permissions:      subject.Permissions,
+		RoleGrantID:      subject.RoleGrantID,
+		BaseRole:         subject.BaseRole,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpiry)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "user_crud_api",
-			Subject:   fmt.Sprintf("%d", userID),
+			Issuer:    TokenIssuer,
+			Subject:   fmt.Sprintf("%d", subject.UserID),
 		},
 	}
 
+	if subject.RoleGrantID != 0 {
+		claims.RoleExpiresAt = subject.RoleExpiresAt.Unix()
+	}
+	if subject.ImpersonatorID != 0 {
+		claims.Act = &ActorClaim{
+			Subject: fmt.Sprintf("%d", subject.ImpersonatorID),
+			UserID:  subject.ImpersonatorID,
+			Email:   subject.ImpersonatorEmail,
+		}
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(AccessTokenSecret))
 	if err != nil {
@@ -51,14 +130,24 @@ func GenerateAccessToken(userID uint, email, role string) (string, error) {
 	return tokenString, nil
 }
 
-// GenerateRefreshToken creates a new JWT refresh token
-func GenerateRefreshToken(userID uint) (string, error) {
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenExpiry)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		NotBefore: jwt.NewNumericDate(time.Now()),
-		Issuer:    "user_crud_api",
-		Subject:   fmt.Sprintf("%d", userID),
+// GenerateRefreshToken creates a new JWT refresh token for a login session.
+// Every token gets a random ID so rotated tokens never repeat.
+func GenerateRefreshToken(userID, sessionID uint) (string, error) {
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := RefreshClaims{
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    TokenIssuer,
+			Subject:   fmt.Sprintf("%d", userID),
+			ID:        tokenID,
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -70,6 +159,102 @@ func GenerateRefreshToken(userID uint) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateServiceAccessToken creates a new JWT access token for a service account.
+// Service account tokens carry scopes instead of a user role and have no refresh token.
+func GenerateServiceAccessToken(serviceAccountID uint, clientID string, scopes []string) (string, error) {
+	claims := JWTClaims{
+		PrincipalType:    "service_account",
+		ServiceAccountID: serviceAccountID,
+		Scopes:           scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    TokenIssuer,
+			Subject:   clientID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(AccessTokenSecret))
+}
+
+// ParseClientAssertionSubject extracts the client ID from a JWT assertion without verifying it,
+// so the caller can look up the key the assertion must be verified against
+func ParseClientAssertionSubject(assertion string) (string, error) {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, &claims); err != nil {
+		return "", err
+	}
+	if claims.Issuer == "" || claims.Issuer != claims.Subject {
+		return "", errors.New("assertion issuer and subject must both be the client ID")
+	}
+	return claims.Subject, nil
+}
+
+// VerifyClientAssertion validates a JWT assertion (RFC 7523) signed with the service account's private key.
+// The returned claims carry the jti and expiry the caller needs to reject replays.
+func VerifyClientAssertion(assertion, clientID, publicKeyPEM string) (*jwt.RegisteredClaims, error) {
+	key, err := parsePublicKeyPEM(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseWithClaims(
+		assertion,
+		&jwt.RegisteredClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			switch token.Method.(type) {
+			case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+				return key, nil
+			}
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		},
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(clientID),
+		jwt.WithSubject(clientID),
+		jwt.WithAudience(TokenIssuer),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid assertion")
+	}
+
+	if time.Until(claims.ExpiresAt.Time) > MaxClientAssertionLifetime {
+		return nil, errors.New("assertion lifetime too long")
+	}
+
+	if claims.ID == "" {
+		return nil, errors.New("assertion must carry a jti")
+	}
+
+	return claims, nil
+}
+
+// ValidatePublicKeyPEM checks that a PEM block holds an RSA, ECDSA or Ed25519 public key
+func ValidatePublicKeyPEM(publicKeyPEM string) error {
+	_, err := parsePublicKeyPEM(publicKeyPEM)
+	return err
+}
+
+func parsePublicKeyPEM(publicKeyPEM string) (interface{}, error) {
+	pemBytes := []byte(publicKeyPEM)
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported or malformed public key")
+}
+
 // VerifyAccessToken validates a JWT access token and returns its claims
 func VerifyAccessToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(
@@ -95,11 +280,11 @@ func VerifyAccessToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
-// VerifyRefreshToken validates a JWT refresh token
-func VerifyRefreshToken(tokenString string) (uint, error) {
+// VerifyRefreshToken validates a JWT refresh token and returns the user and session IDs
+func VerifyRefreshToken(tokenString string) (uint, uint, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&jwt.RegisteredClaims{},
+		&RefreshClaims{},
 		func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -109,18 +294,18 @@ func VerifyRefreshToken(tokenString string) (uint, error) {
 	)
 
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok || !token.Valid {
-		return 0, errors.New("invalid token")
+	claims, ok := token.Claims.(*RefreshClaims)
+	if !ok || !token.Valid || claims.SessionID == 0 {
+		return 0, 0, errors.New("invalid token")
 	}
 
 	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
-	return uint(userID), nil
+	return uint(userID), claims.SessionID, nil
 }
@@ -2,20 +2,210 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword creates a bcrypt hash of the password
+// Supported password hashing algorithms
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+)
+
+// PasswordHasher hashes passwords into self-describing encoded strings, so
+// hashes created with older algorithms or parameters can still be verified
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) bool
+	// NeedsRehash reports whether the hash was created with other settings than the current ones
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2idParams configures argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follows the OWASP recommendation for argon2id
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var passwordHasher = NewPasswordHasher(HashAlgorithmArgon2id, DefaultArgon2idParams, bcrypt.DefaultCost)
+
+// dummyPasswordHash returns a hash no account has, created once with passwordHasher
+var dummyPasswordHash = newDummyPasswordHash(passwordHasher)
+
+// SetPasswordHasher replaces the hasher used by HashPassword and CheckPassword. It creates
+// the dummy hash right away, so the first login for an unknown email is not slower.
+func SetPasswordHasher(hasher PasswordHasher) {
+	passwordHasher = hasher
+	dummyPasswordHash = newDummyPasswordHash(hasher)
+	dummyPasswordHash()
+}
+
+func newDummyPasswordHash(hasher PasswordHasher) func() string {
+	return sync.OnceValue(func() string {
+		hash, err := hasher.Hash("dummy password of no account")
+		if err != nil {
+			return ""
+		}
+		return hash
+	})
+}
+
+// HashPassword creates a hash of the password with the configured hasher
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	return passwordHasher.Hash(password)
+}
+
+// CheckPassword compares a password against a hashed password of any supported algorithm
+func CheckPassword(password, hashedPassword string) bool {
+	return passwordHasher.Verify(password, hashedPassword)
+}
+
+// CheckDummyPassword verifies a password against a hash with the current settings that
+// belongs to no account, so failing a login for an unknown email takes as long as for
+// a wrong password and does not tell whether the account exists
+func CheckDummyPassword(password string) {
+	passwordHasher.Verify(password, dummyPasswordHash())
+}
+
+// PasswordNeedsRehash reports whether a stored hash should be replaced after a successful login
+func PasswordNeedsRehash(hashedPassword string) bool {
+	return passwordHasher.NeedsRehash(hashedPassword)
+}
+
+type passwordHasherImpl struct {
+	algorithm  string
+	argon2id   Argon2idParams
+	bcryptCost int
+}
+
+// NewPasswordHasher creates a hasher that hashes with algorithm and verifies both argon2id and bcrypt hashes
+func NewPasswordHasher(algorithm string, argon2idParams Argon2idParams, bcryptCost int) PasswordHasher {
+	if algorithm != HashAlgorithmBcrypt {
+		algorithm = HashAlgorithmArgon2id
+	}
+
+	return &passwordHasherImpl{
+		algorithm:  algorithm,
+		argon2id:   argon2idParams,
+		bcryptCost: bcryptCost,
+	}
+}
+
+func (h *passwordHasherImpl) Hash(password string) (string, error) {
+	if h.algorithm == HashAlgorithmBcrypt {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedPassword), nil
+	}
+
+	salt := make([]byte, h.argon2id.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashedPassword), nil
+
+	p := h.argon2id
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// CheckPassword compares a password against a hashed password
-func CheckPassword(password, hashedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+func (h *passwordHasherImpl) Verify(password, encodedHash string) bool {
+	if isBcryptHash(encodedHash) {
+		return bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func (h *passwordHasherImpl) NeedsRehash(encodedHash string) bool {
+	if isBcryptHash(encodedHash) {
+		if h.algorithm != HashAlgorithmBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost < h.bcryptCost
+	}
+
+	if h.algorithm != HashAlgorithmArgon2id {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return p.Memory < h.argon2id.Memory ||
+		p.Iterations < h.argon2id.Iterations ||
+		p.Parallelism != h.argon2id.Parallelism ||
+		p.KeyLength < h.argon2id.KeyLength
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// decodeArgon2idHash parses the PHC string format "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>"
+func decodeArgon2idHash(encodedHash string) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != HashAlgorithmArgon2id {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, err
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
 }
@@ -0,0 +1,180 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keeps the tests fast; production uses DefaultArgon2idParams
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	hasher := NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost)
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("expected a PHC encoded argon2id hash, got %s", hash)
+	}
+	if !hasher.Verify("correct horse", hash) {
+		t.Error("expected the password to verify")
+	}
+	if hasher.Verify("correct horse ", hash) || hasher.Verify("", hash) {
+		t.Error("expected other passwords to be rejected")
+	}
+
+	again, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Error("expected a new salt for every hash")
+	}
+
+	// Verification uses the parameters stored in the hash, not the hasher's
+	stronger := NewPasswordHasher(HashAlgorithmArgon2id, DefaultArgon2idParams, bcrypt.MinCost)
+	if !stronger.Verify("correct horse", hash) {
+		t.Error("expected a hash with older parameters to verify")
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	hasher := NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost)
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+
+	tests := map[string]string{
+		"empty":             "",
+		"plain text":        "correct horse",
+		"argon2i":           strings.Replace(hash, "$argon2id$", "$argon2i$", 1),
+		"other version":     strings.Replace(hash, "$v=19$", "$v=16$", 1),
+		"missing parameter": strings.Replace(hash, ",p=1", "", 1),
+		"invalid salt":      strings.Join([]string{"", parts[1], parts[2], parts[3], "!!!", parts[5]}, "$"),
+		"invalid key":       strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], "!!!"}, "$"),
+		"changed key":       strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], parts[4]}, "$"),
+		"missing part":      strings.Join(parts[:5], "$"),
+	}
+	for name, encoded := range tests {
+		t.Run(name, func(t *testing.T) {
+			if hasher.Verify("correct horse", encoded) {
+				t.Errorf("expected %q to be rejected", encoded)
+			}
+			if !hasher.NeedsRehash(encoded) {
+				t.Errorf("expected %q to need a rehash", encoded)
+			}
+		})
+	}
+}
+
+func TestBcryptHashesStillVerify(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasher := NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost)
+	if !hasher.Verify("correct horse", string(legacy)) {
+		t.Error("expected a bcrypt hash to verify")
+	}
+	if hasher.Verify("wrong", string(legacy)) {
+		t.Error("expected a wrong password to be rejected")
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	argon2idHasher := NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost)
+	current, err := argon2idHasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptMin, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(p *Argon2idParams)) PasswordHasher {
+		params := testArgon2idParams
+		change(&params)
+		return NewPasswordHasher(HashAlgorithmArgon2id, params, bcrypt.MinCost)
+	}
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{"same parameters", argon2idHasher, current, false},
+		{"more memory", with(func(p *Argon2idParams) { p.Memory *= 2 }), current, true},
+		{"less memory", with(func(p *Argon2idParams) { p.Memory /= 2 }), current, false},
+		{"more iterations", with(func(p *Argon2idParams) { p.Iterations++ }), current, true},
+		{"other parallelism", with(func(p *Argon2idParams) { p.Parallelism++ }), current, true},
+		{"longer key", with(func(p *Argon2idParams) { p.KeyLength = 64 }), current, true},
+		{"salt length only", with(func(p *Argon2idParams) { p.SaltLength = 32 }), current, false},
+		{"bcrypt while hashing with argon2id", argon2idHasher, string(bcryptMin), true},
+		{"argon2id while hashing with bcrypt", NewPasswordHasher(HashAlgorithmBcrypt, testArgon2idParams, bcrypt.MinCost), current, true},
+		{"bcrypt with the current cost", NewPasswordHasher(HashAlgorithmBcrypt, testArgon2idParams, bcrypt.MinCost), string(bcryptMin), false},
+		{"bcrypt with a lower cost", NewPasswordHasher(HashAlgorithmBcrypt, testArgon2idParams, bcrypt.MinCost+1), string(bcryptMin), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("expected NeedsRehash to be %v", tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordHelpersUseConfiguredHasher(t *testing.T) {
+	previous := passwordHasher
+	t.Cleanup(func() { SetPasswordHasher(previous) })
+	SetPasswordHasher(NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost))
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword("correct horse", hash) || CheckPassword("wrong", hash) {
+		t.Error("expected CheckPassword to verify with the configured hasher")
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Error("expected a fresh hash not to need a rehash")
+	}
+
+	SetPasswordHasher(NewPasswordHasher(HashAlgorithmArgon2id, DefaultArgon2idParams, bcrypt.MinCost))
+	if !PasswordNeedsRehash(hash) {
+		t.Error("expected a hash with weaker parameters to need a rehash")
+	}
+}
+
+func TestCheckDummyPasswordUsesConfiguredHasher(t *testing.T) {
+	previous := passwordHasher
+	t.Cleanup(func() { SetPasswordHasher(previous) })
+	SetPasswordHasher(NewPasswordHasher(HashAlgorithmArgon2id, testArgon2idParams, bcrypt.MinCost))
+
+	CheckDummyPassword("correct horse")
+	hash := dummyPasswordHash()
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("expected a dummy hash with the configured parameters, got %q", hash)
+	}
+	if dummyPasswordHash() != hash {
+		t.Error("expected the dummy hash to be created once")
+	}
+	if CheckPassword("correct horse", hash) {
+		t.Error("expected the dummy hash not to match other passwords")
+	}
+}
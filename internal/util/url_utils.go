@@ -2,15 +2,31 @@ package util
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+
+	"user_crud/pkg/blobstore"
 )
 
-// BuildImageURL generates a full URL for an image using the request's host and protocol
-func BuildImageURL(c *fiber.Ctx, imageName string) string {
+// BuildImageURL generates a full URL for an image. Images the application serves itself
+// are addressed with the request's host and protocol; those in an object store get a
+// presigned URL valid for expires.
+func BuildImageURL(c *fiber.Ctx, images blobstore.BlobStore, imageName string, expires time.Duration) string {
 	if imageName == "" {
 		return ""
 	}
+
+	if presigner, ok := images.(blobstore.Presigner); ok {
+		imageURL, err := presigner.PresignGet(imageName, expires)
+		if err != nil {
+			log.Printf("failed to presign image %q: %v", imageName, err)
+			return ""
+		}
+		return imageURL
+	}
+
 	protocol := "http"
 	if c.Protocol() == "https" {
 		protocol = "https"
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of a webhook payload
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// SignWebhookPayload signs the timestamp and payload with the subscription secret.
+// The result has the form "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<payload>">".
+func SignWebhookPayload(secret string, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, webhookMAC(secret, t, payload))
+}
+
+// VerifyWebhookSignature checks a signature header produced by SignWebhookPayload and
+// rejects signatures older than tolerance, so receivers can refuse replayed requests
+func VerifyWebhookSignature(secret, header string, payload []byte, tolerance time.Duration) error {
+	var t, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signature = value
+		}
+	}
+	if t == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, t, payload))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func webhookMAC(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	payload := []byte(`{"event":"user.created"}`)
+
+	// HMAC-SHA256 of "1700000000.<payload>" keyed with the secret, computed independently
+	want := "t=1700000000,v1=be54c9b0b1bfcb889662e9b74778f194903a82691c8323f7bf085ca53892ee78"
+	if got := SignWebhookPayload("whsec_test", time.Unix(1700000000, 0), payload); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	const tolerance = 5 * time.Minute
+	payload := []byte(`{"event":"user.created"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		err     string
+	}{
+		{name: "valid", header: SignWebhookPayload(secret, now, payload)},
+		{name: "valid with spaces", header: strings.ReplaceAll(SignWebhookPayload(secret, now, payload), ",", ", ")},
+		{name: "within tolerance", header: SignWebhookPayload(secret, now.Add(-tolerance+time.Minute), payload)},
+		{name: "wrong secret", secret: "other", header: SignWebhookPayload(secret, now, payload), err: "signature mismatch"},
+		{name: "changed payload", header: SignWebhookPayload(secret, now, payload), payload: []byte(`{"event":"user.deleted"}`), err: "signature mismatch"},
+		{name: "changed timestamp", header: strings.Replace(SignWebhookPayload(secret, now, payload), "t=", "t=1", 1), err: "signature timestamp outside tolerance"},
+		{name: "too old", header: SignWebhookPayload(secret, now.Add(-tolerance-time.Minute), payload), err: "signature timestamp outside tolerance"},
+		{name: "in the future", header: SignWebhookPayload(secret, now.Add(tolerance+time.Minute), payload), err: "signature timestamp outside tolerance"},
+		{name: "missing signature", header: "t=1700000000", err: "malformed signature header"},
+		{name: "missing timestamp", header: "v1=abc", err: "malformed signature header"},
+		{name: "empty header", header: "", err: "malformed signature header"},
+		{name: "malformed timestamp", header: "t=yesterday,v1=abc", err: "malformed signature timestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret == "" {
+				tt.secret = secret
+			}
+			if tt.payload == nil {
+				tt.payload = payload
+			}
+
+			err := VerifyWebhookSignature(tt.secret, tt.header, tt.payload, tolerance)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("expected the signature to verify, got %v", err)
+			case tt.err != "" && (err == nil || err.Error() != tt.err):
+				t.Errorf("expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
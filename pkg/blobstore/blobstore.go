@@ -0,0 +1,54 @@
+// Package blobstore keeps uploaded files in a local directory or an S3-compatible
+// object store, so application instances need not share a disk.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no object is stored under the key
+var ErrNotFound = errors.New("object not found")
+
+// BlobStore stores objects under slash separated keys such as "avatars/1.png"
+type BlobStore interface {
+	// Put stores size bytes read from body, replacing any object under the key
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object for reading; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by stores whose objects are not served by the application.
+// Clients download them directly with a URL that is only valid for a while.
+type Presigner interface {
+	PresignGet(key string, expires time.Duration) (string, error)
+}
+
+// LocalDirectory reports the directory of a store keeping objects on the local disk,
+// which the application serves itself
+func LocalDirectory(store BlobStore) (string, bool) {
+	local, ok := store.(*localStore)
+	if !ok {
+		return "", false
+	}
+	return local.dir, true
+}
+
+// validateKey rejects keys that are empty or could leave the store's directory or bucket
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Package blobstoretest provides a local stand-in for an S3-compatible object store,
+// so the S3 blob store can be exercised in unit tests without MinIO or AWS, e.g.
+//
+//	server := blobstoretest.StartS3Server(t)
+//	store, _ := blobstore.NewS3Store(server.Config())
+//	_ = store.Put(ctx, "a.png", body, size, "image/png")
+//	object, ok := server.Object("a.png")
+package blobstoretest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"user_crud/pkg/blobstore"
+)
+
+// Credentials and location the stand-in accepts
+const (
+	Region          = "us-east-1"
+	Bucket          = "test-bucket"
+	AccessKeyID     = "AKIASTANDIN"
+	SecretAccessKey = "stand-in-secret"
+)
+
+// Object is an object stored in the stand-in
+type Object struct {
+	Data        []byte
+	ContentType string
+}
+
+// S3Server serves PUT, GET and DELETE of objects in one path-style bucket. Every
+// request must carry a valid AWS Signature Version 4, in the Authorization header or
+// as a presigned query.
+type S3Server struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+// StartS3Server starts an S3 stand-in that is stopped when the test ends
+func StartS3Server(t testing.TB) *S3Server {
+	t.Helper()
+
+	server := &S3Server{objects: make(map[string]Object)}
+	server.server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.server.Close)
+	return server
+}
+
+// URL is the endpoint of the stand-in
+func (s *S3Server) URL() string {
+	return s.server.URL
+}
+
+// Config is the configuration to hand to blobstore.NewS3Store
+func (s *S3Server) Config() blobstore.S3Config {
+	return blobstore.S3Config{
+		Endpoint:        s.server.URL,
+		Region:          Region,
+		Bucket:          Bucket,
+		AccessKeyID:     AccessKeyID,
+		SecretAccessKey: SecretAccessKey,
+		PathStyle:       true,
+		Timeout:         5 * time.Second,
+	}
+}
+
+// Object returns the object stored under key
+func (s *S3Server) Object(key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[key]
+	return object, ok
+}
+
+// Keys returns the keys of all stored objects, sorted
+func (s *S3Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *S3Server) handle(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+Bucket+"/")
+	if !ok || key == "" {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "only "+Bucket+" exists")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if code, message := verify(r, body); code != "" {
+		writeError(w, http.StatusForbidden, code, message)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = Object{Data: body, ContentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		if object.ContentType != "" {
+			w.Header().Set("Content-Type", object.ContentType)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.Data)))
+		_, _ = w.Write(object.Data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+// verify checks the signature of r, returning an S3 error code and message when it is invalid
+func verify(r *http.Request, body []byte) (string, string) {
+	query := r.URL.Query()
+	presigned := query.Get("X-Amz-Signature") != ""
+
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+	if presigned {
+		if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+			return "AuthorizationQueryParametersError", "unsupported algorithm"
+		}
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = "UNSIGNED-PAYLOAD"
+		query.Del("X-Amz-Signature")
+	} else {
+		authorization, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+		if !ok {
+			return "AccessDenied", "request is not signed"
+		}
+		for _, part := range strings.Split(authorization, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		sum := sha256.Sum256(body)
+		if payloadHash != "UNSIGNED-PAYLOAD" && payloadHash != hex.EncodeToString(sum[:]) {
+			return "XAmzContentSHA256Mismatch", "payload does not match its hash"
+		}
+	}
+
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return "AccessDenied", "invalid X-Amz-Date"
+	}
+	if presigned {
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || time.Now().After(signedAt.Add(time.Duration(expires)*time.Second)) {
+			return "AccessDenied", "Request has expired"
+		}
+	} else if time.Since(signedAt).Abs() > 15*time.Minute {
+		return "RequestTimeTooSkewed", "request time is too far from the server time"
+	}
+
+	scope := amzDate[:8] + "/" + Region + "/s3/aws4_request"
+	if credential != AccessKeyID+"/"+scope {
+		return "InvalidAccessKeyId", "unknown access key or credential scope"
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(name, false)+"="+escape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		escape(r.URL.Path, true),
+		strings.Join(pairs, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + SecretAccessKey)
+	for _, part := range []string{amzDate[:8], Region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(signature)) {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."
+	}
+	return "", ""
+}
+
+// escape percent-encodes everything but unreserved characters, as SigV4 does
+func escape(value string, path bool) string {
+	if path {
+		segments := strings.Split(value, "/")
+		for i, segment := range segments {
+			segments[i] = escape(segment, false)
+		}
+		return strings.Join(segments, "/")
+	}
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
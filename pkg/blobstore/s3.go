@@ -0,0 +1,213 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest validity SigV4 allows for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// S3Config locates a bucket of Amazon S3 or a compatible store such as MinIO
+type S3Config struct {
+	Endpoint        string // e.g. "http://localhost:9000"; Amazon S3 of the region when empty
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // address the bucket in the path rather than the host name, as most compatible stores expect
+	Timeout         time.Duration
+}
+
+// s3Store keeps objects in a bucket, signing requests with AWS Signature Version 4
+type s3Store struct {
+	scheme    string
+	host      string // host of the bucket, including it for virtual-hosted style
+	prefix    string // path before the key, "/bucket" for path style
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Store creates a store for the bucket in config
+func NewS3Store(config S3Config) (BlobStore, error) {
+	if config.Region == "" {
+		return nil, errors.New("S3 region is required")
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 credentials are required")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Trim(parsed.Path, "/") != "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	store := &s3Store{
+		scheme:    parsed.Scheme,
+		host:      parsed.Host,
+		region:    config.Region,
+		accessKey: config.AccessKeyID,
+		secretKey: config.SecretAccessKey,
+		client:    &http.Client{Timeout: config.Timeout},
+		now:       time.Now,
+	}
+	if config.PathStyle {
+		store.prefix = "/" + config.Bucket
+	} else {
+		store.host = config.Bucket + "." + parsed.Host
+	}
+	return store, nil
+}
+
+func (s *s3Store) objectURL(key string) (*url.URL, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: s.scheme, Host: s.host, Path: s.prefix + "/" + key}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	// The payload is signed, so it is read in full first; uploads are small
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(payload)) != size {
+		return fmt.Errorf("read %d of %d bytes", len(payload), size)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, payload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do signs and sends req, turning error responses into errors
+func (s *s3Store) do(req *http.Request, payload []byte) (*http.Response, error) {
+	s.sign(req, hashHex(payload), s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&failure)
+	if resp.StatusCode == http.StatusNotFound && (failure.Code == "" || failure.Code == "NoSuchKey") {
+		return nil, ErrNotFound
+	}
+	if failure.Code != "" {
+		return nil, fmt.Errorf("S3 %s answered %d %s: %s", req.Method, resp.StatusCode, failure.Code, failure.Message)
+	}
+	return nil, fmt.Errorf("S3 %s answered %d", req.Method, resp.StatusCode)
+}
+
+// PresignGet returns a URL that downloads the object without credentials until it expires
+func (s *s3Store) PresignGet(key string, expires time.Duration) (string, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > maxPresignExpiry {
+		return "", fmt.Errorf("presigned URLs must expire within %s", maxPresignExpiry)
+	}
+
+	now := s.now().UTC()
+	scope := credentialScope(now, s.region)
+	query := url.Values{
+		"X-Amz-Algorithm":     {signingAlgorithm},
+		"X-Amz-Credential":    {s.accessKey + "/" + scope},
+		"X-Amz-Date":          {now.Format(amzDateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		uriEncode(target.Path, true),
+		canonicalQuery,
+		"host:" + target.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	signature := s.signature(now, scope, canonicalRequest)
+	target.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
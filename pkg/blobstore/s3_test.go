@@ -0,0 +1,97 @@
+package blobstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"user_crud/pkg/blobstore"
+	"user_crud/pkg/blobstore/blobstoretest"
+)
+
+func TestS3StoreAgainstStandIn(t *testing.T) {
+	server := blobstoretest.StartS3Server(t)
+	store, err := blobstore.NewS3Store(server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data := []byte("\x89PNG not really")
+
+	// A key that needs encoding checks that both sides build the same canonical path
+	const key = "avatars/1 a+b.png"
+	if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	object, ok := server.Object(key)
+	if !ok || !bytes.Equal(object.Data, data) || object.ContentType != "image/png" {
+		t.Fatalf("expected the object to be stored, got %+v", object)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+
+	presigned, err := store.(blobstore.Presigner).PresignGet(key, time.Minute)
+	if err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+	resp, err := http.Get(presigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, data) {
+		t.Errorf("expected the presigned URL to download the object, got %d %s", resp.StatusCode, got)
+	}
+
+	// Changing the signed query invalidates the signature
+	resp, err = http.Get(strings.Replace(presigned, "X-Amz-Expires=60", "X-Amz-Expires=600", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a tampered presigned URL to be refused, got %d", resp.StatusCode)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("expected deleting a missing object to succeed, got %v", err)
+	}
+}
+
+func TestS3StoreWrongSecret(t *testing.T) {
+	server := blobstoretest.StartS3Server(t)
+	config := server.Config()
+	config.SecretAccessKey = "wrong"
+	store, err := blobstore.NewS3Store(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(context.Background(), "a.png", strings.NewReader("a"), 1, "image/png")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("expected SignatureDoesNotMatch, got %v", err)
+	}
+	if len(server.Keys()) != 0 {
+		t.Errorf("expected nothing to be stored, got %v", server.Keys())
+	}
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AllEvents subscribes a handler to every event type
+const AllEvents = "*"
+
+// Handler processes an event inside the process. Handlers are called again when
+// a publish is retried, so they should be idempotent.
+type Handler func(ctx context.Context, event Event) error
+
+// Bus is a sink that hands events to in-process subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers a handler for an event type or for AllEvents
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Name() string {
+	return "in_process"
+}
+
+// Publish calls every handler subscribed to the event, in the order they subscribed,
+// and reports the failures of all of them
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("handler failed on %s: %w", event.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+// Package events publishes domain events to in-process subscribers and message brokers
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event is a domain event as it is handed to sinks
+type Event struct {
+	ID            string          `json:"id"` // unique, lets consumers drop duplicates
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uint            `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Sink receives published events. Delivery is at least once: after a failure a sink
+// may see an event again, so consumers should drop duplicates by ID.
+type Sink interface {
+	// Name identifies the sink in logs and in the record of where an event was delivered
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
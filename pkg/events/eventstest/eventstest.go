@@ -0,0 +1,143 @@
+// Package eventstest provides local stand-ins for the brokers events are published to,
+// so sinks can be exercised in unit tests without a NATS server or Kafka cluster, e.g.
+//
+//	server := eventstest.StartNATSServer(t)
+//	sink, _ := events.NewNATSSink(server.URL(), "app", time.Second)
+//	_ = sink.Publish(ctx, event)
+//	messages := server.Messages()
+package eventstest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"user_crud/pkg/events"
+)
+
+// Recorder is a sink that keeps every event it receives. Failures can be
+// injected to check that publishing is retried.
+type Recorder struct {
+	mu     sync.Mutex
+	name   string
+	events []events.Event
+	fail   []error
+}
+
+// NewRecorder creates a recording sink with the given name
+func NewRecorder(name string) *Recorder {
+	return &Recorder{name: name}
+}
+
+func (r *Recorder) Name() string {
+	return r.name
+}
+
+func (r *Recorder) Publish(_ context.Context, event events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.fail) > 0 {
+		err := r.fail[0]
+		r.fail = r.fail[1:]
+		return err
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+// FailNext makes the next publishes fail with the errors, one per publish
+func (r *Recorder) FailNext(errs ...error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = append(r.fail, errs...)
+}
+
+// Events returns the events received so far
+func (r *Recorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+// KafkaRecord is a record produced through the Kafka REST proxy stand-in
+type KafkaRecord struct {
+	Topic string
+	Key   string
+	Value events.Event
+}
+
+// KafkaRESTProxy accepts produce requests of the Confluent REST proxy v2 API
+type KafkaRESTProxy struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	records []KafkaRecord
+	status  int
+}
+
+// StartKafkaRESTProxy starts a REST proxy stand-in that is stopped when the test ends
+func StartKafkaRESTProxy(t testing.TB) *KafkaRESTProxy {
+	t.Helper()
+
+	proxy := &KafkaRESTProxy{}
+	proxy.server = httptest.NewServer(http.HandlerFunc(proxy.produce))
+	t.Cleanup(proxy.server.Close)
+	return proxy
+}
+
+// URL is the base URL to hand to events.NewKafkaRESTSink
+func (p *KafkaRESTProxy) URL() string {
+	return p.server.URL
+}
+
+// FailWith makes the proxy answer produce requests with the status, or succeed again with 0
+func (p *KafkaRESTProxy) FailWith(status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+}
+
+// Records returns the records produced so far
+func (p *KafkaRESTProxy) Records() []KafkaRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]KafkaRecord(nil), p.records...)
+}
+
+func (p *KafkaRESTProxy) produce(w http.ResponseWriter, r *http.Request) {
+	topic, ok := strings.CutPrefix(r.URL.Path, "/topics/")
+	if r.Method != http.MethodPost || !ok || topic == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status != 0 {
+		http.Error(w, `{"error_code":50302,"message":"stand-in failure"}`, p.status)
+		return
+	}
+
+	var body struct {
+		Records []struct {
+			Key   string       `json:"key"`
+			Value events.Event `json:"value"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error_code":422,"message":"invalid records"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	offsets := make([]map[string]any, 0, len(body.Records))
+	for _, record := range body.Records {
+		offsets = append(offsets, map[string]any{"partition": 0, "offset": len(p.records)})
+		p.records = append(p.records, KafkaRecord{Topic: topic, Key: record.Key, Value: record.Value})
+	}
+	w.Header().Set("Content-Type", "application/vnd.kafka.v2+json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"offsets": offsets})
+}
@@ -0,0 +1,218 @@
+package eventstest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// NATSMessage is a message published to the NATS stand-in
+type NATSMessage struct {
+	Subject string
+	Data    []byte
+}
+
+// natsClient is a connection to the stand-in; writes to it are serialized because
+// messages for its subscriptions come from other connections
+type natsClient struct {
+	conn          net.Conn
+	writeMu       sync.Mutex
+	subscriptions map[string]string // subscription ID to subject, guarded by the server
+}
+
+func (c *natsClient) write(format string, args ...any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := fmt.Fprintf(c.conn, format, args...)
+	return err
+}
+
+// NATSServer speaks enough of the NATS core protocol to accept connections, answer
+// PINGs, record PUBs and deliver them to subscriptions on the exact subject. Wildcards
+// and queue groups are not supported.
+type NATSServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []NATSMessage
+	reject   string
+	clients  map[*natsClient]struct{}
+	wg       sync.WaitGroup
+}
+
+// StartNATSServer starts a NATS stand-in on a free local port that is stopped when the test ends
+func StartNATSServer(t testing.TB) *NATSServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start NATS stand-in: %v", err)
+	}
+
+	server := &NATSServer{listener: listener, clients: make(map[*natsClient]struct{})}
+	server.wg.Add(1)
+	go server.accept()
+	t.Cleanup(server.close)
+	return server
+}
+
+// URL is the server URL to hand to events.NewNATSSink and events.NewNATSHub
+func (s *NATSServer) URL() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+// Reject makes the server answer publishes with -ERR and the message, or accept them again with ""
+func (s *NATSServer) Reject(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = message
+}
+
+// Messages returns the messages published so far
+func (s *NATSServer) Messages() []NATSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]NATSMessage(nil), s.messages...)
+}
+
+// Disconnect closes every client connection, e.g. to check that clients reconnect
+func (s *NATSServer) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		_ = client.conn.Close()
+	}
+}
+
+func (s *NATSServer) close() {
+	_ = s.listener.Close()
+
+	// Clients keep their connections open, so they are cut off here
+	s.Disconnect()
+	s.wg.Wait()
+}
+
+func (s *NATSServer) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		client := &natsClient{conn: conn, subscriptions: make(map[string]string)}
+		s.mu.Lock()
+		s.clients[client] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(client)
+	}
+}
+
+func (s *NATSServer) serve(client *natsClient) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+		_ = client.conn.Close()
+	}()
+
+	if err := client.write("INFO {\"server_id\":\"eventstest\",\"version\":\"2.10.0\",\"proto\":1,\"max_payload\":1048576}\r\n"); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(client.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			err = client.write("PONG\r\n")
+		case "PUB":
+			err = s.publish(client, reader, fields[1:])
+		case "SUB":
+			err = s.subscribe(client, fields[1:])
+		case "UNSUB":
+			if len(fields) > 1 {
+				s.mu.Lock()
+				delete(client.subscriptions, fields[1])
+				s.mu.Unlock()
+			}
+		case "CONNECT", "PONG":
+		default:
+			err = client.write("-ERR 'Unknown Protocol Operation'\r\n")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscribe handles "SUB <subject> [queue group] <sid>"
+func (s *NATSServer) subscribe(client *natsClient, args []string) error {
+	if len(args) < 2 {
+		return client.write("-ERR 'Unknown Protocol Operation'\r\n")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client.subscriptions[args[len(args)-1]] = args[0]
+	return nil
+}
+
+// publish reads the payload of "PUB <subject> [reply-to] <#bytes>"
+func (s *NATSServer) publish(client *natsClient, reader *bufio.Reader, args []string) error {
+	if len(args) < 2 {
+		return client.write("-ERR 'Unknown Protocol Operation'\r\n")
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || size < 0 {
+		return client.write("-ERR 'Unknown Protocol Operation'\r\n")
+	}
+
+	// The payload is followed by CRLF
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return err
+	}
+	subject := args[0]
+
+	s.mu.Lock()
+	reject := s.reject
+	type delivery struct {
+		client *natsClient
+		sid    string
+	}
+	var deliveries []delivery
+	if reject == "" {
+		s.messages = append(s.messages, NATSMessage{Subject: subject, Data: data[:size]})
+		for other := range s.clients {
+			for sid, subscribed := range other.subscriptions {
+				if subscribed == subject {
+					deliveries = append(deliveries, delivery{client: other, sid: sid})
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if reject != "" {
+		return client.write("-ERR '%s'\r\n", reject)
+	}
+	for _, d := range deliveries {
+		// A subscriber that went away does not fail the publisher
+		_ = d.client.write("MSG %s %s %d\r\n%s\r\n", subject, d.sid, size, data[:size])
+	}
+	return nil
+}
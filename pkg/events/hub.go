@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Hub passes short-lived messages, such as presence changes, between the instances of
+// the application. Unlike sinks it gives no delivery guarantee: messages published while
+// an instance is disconnected are lost to it.
+type Hub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe calls the handler with every message published on the topic, including
+	// those published by this instance, until cancel is called. Handlers must not block.
+	Subscribe(topic string, handler func(payload []byte)) (cancel func())
+	Close() error
+}
+
+// memoryHub is a hub for a single instance
+type memoryHub struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[string]map[int]func(payload []byte)
+}
+
+// NewMemoryHub creates a hub that only reaches subscribers in this process
+func NewMemoryHub() Hub {
+	return &memoryHub{handlers: make(map[string]map[int]func(payload []byte))}
+}
+
+// Publish calls the handlers of the topic before returning
+func (h *memoryHub) Publish(_ context.Context, topic string, payload []byte) error {
+	h.mu.RLock()
+	handlers := make([]func(payload []byte), 0, len(h.handlers[topic]))
+	for _, handler := range h.handlers[topic] {
+		handlers = append(handlers, handler)
+	}
+	h.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+func (h *memoryHub) Subscribe(topic string, handler func(payload []byte)) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	id := h.nextID
+	if h.handlers[topic] == nil {
+		h.handlers[topic] = make(map[int]func(payload []byte))
+	}
+	h.handlers[topic][id] = handler
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.handlers[topic], id)
+	}
+}
+
+func (h *memoryHub) Close() error {
+	return nil
+}
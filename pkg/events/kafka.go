@@ -0,0 +1,108 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kafkaRESTContentType = "application/vnd.kafka.json.v2+json"
+	kafkaRESTAccept      = "application/vnd.kafka.v2+json"
+)
+
+// kafkaRESTSink produces events to a Kafka topic through a REST proxy speaking the
+// Confluent v2 API. Records are keyed by aggregate, so the events of one aggregate
+// land in the same partition and keep their order.
+type kafkaRESTSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaRESTSink creates a sink for the REST proxy at baseURL, e.g. "http://localhost:8082"
+func NewKafkaRESTSink(baseURL, topic string, timeout time.Duration) (Sink, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Kafka REST proxy URL %q", baseURL)
+	}
+	if topic == "" {
+		return nil, errors.New("kafka topic is required")
+	}
+
+	return &kafkaRESTSink{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *kafkaRESTSink) Name() string {
+	return "kafka"
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *kafkaRESTSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{
+			Key:   event.AggregateType + ":" + strconv.FormatUint(uint64(event.AggregateID), 10),
+			Value: event,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaRESTContentType)
+	req.Header.Set("Accept", kafkaRESTAccept)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce to Kafka: %w", err)
+	}
+	defer resp.Body.Close()
+
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("kafka REST proxy answered with status %d: %s", resp.StatusCode, strings.TrimSpace(string(answer)))
+	}
+
+	// The proxy reports failures of single records with a success status
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(answer, &produced); err != nil {
+		return fmt.Errorf("invalid Kafka REST proxy response: %w", err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil || offset.Error != nil {
+			message := "unknown error"
+			if offset.Error != nil {
+				message = *offset.Error
+			}
+			return fmt.Errorf("kafka rejected the record: %s", message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request asks whether the subject may perform the action on the resource.
+// Resource attributes should include "type".
+type Request struct {
+	Subject     Attributes `json:"subject"`
+	Action      string     `json:"action"`
+	Resource    Attributes `json:"resource"`
+	Environment Attributes `json:"environment"`
+}
+
+// Decision is the outcome of evaluating a request
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	RuleID  string `json:"rule_id,omitempty"` // rule that decided, empty when no rule matched
+	Reason  string `json:"reason"`
+	// Result of every rule, only filled in by Explain
+	Trace []RuleResult `json:"trace,omitempty"`
+}
+
+// RuleResult tells whether a rule matched a request and otherwise why not
+type RuleResult struct {
+	RuleID  string `json:"rule_id"`
+	Effect  string `json:"effect"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Engine evaluates requests against a policy. It is safe for concurrent use and
+// the policy can be replaced at runtime.
+type Engine struct {
+	mu     sync.RWMutex
+	policy Policy
+	now    func() time.Time
+}
+
+// NewEngine creates an engine for a validated policy
+func NewEngine(policy Policy) (*Engine, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{policy: policy, now: time.Now}, nil
+}
+
+// SetClock replaces the clock the default environment attributes are taken from
+func (e *Engine) SetClock(now func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = now
+}
+
+// Policy returns the policy currently in use
+func (e *Engine) Policy() Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policy
+}
+
+// Replace swaps in a new policy once it has been validated
+func (e *Engine) Replace(policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+	return nil
+}
+
+// Evaluate decides a request. Deny rules take precedence over allow rules.
+func (e *Engine) Evaluate(req Request) Decision {
+	return e.evaluate(req, false)
+}
+
+// Explain decides a request like Evaluate and reports the result of every rule
+func (e *Engine) Explain(req Request) Decision {
+	return e.evaluate(req, true)
+}
+
+// Prepare returns the request with the default environment attributes filled in,
+// as it is seen by the rules
+func (e *Engine) Prepare(req Request) Request {
+	e.mu.RLock()
+	now := e.now()
+	e.mu.RUnlock()
+
+	environment := Attributes{
+		"time":    now.UTC().Format(time.RFC3339),
+		"hour":    now.Hour(),
+		"weekday": strings.ToLower(now.Weekday().String()),
+	}
+	for key, value := range req.Environment {
+		environment[key] = value
+	}
+	req.Environment = environment
+	return req
+}
+
+func (e *Engine) evaluate(req Request, explain bool) Decision {
+	req = e.Prepare(req)
+	policy := e.Policy()
+
+	var decision Decision
+	var allowedBy, deniedBy string
+	for _, rule := range policy.Rules {
+		matched, reason := rule.matches(req)
+		if explain {
+			decision.Trace = append(decision.Trace, RuleResult{
+				RuleID:  rule.ID,
+				Effect:  rule.Effect,
+				Matched: matched,
+				Reason:  reason,
+			})
+		}
+		if !matched {
+			continue
+		}
+
+		if rule.Effect == EffectDeny && deniedBy == "" {
+			deniedBy = rule.ID
+			if !explain {
+				break
+			}
+		}
+		if rule.Effect == EffectAllow && allowedBy == "" {
+			allowedBy = rule.ID
+		}
+	}
+
+	switch {
+	case deniedBy != "":
+		decision.RuleID = deniedBy
+		decision.Reason = fmt.Sprintf("denied by rule %s", deniedBy)
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.RuleID = allowedBy
+		decision.Reason = fmt.Sprintf("allowed by rule %s", allowedBy)
+	default:
+		decision.Reason = fmt.Sprintf("no rule allows %s", req.Action)
+	}
+	return decision
+}
+
+// matches reports whether the rule applies to the request and otherwise names the first mismatch
+func (r Rule) matches(req Request) (bool, string) {
+	if !matchesAction(r.Actions, req.Action) {
+		return false, "action does not match"
+	}
+
+	if len(r.Resources) > 0 {
+		resourceType, _ := req.Resource["type"].(string)
+		if !slices.Contains(r.Resources, resourceType) {
+			return false, "resource type does not match"
+		}
+	}
+
+	for _, condition := range r.Conditions {
+		if !condition.holds(req) {
+			return false, "condition failed: " + condition.String()
+		}
+	}
+	return true, ""
+}
+
+func (c Condition) holds(req Request) bool {
+	actual, found := lookup(req, c.Attribute)
+	if c.Operator == OperatorExists {
+		want, _ := c.Value.(bool)
+		return found == want
+	}
+
+	expected := c.Value
+	if c.Ref != "" {
+		var ok bool
+		if expected, ok = lookup(req, c.Ref); !ok {
+			return false
+		}
+	}
+
+	// Comparisons with missing attributes fail, so negated operators never grant by omission
+	if !found {
+		return false
+	}
+
+	switch c.Operator {
+	case OperatorEq:
+		return equal(actual, expected)
+	case OperatorNe:
+		return !equal(actual, expected)
+	case OperatorIn:
+		return listContains(expected, actual)
+	case OperatorNotIn:
+		return !listContains(expected, actual)
+	case OperatorContains:
+		return listContains(actual, expected)
+	case OperatorNotContains:
+		return !listContains(actual, expected)
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		a, aok := number(actual)
+		b, bok := number(expected)
+		if !aok || !bok {
+			return false
+		}
+		switch c.Operator {
+		case OperatorGt:
+			return a > b
+		case OperatorGte:
+			return a >= b
+		case OperatorLt:
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return false
+}
+
+// String renders the condition for explanations, e.g. `subject.role eq "admin"`
+func (c Condition) String() string {
+	if c.Ref != "" {
+		return fmt.Sprintf("%s %s %s", c.Attribute, c.Operator, c.Ref)
+	}
+	return fmt.Sprintf("%s %s %v", c.Attribute, c.Operator, formatValue(c.Value))
+}
+
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// lookup resolves "action" and "scope.name" paths
+func lookup(req Request, path string) (any, bool) {
+	if path == "action" {
+		return req.Action, true
+	}
+
+	scope, name, _ := strings.Cut(path, ".")
+	var attributes Attributes
+	switch scope {
+	case "subject":
+		attributes = req.Subject
+	case "resource":
+		attributes = req.Resource
+	case "environment":
+		attributes = req.Environment
+	}
+
+	value, ok := attributes[name]
+	if !ok || value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func matchesAction(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == action {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares attribute values loosely, so that numbers decoded from JSON
+// match the integer types attributes are usually built from
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isList(value any) bool {
+	kind := reflect.ValueOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// listContains reports whether the list holds an element equal to the value
+func listContains(list, value any) bool {
+	if !isList(list) {
+		return false
+	}
+	v := reflect.ValueOf(list)
+	for i := 0; i < v.Len(); i++ {
+		if equal(v.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func number(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
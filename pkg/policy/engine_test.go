@@ -0,0 +1,166 @@
+package policy_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"user_crud/pkg/policy"
+	"user_crud/pkg/policy/policytest"
+)
+
+// singleRule wraps a condition in a policy whose only rule allows "test:run"
+func singleRule(condition string) string {
+	return fmt.Sprintf(`{"rules": [{"id": "rule", "effect": "allow", "actions": ["test:run"], "conditions": [%s]}]}`, condition)
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		subject   policy.Attributes
+		allowed   bool
+	}{
+		{"eq string", `{"attribute": "subject.role", "operator": "eq", "value": "admin"}`, policy.Attributes{"role": "admin"}, true},
+		{"eq string mismatch", `{"attribute": "subject.role", "operator": "eq", "value": "admin"}`, policy.Attributes{"role": "user"}, false},
+		{"eq number across types", `{"attribute": "subject.id", "operator": "eq", "value": 5}`, policy.Attributes{"id": uint(5)}, true},
+		{"ne", `{"attribute": "subject.role", "operator": "ne", "value": "admin"}`, policy.Attributes{"role": "user"}, true},
+		{"ne missing attribute", `{"attribute": "subject.role", "operator": "ne", "value": "admin"}`, policy.Attributes{}, false},
+		{"in", `{"attribute": "subject.role", "operator": "in", "value": ["admin", "moderator"]}`, policy.Attributes{"role": "moderator"}, true},
+		{"in mismatch", `{"attribute": "subject.role", "operator": "in", "value": ["admin", "moderator"]}`, policy.Attributes{"role": "user"}, false},
+		{"not_in", `{"attribute": "subject.role", "operator": "not_in", "value": ["admin"]}`, policy.Attributes{"role": "user"}, true},
+		{"not_in missing attribute", `{"attribute": "subject.role", "operator": "not_in", "value": ["admin"]}`, policy.Attributes{}, false},
+		{"contains", `{"attribute": "subject.scopes", "operator": "contains", "value": "users:read"}`, policy.Attributes{"scopes": []string{"users:read"}}, true},
+		{"contains mismatch", `{"attribute": "subject.scopes", "operator": "contains", "value": "users:write"}`, policy.Attributes{"scopes": []string{"users:read"}}, false},
+		{"contains on a scalar", `{"attribute": "subject.scopes", "operator": "contains", "value": "users:read"}`, policy.Attributes{"scopes": "users:read"}, false},
+		{"not_contains", `{"attribute": "subject.scopes", "operator": "not_contains", "value": "users:write"}`, policy.Attributes{"scopes": []string{"users:read"}}, true},
+		{"not_contains mismatch", `{"attribute": "subject.scopes", "operator": "not_contains", "value": "users:read"}`, policy.Attributes{"scopes": []string{"users:read"}}, false},
+		{"gt", `{"attribute": "subject.level", "operator": "gt", "value": 2}`, policy.Attributes{"level": 3}, true},
+		{"gt equal", `{"attribute": "subject.level", "operator": "gt", "value": 2}`, policy.Attributes{"level": 2}, false},
+		{"gte", `{"attribute": "subject.level", "operator": "gte", "value": 2}`, policy.Attributes{"level": 2}, true},
+		{"lt", `{"attribute": "subject.level", "operator": "lt", "value": 2}`, policy.Attributes{"level": 1.5}, true},
+		{"lte", `{"attribute": "subject.level", "operator": "lte", "value": 2}`, policy.Attributes{"level": 3}, false},
+		{"gt on a string", `{"attribute": "subject.level", "operator": "gt", "value": 2}`, policy.Attributes{"level": "3"}, false},
+		{"exists", `{"attribute": "subject.role", "operator": "exists", "value": true}`, policy.Attributes{"role": "user"}, true},
+		{"exists nil", `{"attribute": "subject.role", "operator": "exists", "value": true}`, policy.Attributes{"role": nil}, false},
+		{"exists false", `{"attribute": "subject.role", "operator": "exists", "value": false}`, policy.Attributes{}, true},
+		{"ref", `{"attribute": "subject.id", "operator": "eq", "ref": "resource.owner_id"}`, policy.Attributes{"id": 7}, true},
+		{"ref mismatch", `{"attribute": "subject.id", "operator": "eq", "ref": "resource.owner_id"}`, policy.Attributes{"id": 8}, false},
+		{"ref missing", `{"attribute": "subject.id", "operator": "ne", "ref": "resource.missing"}`, policy.Attributes{"id": 8}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := policytest.Engine(t, singleRule(tt.condition))
+			builder := policytest.NewRequest("test:run").Resource("owner_id", 7)
+			for name, value := range tt.subject {
+				builder.Subject(name, value)
+			}
+
+			if tt.allowed {
+				policytest.AssertAllowed(t, engine, builder.Build())
+			} else {
+				policytest.AssertDenied(t, engine, builder.Build())
+			}
+		})
+	}
+}
+
+func TestEnvironmentUsesClock(t *testing.T) {
+	engine := policytest.Engine(t, singleRule(`{"attribute": "environment.hour", "operator": "gte", "value": 9}`))
+	req := policytest.NewRequest("test:run").Build()
+
+	policytest.FixedClock(engine, time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local))
+	policytest.AssertAllowed(t, engine, req)
+
+	policytest.FixedClock(engine, time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local))
+	policytest.AssertDenied(t, engine, req)
+}
+
+func TestActionsAndResources(t *testing.T) {
+	engine := policytest.Engine(t, `{"rules": [
+		{"id": "users", "effect": "allow", "actions": ["users:*"], "resources": ["user"]},
+		{"id": "everything", "effect": "allow", "actions": ["*"], "resources": ["policy"]}
+	]}`)
+
+	tests := []struct {
+		action, resourceType string
+		ruleID               string
+	}{
+		{"users:update", "user", "users"},
+		{"users:update", "group", ""},
+		{"groups:update", "user", ""},
+		{"policy:manage", "policy", "everything"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action+" on "+tt.resourceType, func(t *testing.T) {
+			req := policytest.NewRequest(tt.action).Resource("type", tt.resourceType).Build()
+			decision := policytest.AssertDecidedBy(t, engine, req, tt.ruleID)
+			if decision.Allowed != (tt.ruleID != "") {
+				t.Errorf("expected allowed to be %v, %s", tt.ruleID != "", decision.Reason)
+			}
+		})
+	}
+}
+
+func TestDenyTakesPrecedence(t *testing.T) {
+	engine := policytest.Engine(t, `{"rules": [
+		{"id": "allow-all", "effect": "allow", "actions": ["*"]},
+		{"id": "deny-guests", "effect": "deny", "actions": ["users:delete"],
+			"conditions": [{"attribute": "subject.role", "operator": "eq", "value": "guest"}]},
+		{"id": "deny-everyone", "effect": "deny", "actions": ["users:delete"],
+			"conditions": [{"attribute": "subject.role", "operator": "exists", "value": true}]}
+	]}`)
+
+	guest := policytest.NewRequest("users:delete").Subject("role", "guest").Build()
+	// The first matching deny rule decides, even though an earlier rule allows
+	decision := policytest.AssertDecidedBy(t, engine, guest, "deny-guests")
+	if decision.Allowed {
+		t.Errorf("expected deny to win over allow, %s", decision.Reason)
+	}
+	if len(decision.Trace) != 3 {
+		t.Errorf("expected Explain to trace all 3 rules, got %d", len(decision.Trace))
+	}
+
+	// Evaluate stops at the first deny and reaches the same decision
+	if decision := engine.Evaluate(guest); decision.Allowed || decision.RuleID != "deny-guests" {
+		t.Errorf("expected Evaluate to be denied by deny-guests, got %+v", decision)
+	}
+
+	policytest.AssertDecidedBy(t, engine, policytest.NewRequest("users:update").Subject("role", "guest").Build(), "allow-all")
+}
+
+func TestNoMatchingRuleDenies(t *testing.T) {
+	engine := policytest.Engine(t, `{"rules": []}`)
+
+	decision := policytest.AssertDenied(t, engine, policytest.NewRequest("users:read").Build())
+	if decision.RuleID != "" {
+		t.Errorf("expected no deciding rule, got %q", decision.RuleID)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"missing id", `{"rules": [{"effect": "allow", "actions": ["*"]}]}`},
+		{"duplicate id", `{"rules": [{"id": "a", "effect": "allow", "actions": ["*"]}, {"id": "a", "effect": "deny", "actions": ["*"]}]}`},
+		{"unknown effect", `{"rules": [{"id": "a", "effect": "maybe", "actions": ["*"]}]}`},
+		{"no actions", `{"rules": [{"id": "a", "effect": "allow"}]}`},
+		{"unknown operator", singleRule(`{"attribute": "subject.role", "operator": "like", "value": "a"}`)},
+		{"invalid attribute", singleRule(`{"attribute": "role", "operator": "eq", "value": "a"}`)},
+		{"value and ref", singleRule(`{"attribute": "subject.id", "operator": "eq", "value": 1, "ref": "resource.id"}`)},
+		{"in without a list", singleRule(`{"attribute": "subject.role", "operator": "in", "value": "admin"}`)},
+		{"exists without a boolean", singleRule(`{"attribute": "subject.role", "operator": "exists", "value": "yes"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := policy.Parse([]byte(tt.source)); err == nil {
+				t.Error("expected the policy to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rule effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Condition operators
+const (
+	OperatorEq          = "eq"
+	OperatorNe          = "ne"
+	OperatorIn          = "in"           // attribute is one of the values
+	OperatorNotIn       = "not_in"       // attribute is none of the values
+	OperatorContains    = "contains"     // list attribute contains the value
+	OperatorNotContains = "not_contains" // list attribute does not contain the value
+	OperatorGt          = "gt"
+	OperatorGte         = "gte"
+	OperatorLt          = "lt"
+	OperatorLte         = "lte"
+	OperatorExists      = "exists" // attribute is set; value true or false
+)
+
+// Attributes describe the subject, resource or environment of a request
+type Attributes map[string]any
+
+// Policy is an ordered list of rules. A request is allowed when an allow rule matches
+// and no deny rule does; requests no rule matches are denied.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule applies its effect to requests for one of its actions on one of its resource
+// types when all conditions hold
+type Rule struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Effect      string      `json:"effect"`
+	Actions     []string    `json:"actions"`             // exact names, "*" or prefixes such as "users:*"
+	Resources   []string    `json:"resources,omitempty"` // resource types, all when empty
+	Conditions  []Condition `json:"conditions,omitempty"`
+}
+
+// Condition compares an attribute, e.g. "subject.role" or "resource.organization_ids",
+// with a literal value or with another attribute referenced by Ref
+type Condition struct {
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"`
+	Value     any    `json:"value,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+}
+
+// Parse reads a JSON policy and validates it
+func Parse(data []byte) (Policy, error) {
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	return policy, nil
+}
+
+// LoadFile reads a JSON policy file
+func LoadFile(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	return Parse(data)
+}
+
+// Validate reports the first malformed rule of the policy
+func (p Policy) Validate() error {
+	seen := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d: id is required", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("rule %s: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s: effect must be %q or %q", rule.ID, EffectAllow, EffectDeny)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("rule %s: at least one action is required", rule.ID)
+		}
+
+		for _, condition := range rule.Conditions {
+			if err := condition.validate(); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate() error {
+	if !validAttribute(c.Attribute) {
+		return fmt.Errorf("invalid attribute %q", c.Attribute)
+	}
+	if c.Ref != "" && !validAttribute(c.Ref) {
+		return fmt.Errorf("invalid reference %q", c.Ref)
+	}
+
+	switch c.Operator {
+	case OperatorExists:
+		if _, ok := c.Value.(bool); !ok {
+			return fmt.Errorf("%s on %s needs a boolean value", c.Operator, c.Attribute)
+		}
+		return nil
+	case OperatorEq, OperatorNe, OperatorIn, OperatorNotIn, OperatorContains, OperatorNotContains,
+		OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+
+	if (c.Value == nil) == (c.Ref == "") {
+		return fmt.Errorf("%s on %s needs either a value or a ref", c.Operator, c.Attribute)
+	}
+	if (c.Operator == OperatorIn || c.Operator == OperatorNotIn) && c.Ref == "" {
+		if !isList(c.Value) {
+			return fmt.Errorf("%s on %s needs a list value", c.Operator, c.Attribute)
+		}
+	}
+	return nil
+}
+
+// validAttribute accepts "action" and paths into the subject, resource and environment
+func validAttribute(path string) bool {
+	if path == "action" {
+		return true
+	}
+	scope, name, ok := strings.Cut(path, ".")
+	if !ok || name == "" {
+		return false
+	}
+	return scope == "subject" || scope == "resource" || scope == "environment"
+}
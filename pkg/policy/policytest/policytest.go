@@ -0,0 +1,137 @@
+// Package policytest helps unit tests check policies, e.g.
+//
+//	engine := policytest.Engine(t, policySource)
+//	policytest.AssertAllowed(t, engine, policytest.NewRequest("users:update").
+//		Subject("id", 5).Subject("role", "user").
+//		Resource("type", "user").Resource("id", 5).
+//		Build())
+package policytest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"user_crud/pkg/policy"
+)
+
+// Engine parses a JSON policy and fails the test when it is invalid
+func Engine(t testing.TB, source string) *policy.Engine {
+	t.Helper()
+
+	parsed, err := policy.Parse([]byte(source))
+	if err != nil {
+		t.Fatalf("invalid policy: %v", err)
+	}
+	engine, err := policy.NewEngine(parsed)
+	if err != nil {
+		t.Fatalf("invalid policy: %v", err)
+	}
+	return engine
+}
+
+// EngineFromFile loads a JSON policy file and fails the test when it is invalid
+func EngineFromFile(t testing.TB, path string) *policy.Engine {
+	t.Helper()
+
+	parsed, err := policy.LoadFile(path)
+	if err != nil {
+		t.Fatalf("failed to load policy %s: %v", path, err)
+	}
+	engine, err := policy.NewEngine(parsed)
+	if err != nil {
+		t.Fatalf("invalid policy %s: %v", path, err)
+	}
+	return engine
+}
+
+// FixedClock makes the engine see the given time in its default environment attributes
+func FixedClock(engine *policy.Engine, now time.Time) {
+	engine.SetClock(func() time.Time { return now })
+}
+
+// AssertAllowed fails the test with the rule trace unless the request is allowed
+func AssertAllowed(t testing.TB, engine *policy.Engine, req policy.Request) policy.Decision {
+	t.Helper()
+
+	decision := engine.Explain(req)
+	if !decision.Allowed {
+		t.Errorf("expected %s to be allowed, %s\n%s", req.Action, decision.Reason, FormatTrace(decision))
+	}
+	return decision
+}
+
+// AssertDenied fails the test with the rule trace unless the request is denied
+func AssertDenied(t testing.TB, engine *policy.Engine, req policy.Request) policy.Decision {
+	t.Helper()
+
+	decision := engine.Explain(req)
+	if decision.Allowed {
+		t.Errorf("expected %s to be denied, %s\n%s", req.Action, decision.Reason, FormatTrace(decision))
+	}
+	return decision
+}
+
+// AssertDecidedBy fails the test unless the given rule decided the request
+func AssertDecidedBy(t testing.TB, engine *policy.Engine, req policy.Request, ruleID string) policy.Decision {
+	t.Helper()
+
+	decision := engine.Explain(req)
+	if decision.RuleID != ruleID {
+		t.Errorf("expected %s to be decided by rule %q, %s\n%s", req.Action, ruleID, decision.Reason, FormatTrace(decision))
+	}
+	return decision
+}
+
+// FormatTrace renders the result of every rule, one per line
+func FormatTrace(decision policy.Decision) string {
+	var b strings.Builder
+	for _, result := range decision.Trace {
+		b.WriteString("  ")
+		b.WriteString(result.RuleID)
+		b.WriteString(" (")
+		b.WriteString(result.Effect)
+		b.WriteString("): ")
+		if result.Matched {
+			b.WriteString("matched")
+		} else {
+			b.WriteString(result.Reason)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RequestBuilder assembles a policy request attribute by attribute
+type RequestBuilder struct {
+	req policy.Request
+}
+
+// NewRequest starts a request for the action
+func NewRequest(action string) *RequestBuilder {
+	return &RequestBuilder{req: policy.Request{
+		Action:      action,
+		Subject:     policy.Attributes{},
+		Resource:    policy.Attributes{},
+		Environment: policy.Attributes{},
+	}}
+}
+
+func (b *RequestBuilder) Subject(name string, value any) *RequestBuilder {
+	b.req.Subject[name] = value
+	return b
+}
+
+func (b *RequestBuilder) Resource(name string, value any) *RequestBuilder {
+	b.req.Resource[name] = value
+	return b
+}
+
+func (b *RequestBuilder) Environment(name string, value any) *RequestBuilder {
+	b.req.Environment[name] = value
+	return b
+}
+
+func (b *RequestBuilder) Build() policy.Request {
+	return b.req
+}
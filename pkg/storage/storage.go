@@ -1,25 +1,111 @@
 package storage
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 
 	"user_crud/internal/domain/entity"
+	"user_crud/internal/util"
 )
 
 func NewDatabaseConnection(dsn string) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	// Constraint violations surface as gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Existing users are normalized before the unique index on their addresses is created
+	if err := prepareNormalizedEmails(db); err != nil {
+		log.Fatalf("Failed to normalize user emails: %v", err)
+	}
+
 	// Auto migrate the schema
-	err = db.AutoMigrate(&entity.User{}, &entity.File{}, &entity.Role{})
+	err = db.AutoMigrate(
+		&entity.User{},
+		&entity.File{},
+		&entity.Role{},
+		&entity.ServiceAccount{},
+		&entity.UsedAssertion{},
+		&entity.Session{},
+		&entity.LoginAttempt{},
+		&entity.PasswordHistory{},
+		&entity.VerificationToken{},
+		&entity.Invitation{},
+		&entity.Organization{},
+		&entity.OrganizationMembership{},
+		&entity.Group{},
+		&entity.GroupMember{},
+		&entity.RoleGrant{},
+		&entity.AuditEvent{},
+		&entity.WebhookSubscription{},
+		&entity.WebhookDelivery{},
+		&entity.OutboxEvent{},
+		&entity.Notification{},
+		&entity.NotificationPreference{},
+	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	return db
 }
+
+// prepareNormalizedEmails readies databases created before entity.User.NormalizedEmail was unique:
+// it fills the column for rows created before it existed, drops the plain index it used to have
+// and moves aside the addresses of all but the oldest of users that normalize to the same address
+func prepareNormalizedEmails(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&entity.User{}) {
+		return nil
+	}
+	if !migrator.HasColumn(&entity.User{}, "NormalizedEmail") {
+		if err := migrator.AddColumn(&entity.User{}, "NormalizedEmail"); err != nil {
+			return err
+		}
+	}
+
+	indexes, err := migrator.GetIndexes(&entity.User{})
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if unique, _ := index.Unique(); index.Name() == "idx_users_normalized_email" && !unique {
+			if err := migrator.DropIndex(&entity.User{}, index.Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	var users []entity.User
+	if err := db.Select("id", "email", "normalized_email").Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]uint, len(users))
+	for _, user := range users {
+		normalized := user.NormalizedEmail
+		if normalized == "" {
+			normalized = util.NormalizeEmail(user.Email)
+		}
+		if firstID, ok := seen[normalized]; ok {
+			// The user keeps their address but cannot be found by it until an admin resolves the duplicate
+			log.Printf("user:%d has the same normalized email as user:%d, marking it as a duplicate", user.ID, firstID)
+			normalized = fmt.Sprintf("duplicate:%d:%s", user.ID, normalized)
+		} else {
+			seen[normalized] = user.ID
+		}
+
+		if normalized != user.NormalizedEmail {
+			err := db.Model(&entity.User{}).Where("id = ?", user.ID).
+				UpdateColumn("normalized_email", normalized).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}